@@ -22,6 +22,7 @@ import (
 	"github.com/walles/moor/v2/internal"
 	"github.com/walles/moor/v2/internal/linemetadata"
 	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/reformat"
 	"github.com/walles/moor/v2/internal/textstyles"
 	"github.com/walles/moor/v2/internal/util"
 	"github.com/walles/moor/v2/twin"
@@ -395,6 +396,18 @@ func pagerFromArgs(
 
 	wrap := flagSet.Bool("wrap", false, "Wrap long lines")
 	follow := flagSet.Bool("follow", false, "Follow piped input just like \"tail -f\"")
+	followName := flagSet.Bool("follow-name", false, "Follow files by name just like \"tail -F\", surviving log rotation")
+	merge := flagSet.Bool("merge", false, "When paging multiple files, start with all of them merged by line timestamps")
+	startupCommands := []string{}
+	addStartupCommand := func(command string) error {
+		startupCommands = append(startupCommands, command)
+		return nil
+	}
+	flagSet.Func("exec", "Run a ':' `command` on startup, like \"set wrap\" or \"filter ERROR\". Can be repeated.", addStartupCommand)
+	flagSet.Func("c", "Short for --exec", addStartupCommand)
+	flagSet.Func("highlight", "Always highlight `pattern` in its own color. Can be repeated.", func(pattern string) error {
+		return addStartupCommand("highlight " + pattern)
+	})
 	styleOption := flagSetFunc(flagSet,
 		"style", nil,
 		"Highlighting `style` from https://xyproto.github.io/splash/docs/longer/all.html", parseStyleOption)
@@ -413,7 +426,26 @@ func pagerFromArgs(
 
 	noLineNumbers := flagSet.Bool("no-linenumbers", noLineNumbersDefault(), "Hide line numbers on startup, press left arrow key to show")
 	noStatusBar := flagSet.Bool("no-statusbar", false, "Hide the status bar, toggle with '='")
-	reFormat := flagSet.Bool("reformat", false, "Reformat some input files (JSON)")
+	scrollbar := flagSet.Bool("scrollbar", false, "Show a scrollbar marking search hits, filter matches and bookmarks")
+	reFormat := false
+	reformatter := ""
+	flagSet.BoolFunc("reformat", "Reformat some input files. Use --reformat=kind to pick the kind: "+strings.Join(reformat.Kinds(), ", "), func(value string) error {
+		switch value {
+		case "true":
+			reFormat = true
+			reformatter = ""
+		case "false":
+			reFormat = false
+			reformatter = ""
+		default:
+			if reformat.Get(value) == nil {
+				return fmt.Errorf("Unknown --reformat kind <%s>, pick one of: %s", value, strings.Join(reformat.Kinds(), ", "))
+			}
+			reFormat = true
+			reformatter = value
+		}
+		return nil
+	})
 	flagSet.Bool("no-reformat", true, "No effect, kept for compatibility. See --reformat")
 	quitIfOneScreen := flagSet.Bool("quit-if-one-screen", false, "Don't page if contents fits on one screen. Affected by --no-clear-on-exit-margin.")
 	noClearOnExit := flagSet.Bool("no-clear-on-exit", false, "Retain screen contents when exiting moor")
@@ -553,8 +585,7 @@ func pagerFromArgs(
 	}
 
 	var readerImpls []*reader.ReaderImpl
-	shouldFormat := *reFormat
-	readerOptions := reader.ReaderOptions{Lexer: *lexer, ShouldFormat: shouldFormat}
+	readerOptions := reader.ReaderOptions{Lexer: *lexer, ShouldFormat: reFormat, Reformatter: reformatter, FollowName: *followName}
 
 	stdinName := ""
 	if os.Getenv("PAGER_LABEL") != "" {
@@ -622,12 +653,18 @@ func pagerFromArgs(
 	}
 
 	pager := internal.NewPager(readerImpls...)
+	pager.LoadHistoryFiles()
 	pager.WrapLongLines = *wrap
+	pager.UnfoldJSONRecords = reFormat && (reformatter == "" || reformatter == "json")
 	pager.ShowLineNumbers = !*noLineNumbers
 	pager.ShowStatusBar = !*noStatusBar
+	pager.ShowScrollbar = *scrollbar
 	pager.DeInit = !*noClearOnExit
 	pager.DeInitFalseMargin = *noClearOnExitMargin
 	pager.QuitIfOneScreen = *quitIfOneScreen
+	pager.MergeFiles = *merge
+	pager.StartupCommands = startupCommands
+	pager.ReaderOptions = readerOptions
 	pager.StatusBarStyle = *statusBarStyle
 	pager.UnprintableStyle = *unprintableStyle
 	pager.WithTerminalFg = *terminalFg
@@ -638,7 +675,7 @@ func pagerFromArgs(
 	pager.WithSearchHitLineBackground = !*noSearchLineHighlight
 
 	pager.TargetLine = targetLine
-	if *follow && pager.TargetLine == nil {
+	if (*follow || *followName) && pager.TargetLine == nil {
 		reallyHigh := linemetadata.IndexMax()
 		pager.TargetLine = &reallyHigh
 	}
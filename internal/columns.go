@@ -0,0 +1,294 @@
+// Show JSON lines and logfmt lines as aligned columns, like:
+//
+//	2024-05-01T12:00:00Z  error  Connection refused
+//
+// rather than {"ts":"2024-05-01T12:00:00Z","level":"error","msg":"Conn...
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"github.com/walles/moor/v2/internal/reader"
+)
+
+// These column names match whichever of their keys a line has
+var columnAliases = map[string][]string{
+	"time":    {"time", "ts", "timestamp", "@timestamp", "t"},
+	"level":   {"level", "lvl", "severity", "loglevel", "@level"},
+	"message": {"message", "msg", "@message"},
+}
+
+var defaultColumns = []string{"time", "level", "message"}
+
+// Longer values are truncated, except in the last column
+const maxColumnWidth = 50
+
+const columnSeparator = "  "
+
+type columnSettings struct {
+	enabled bool
+
+	// Column names in order. Either columnAliases keys or field names, with
+	// nested JSON fields written like "user.id".
+	names []string
+
+	// How wide each column has been so far. Columns only grow, so that they
+	// don't jump around while scrolling.
+	widths []int
+}
+
+func newColumnSettings() columnSettings {
+	return columnSettings{names: defaultColumns}
+}
+
+// Parse a JSON object or a logfmt line into a map of field values. Nested JSON
+// objects are flattened into "outer.inner" keys. Returns nil if the line is
+// neither.
+func parseStructuredLine(plain string) map[string]string {
+	trimmed := strings.TrimSpace(plain)
+	if strings.HasPrefix(trimmed, "{") {
+		return parseJSONFields(trimmed)
+	}
+
+	return parseLogfmt(trimmed)
+}
+
+func parseJSONFields(line string) map[string]string {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	var object map[string]any
+	if decoder.Decode(&object) != nil {
+		return nil
+	}
+
+	fields := map[string]string{}
+	flattenJSON("", object, fields)
+	return fields
+}
+
+func flattenJSON(prefix string, object map[string]any, fields map[string]string) {
+	for key, value := range object {
+		switch value := value.(type) {
+		case map[string]any:
+			flattenJSON(prefix+key+".", value, fields)
+		case string:
+			fields[prefix+key] = value
+		case json.Number:
+			fields[prefix+key] = value.String()
+		default:
+			// Arrays, booleans and null
+			var encoded bytes.Buffer
+			encoder := json.NewEncoder(&encoded)
+			encoder.SetEscapeHTML(false)
+			if encoder.Encode(value) == nil {
+				fields[prefix+key] = strings.TrimSpace(encoded.String())
+			}
+		}
+	}
+}
+
+// Parse lines like `level=info msg="Hello world" user=7`. Returns nil unless
+// the line has at least two key=value pairs, and nothing else.
+func parseLogfmt(line string) map[string]string {
+	fields := map[string]string{}
+	rest := line
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+
+		equals := strings.IndexAny(rest, "= \t\"")
+		if equals <= 0 || rest[equals] != '=' {
+			// Not a key=value pair
+			return nil
+		}
+		key := rest[:equals]
+		rest = rest[equals+1:]
+
+		if !strings.HasPrefix(rest, "\"") {
+			end := strings.IndexAny(rest, " \t")
+			if end == -1 {
+				end = len(rest)
+			}
+			fields[key] = rest[:end]
+			rest = rest[end:]
+			continue
+		}
+
+		// Quoted value, find the closing quote
+		end := 1
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			// Unterminated
+			return nil
+		}
+
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			value = rest[1:end]
+		}
+		fields[key] = value
+		rest = rest[end+1:]
+	}
+
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields
+}
+
+// All keys in a structured line, sorted. Keys matching a columnAliases entry
+// are left out, the alias is listed instead.
+func structuredLineKeys(fields map[string]string) []string {
+	keys := []string{}
+	for key := range fields {
+		if isColumnAlias(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isColumnAlias(key string) bool {
+	for _, aliases := range columnAliases {
+		for _, alias := range aliases {
+			if key == alias {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func columnValue(fields map[string]string, name string) (string, bool) {
+	aliases, ok := columnAliases[name]
+	if !ok {
+		aliases = []string{name}
+	}
+
+	for _, alias := range aliases {
+		if value, ok := fields[alias]; ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// Error levels in red, warnings in yellow and debug levels dimmed
+func levelColor(level string) string {
+	lowercase := strings.ToLower(level)
+	for _, prefix := range []string{"err", "fatal", "panic", "crit", "alert", "emerg"} {
+		if strings.HasPrefix(lowercase, prefix) {
+			return "\x1b[31m"
+		}
+	}
+	if strings.HasPrefix(lowercase, "warn") {
+		return "\x1b[33m"
+	}
+	if strings.HasPrefix(lowercase, "debug") || strings.HasPrefix(lowercase, "trace") {
+		return "\x1b[2m"
+	}
+	return ""
+}
+
+// Cut s down to width screen cells, ending with an ellipsis if anything was
+// cut
+func truncateToWidth(s string, width int) string {
+	if uniseg.StringWidth(s) <= width {
+		return s
+	}
+
+	result := strings.Builder{}
+	used := 0
+	for _, char := range s {
+		charWidth := uniseg.StringWidth(string(char))
+		if used+charWidth > width-1 {
+			break
+		}
+		result.WriteRune(char)
+		used += charWidth
+	}
+	result.WriteRune('…')
+	return result.String()
+}
+
+// Returns nil if the line should be shown as it is
+func (p *Pager) columnizeLine(line reader.NumberedLine) *reader.NumberedLine {
+	settings := &p.columns
+	if !settings.enabled || p.isShowingHelp || len(settings.names) == 0 {
+		return nil
+	}
+
+	fields := parseStructuredLine(line.Plain())
+	if fields == nil {
+		return nil
+	}
+
+	values := make([]string, len(settings.names))
+	foundAny := false
+	for i, name := range settings.names {
+		value, found := columnValue(fields, name)
+		foundAny = foundAny || found
+
+		// Multi line values would mess up the layout
+		values[i] = strings.Join(strings.Fields(value), " ")
+	}
+	if !foundAny {
+		// Nothing to show, better show the line as it is
+		return nil
+	}
+
+	for len(settings.widths) < len(settings.names) {
+		settings.widths = append(settings.widths, 0)
+	}
+
+	formatted := strings.Builder{}
+	for i, name := range settings.names {
+		value := values[i]
+		isLast := i == len(settings.names)-1
+		if !isLast {
+			value = truncateToWidth(value, maxColumnWidth)
+			settings.widths[i] = max(settings.widths[i], uniseg.StringWidth(value))
+		}
+
+		if name == "level" && levelColor(value) != "" {
+			formatted.WriteString(levelColor(value) + value + "\x1b[m")
+		} else {
+			formatted.WriteString(value)
+		}
+
+		if !isLast {
+			formatted.WriteString(strings.Repeat(" ", settings.widths[i]-uniseg.StringWidth(value)))
+			formatted.WriteString(columnSeparator)
+		}
+	}
+
+	return &reader.NumberedLine{
+		Index:  line.Index,
+		Number: line.Number,
+		Line:   reader.NewLine(strings.TrimRight(formatted.String(), " ")),
+	}
+}
+
+// Show these columns in this order, and start over with the widths
+func (p *Pager) setColumns(names []string) {
+	p.columns.names = names
+	p.columns.widths = nil
+	p.columns.enabled = true
+}
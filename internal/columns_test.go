@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseStructuredLine(t *testing.T) {
+	assert.DeepEqual(t,
+		parseStructuredLine(`{"ts": "12:00", "level": "info", "user": {"id": 7, "tags": ["a"]}, "ok": true}`),
+		map[string]string{"ts": "12:00", "level": "info", "user.id": "7", "user.tags": `["a"]`, "ok": "true"})
+
+	assert.DeepEqual(t,
+		parseStructuredLine(`level=warn msg="disk \"almost\" full" used=97%`),
+		map[string]string{"level": "warn", "msg": `disk "almost" full`, "used": "97%"})
+
+	// Not structured
+	assert.Assert(t, parseStructuredLine("Hello world") == nil)
+	assert.Assert(t, parseStructuredLine("a=b and then some") == nil)
+	assert.Assert(t, parseStructuredLine("only=one") == nil)
+	assert.Assert(t, parseStructuredLine(`{"broken`) == nil)
+	assert.Assert(t, parseStructuredLine(`a="unterminated b=c`) == nil)
+}
+
+func TestColumnView(t *testing.T) {
+	pager := newCommandTestPager(t, strings.Join([]string{
+		`{"ts": "12:00", "level": "info", "msg": "Started", "user": {"id": 7}}`,
+		`not structured`,
+		`time=12:01 lvl=error message="Disk full"`,
+	}, "\n"))
+	pager.screen = twin.NewFakeScreen(60, 10)
+	pager.ShowLineNumbers = false
+	pager.showLineNumbers = false
+
+	rows := func() []string {
+		result := []string{}
+		for _, line := range pager.renderLines().lines {
+			result = append(result, renderedToString(line.cells))
+		}
+		return result
+	}
+
+	assert.NilError(t, pager.executeCommand("set columns"))
+	assert.DeepEqual(t, rows(), []string{
+		"12:00  info   Started",
+		"not structured",
+		"12:01  error  Disk full",
+	})
+
+	// Errors are red
+	rendered := pager.renderLines()
+	assert.Equal(t, rendered.lines[2].cells[7].Style, twin.StyleDefault.WithForeground(twin.NewColor16(1)))
+
+	// Search hits are highlighted where they are shown
+	pager.search.For("Disk")
+	rendered = pager.renderLines()
+	assert.Assert(t, rendered.lines[2].cells[14].StartsSearchHit)
+	pager.search.Clear()
+
+	assert.NilError(t, pager.executeCommand("columns user.id message"))
+	assert.DeepEqual(t, rows(), []string{
+		"7  Started",
+		"not structured",
+		"   Disk full",
+	})
+
+	assert.NilError(t, pager.executeCommand("set nocolumns"))
+	assert.Equal(t, rows()[1], "not structured")
+	assert.Assert(t, strings.HasPrefix(rows()[0], `{"ts"`))
+}
+
+func TestColumnsMode(t *testing.T) {
+	pager := newCommandTestPager(t, `{"ts": "12:00", "level": "info", "msg": "Started", "user": "joe"}`)
+	pager.screen = twin.NewFakeScreen(60, 10)
+	pager.ShowLineNumbers = false
+	pager.showLineNumbers = false
+
+	pager.mode = PagerModeViewing{pager: pager}
+	pager.mode.onRune('C')
+	_, isColumns := pager.mode.(*PagerModeColumns)
+	assert.Assert(t, isColumns)
+	assert.Assert(t, pager.columns.enabled)
+
+	pager.redraw("")
+	screen := pager.screen.(*twin.FakeScreen)
+	assert.Equal(t, rowToString(screen.GetRow(5)), "[x] time")
+	assert.Equal(t, rowToString(screen.GetRow(6)), "[x] level")
+	assert.Equal(t, rowToString(screen.GetRow(7)), "[x] message")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "[ ] user")
+
+	// Show the user first, and skip the level
+	pager.mode.onRune('j')
+	pager.mode.onRune(' ')
+	pager.mode.onKey(twin.KeyEnd) // Ignored
+	pager.mode.onRune('j')
+	pager.mode.onRune('j')
+	pager.mode.onRune(' ')
+	pager.mode.onRune('K')
+	pager.mode.onRune('K')
+	pager.mode.onRune('K')
+	assert.DeepEqual(t, pager.columns.names, []string{"user", "time", "message"})
+
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, renderedToString(pager.renderLines().lines[0].cells), "joe  12:00  Started")
+
+	// ESC restores what we had before
+	pager.mode.onRune('C')
+	pager.mode.onRune(' ')
+	pager.mode.onKey(twin.KeyEscape)
+	assert.DeepEqual(t, pager.columns.names, []string{"user", "time", "message"})
+}
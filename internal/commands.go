@@ -0,0 +1,494 @@
+// The command language of the ':' prompt
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/internal/textstyles"
+)
+
+type colonCommand struct {
+	// The first name is what we complete to, the others are aliases
+	names []string
+
+	run func(p *Pager, args string) error
+
+	// Optional. Returns where in args the word being completed starts, and
+	// all possible replacements for that word.
+	complete func(p *Pager, args string) (int, []string)
+}
+
+var colonCommands = []colonCommand{
+	{names: []string{"edit", "e"}, run: (*Pager).editCommand, complete: completePath},
+	{names: []string{"write", "w"}, run: (*Pager).writeCommand, complete: completeWritePath},
+	{names: []string{"write!", "w!"}, run: (*Pager).overwriteCommand, complete: completeWritePath},
+	{names: []string{"set"}, run: (*Pager).setCommand, complete: completeSetOption},
+	{names: []string{"filter"}, run: (*Pager).filterCommand},
+	{names: []string{"unfilter"}, run: (*Pager).unfilterCommand},
+	{names: []string{"highlight", "hl"}, run: (*Pager).highlightCommand},
+	{names: []string{"unhighlight"}, run: (*Pager).unhighlightCommand},
+	{names: []string{"columns"}, run: (*Pager).columnsCommand},
+	{names: []string{"pipe"}, run: (*Pager).pipeCommand},
+	{names: []string{"searchall", "sa"}, run: (*Pager).searchAllCommand},
+	{names: []string{"next", "n"}, run: func(p *Pager, _ string) error { p.nextFile(); return nil }},
+	{names: []string{"previous", "p"}, run: func(p *Pager, _ string) error { p.previousFile(); return nil }},
+	{names: []string{"first", "x"}, run: func(p *Pager, _ string) error { p.firstFile(); return nil }},
+	{names: []string{"merged", "m"}, run: func(p *Pager, _ string) error { p.mergedFiles(); return nil }},
+	{names: []string{"quit", "q"}, run: func(p *Pager, _ string) error { p.Quit(); return nil }},
+}
+
+// Windows accepts both separators, everybody else just '/'
+const pathSeparators = "/" + string(os.PathSeparator)
+
+var setOptionNames = []string{
+	"wrap", "nowrap",
+	"linenumbers", "nolinenumbers",
+	"statusbar", "nostatusbar",
+	"scrollbar", "noscrollbar",
+	"tabsize",
+	"context", "before", "after",
+	"keepnonjson", "nokeepnonjson",
+	"columns", "nocolumns",
+	"unfoldjson", "nounfoldjson",
+}
+
+// Run a ':' command, telling the user if it fails
+func (p *Pager) runCommand(command string) {
+	err := p.executeCommand(command)
+	if err != nil {
+		log.Infof("Command %q failed: %v", command, err)
+		p.mode = &PagerModeInfo{Pager: p, Text: err.Error()}
+	}
+}
+
+func (p *Pager) executeCommand(command string) error {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil
+	}
+
+	if lineNumber, err := strconv.Atoi(command); err == nil {
+		if lineNumber < 1 {
+			return fmt.Errorf("Line numbers start at 1, got %d", lineNumber)
+		}
+		p.goToLineNumber(lineNumber)
+		return nil
+	}
+
+	name, args, _ := strings.Cut(command, " ")
+	args = strings.TrimSpace(args)
+
+	colonCommand := findColonCommand(name)
+	if colonCommand == nil {
+		return fmt.Errorf("Unknown command: %s", name)
+	}
+
+	log.Debugf("Running command %q with args %q", name, args)
+	return colonCommand.run(p, args)
+}
+
+func findColonCommand(name string) *colonCommand {
+	for i := range colonCommands {
+		if slices.Contains(colonCommands[i].names, name) {
+			return &colonCommands[i]
+		}
+	}
+
+	return nil
+}
+
+// Complete the last word of a command line.
+//
+// Returns the new command line, plus all candidates if there was more than one.
+func (p *Pager) completeCommand(commandLine string) (string, []string) {
+	name, args, hasArgs := strings.Cut(commandLine, " ")
+	var candidates []string
+	var word string
+	if !hasArgs {
+		word = name
+		for _, colonCommand := range colonCommands {
+			if strings.HasPrefix(colonCommand.names[0], word) {
+				candidates = append(candidates, colonCommand.names[0])
+			}
+		}
+	} else {
+		colonCommand := findColonCommand(name)
+		if colonCommand == nil || colonCommand.complete == nil {
+			return commandLine, nil
+		}
+
+		var wordStart int
+		wordStart, candidates = colonCommand.complete(p, args)
+		word = args[wordStart:]
+	}
+
+	if len(candidates) == 0 {
+		return commandLine, nil
+	}
+
+	withoutWord := commandLine[:len(commandLine)-len(word)]
+	if len(candidates) == 1 {
+		completed := withoutWord + candidates[0]
+		if !strings.HasSuffix(completed, string(os.PathSeparator)) {
+			completed += " "
+		}
+		return completed, nil
+	}
+
+	return withoutWord + commonPrefix(candidates), candidates
+}
+
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		length := 0
+		for _, char := range word {
+			if length >= len(prefix) || prefix[length] != char {
+				break
+			}
+			length++
+		}
+		prefix = prefix[:length]
+	}
+
+	return string(prefix)
+}
+
+func completeSetOption(_ *Pager, args string) (int, []string) {
+	wordStart := strings.LastIndex(args, " ") + 1
+	words := strings.Split(args, " ")
+	if len(words) > 1 && slices.Contains([]string{"tabsize", "context", "before", "after"}, words[len(words)-2]) {
+		// Expecting a number here
+		return wordStart, nil
+	}
+
+	word := args[wordStart:]
+	candidates := []string{}
+	for _, option := range setOptionNames {
+		if strings.HasPrefix(option, word) {
+			candidates = append(candidates, option)
+		}
+	}
+
+	return wordStart, candidates
+}
+
+// Complete the last path component of args. Directories get a trailing path
+// separator so that the user can go on completing inside of them.
+func completePath(_ *Pager, args string) (int, []string) {
+	wordStart := strings.LastIndexAny(args, pathSeparators) + 1
+	dir := args[:wordStart]
+	prefix := args[wordStart:]
+
+	listMe := expandHome(dir)
+	if listMe == "" {
+		listMe = "."
+	}
+
+	entries, err := os.ReadDir(listMe)
+	if err != nil {
+		log.Debugf("Not completing in %s: %v", listMe, err)
+		return wordStart, nil
+	}
+
+	candidates := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			// Hidden files only on request, just like in the shell
+			continue
+		}
+
+		// Stat rather than use the entry, to get symlinks to directories right
+		stat, err := os.Stat(filepath.Join(listMe, name))
+		if err == nil && stat.IsDir() {
+			name += string(os.PathSeparator)
+		}
+
+		candidates = append(candidates, name)
+	}
+
+	return wordStart, candidates
+}
+
+// Like completePath(), but skipping any "-raw" / "-all" flags
+func completeWritePath(p *Pager, args string) (int, []string) {
+	flagsLength := 0
+	for {
+		flag, _, found := strings.Cut(args[flagsLength:], " ")
+		if !found || (flag != "-raw" && flag != "-all") {
+			break
+		}
+		flagsLength += len(flag) + 1
+	}
+
+	wordStart, candidates := completePath(p, args[flagsLength:])
+	return flagsLength + wordStart, candidates
+}
+
+// Expand a leading ~ into the user's home directory
+func expandHome(path string) string {
+	if path != "~" && (len(path) < 2 || path[0] != '~' || !strings.ContainsRune(pathSeparators, rune(path[1]))) {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Debugf("Not expanding ~ in %s: %v", path, err)
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+func (p *Pager) setCommand(args string) error {
+	words := strings.Fields(strings.ReplaceAll(args, "=", " "))
+	if len(words) == 0 {
+		return fmt.Errorf("Usage: set %s", strings.Join(setOptionNames, "|"))
+	}
+
+	for i := 0; i < len(words); i++ {
+		switch words[i] {
+		case "wrap":
+			p.WrapLongLines = true
+		case "nowrap":
+			p.WrapLongLines = false
+		case "linenumbers":
+			p.ShowLineNumbers = true
+			p.showLineNumbers = true
+		case "nolinenumbers":
+			p.ShowLineNumbers = false
+			p.showLineNumbers = false
+		case "statusbar":
+			p.ShowStatusBar = true
+		case "nostatusbar":
+			p.ShowStatusBar = false
+		case "scrollbar":
+			p.ShowScrollbar = true
+		case "noscrollbar":
+			p.ShowScrollbar = false
+		case "columns":
+			p.columns.enabled = true
+		case "nocolumns":
+			p.columns.enabled = false
+		case "unfoldjson":
+			p.setUnfoldJSONRecords(true)
+		case "nounfoldjson":
+			p.setUnfoldJSONRecords(false)
+		case "keepnonjson":
+			p.keepNonJSON = true
+		case "nokeepnonjson":
+			p.keepNonJSON = false
+		case "tabsize":
+			i++
+			if i >= len(words) {
+				return errors.New("Usage: set tabsize <number>")
+			}
+
+			tabSize, err := strconv.Atoi(words[i])
+			if err != nil || tabSize < 1 {
+				return fmt.Errorf("Tab size must be a positive number, got: %s", words[i])
+			}
+			p.TabSize = tabSize
+			textstyles.TabSize = tabSize
+		case "context", "before", "after":
+			option := words[i]
+			i++
+			if i >= len(words) {
+				return fmt.Errorf("Usage: set %s <number>", option)
+			}
+
+			lines, err := strconv.Atoi(words[i])
+			if err != nil || lines < 0 {
+				return fmt.Errorf("Context must be zero or more lines, got: %s", words[i])
+			}
+			if option != "after" {
+				p.filterContext.before = lines
+			}
+			if option != "before" {
+				p.filterContext.after = lines
+			}
+		default:
+			return fmt.Errorf("Unknown option: %s", words[i])
+		}
+	}
+
+	return nil
+}
+
+func (p *Pager) filterCommand(args string) error {
+	if p.isShowingHelp {
+		return errors.New("Filtering the help text is not supported")
+	}
+
+	if args == "" {
+		*p.filter = filterChain{}
+		p.search.Clear()
+		return nil
+	}
+
+	*p.filter = p.filter.with(len(p.filter.entries), args)
+	p.search = p.filter.highlight()
+	return nil
+}
+
+// Remove one filter from the filter chain, the last one by default
+func (p *Pager) unfilterCommand(args string) error {
+	if len(p.filter.entries) == 0 {
+		return errors.New("No filters to remove")
+	}
+
+	number := len(p.filter.entries)
+	if args != "" {
+		var err error
+		number, err = strconv.Atoi(args)
+		if err != nil || number < 1 || number > len(p.filter.entries) {
+			return fmt.Errorf("Expected a filter number between 1 and %d, got: %s", len(p.filter.entries), args)
+		}
+	}
+
+	*p.filter = p.filter.without(number - 1)
+	p.search = p.filter.highlight()
+	return nil
+}
+
+// Highlight a pattern in its own color, or remove all highlights if there's no
+// pattern
+func (p *Pager) highlightCommand(args string) error {
+	if args == "" {
+		p.highlights = nil
+		return nil
+	}
+
+	p.highlights = append(p.highlights, search.For(args))
+	return nil
+}
+
+// List hit counts in all files, for args or for the current search
+func (p *Pager) searchAllCommand(args string) error {
+	if args != "" {
+		p.search = search.For(args)
+		p.searchHistory.addEntry(args)
+	}
+	if p.search.Inactive() {
+		return errors.New("Nothing to search for, try :searchall <pattern>")
+	}
+
+	p.mode = NewPagerModeFileHits(p)
+	return nil
+}
+
+// Show JSON and logfmt lines as these columns, or the default columns if
+// there are no args
+func (p *Pager) columnsCommand(args string) error {
+	names := strings.Fields(args)
+	if len(names) == 0 {
+		names = defaultColumns
+	}
+
+	p.setColumns(names)
+	return nil
+}
+
+// Remove one highlight, the last one by default
+func (p *Pager) unhighlightCommand(args string) error {
+	if len(p.highlights) == 0 {
+		return errors.New("No highlights to remove")
+	}
+
+	number := len(p.highlights)
+	if args != "" {
+		var err error
+		number, err = strconv.Atoi(args)
+		if err != nil || number < 1 || number > len(p.highlights) {
+			return fmt.Errorf("Expected a highlight number between 1 and %d, got: %s", len(p.highlights), args)
+		}
+	}
+
+	p.highlights = slices.Delete(slices.Clone(p.highlights), number-1, number)
+	return nil
+}
+
+func (p *Pager) editCommand(args string) error {
+	if os.Getenv("LESSSECURE") == "1" {
+		return errors.New("Not opening files since LESSSECURE=1 is set in the environment")
+	}
+	if args == "" {
+		return errors.New("Usage: edit <file>")
+	}
+
+	return p.openFile(expandHome(args))
+}
+
+func (p *Pager) writeCommand(args string) error {
+	return p.write(args, false)
+}
+
+func (p *Pager) overwriteCommand(args string) error {
+	return p.write(args, true)
+}
+
+// Syntax: write [-raw] [-all] <file>
+//
+// By default we save what's visible, meaning only the filtered lines if a
+// filter is active, as plain text. "-raw" keeps the ANSI escape codes, and
+// "-all" ignores the filter.
+//
+// Since the reader pauses after a while, we read all lines before saving.
+func (p *Pager) write(args string, overwrite bool) error {
+	if os.Getenv("LESSSECURE") == "1" {
+		return errors.New("Not saving since LESSSECURE=1 is set in the environment")
+	}
+
+	options := saveOptions{overwrite: overwrite}
+	all := false
+	for {
+		flag, rest, _ := strings.Cut(args, " ")
+		if flag == "-raw" {
+			options.raw = true
+		} else if flag == "-all" {
+			all = true
+		} else {
+			break
+		}
+		args = strings.TrimSpace(rest)
+	}
+
+	fileName := args
+	if fileName == "" {
+		return errors.New("Usage: write [-raw] [-all] <file>")
+	}
+
+	var r reader.Reader = p.filteringReader
+	if all {
+		p.readerLock.Lock()
+		r = p.readers[p.currentReader]
+		p.readerLock.Unlock()
+	}
+
+	return p.readAllThen("saving to "+fileName, func() error {
+		lineCount, err := saveToFile(r, expandHome(fileName), options)
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists, use 'write!' to overwrite it", fileName)
+		}
+		if err != nil {
+			return err
+		}
+
+		p.mode = &PagerModeInfo{Pager: p, Text: fmt.Sprintf("Saved %d lines to %s", lineCount, fileName)}
+		return nil
+	})
+}
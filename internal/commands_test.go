@@ -0,0 +1,330 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func newCommandTestPager(t *testing.T, text string) *Pager {
+	t.Helper()
+
+	pager := NewPager(reader.NewFromTextForTesting("test", text))
+	pager.screen = twin.NewFakeScreen(20, 10)
+	return pager
+}
+
+func TestExecuteCommand_Set(t *testing.T) {
+	pager := newCommandTestPager(t, "hello")
+
+	assert.NilError(t, pager.executeCommand("set wrap nolinenumbers tabsize=4"))
+	assert.Assert(t, pager.WrapLongLines)
+	assert.Assert(t, !pager.ShowLineNumbers)
+	assert.Equal(t, pager.TabSize, 4)
+
+	assert.NilError(t, pager.executeCommand("set nowrap"))
+	assert.Assert(t, !pager.WrapLongLines)
+
+	assert.Error(t, pager.executeCommand("set tabsize 0"), "Tab size must be a positive number, got: 0")
+	assert.Error(t, pager.executeCommand("set bogus"), "Unknown option: bogus")
+}
+
+func TestExecuteCommand_GoToLine(t *testing.T) {
+	pager := newCommandTestPager(t, "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl")
+
+	assert.NilError(t, pager.executeCommand("3"))
+	assert.Equal(t, pager.lineIndex().Index(), 2)
+
+	assert.Error(t, pager.executeCommand("0"), "Line numbers start at 1, got 0")
+}
+
+func TestExecuteCommand_Filter(t *testing.T) {
+	pager := newCommandTestPager(t, "apa\nbepa\ncepa")
+
+	assert.NilError(t, pager.executeCommand("filter epa"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 2)
+
+	assert.NilError(t, pager.executeCommand("filter"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 3)
+}
+
+func TestExecuteCommand_Unknown(t *testing.T) {
+	pager := newCommandTestPager(t, "hello")
+
+	assert.Error(t, pager.executeCommand("bogus arg"), "Unknown command: bogus")
+
+	// Unknown commands should be reported to the user
+	pager.runCommand("bogus")
+	info, isInfo := pager.mode.(*PagerModeInfo)
+	assert.Assert(t, isInfo)
+	assert.Equal(t, info.Text, "Unknown command: bogus")
+}
+
+func TestExecuteCommand_Write(t *testing.T) {
+	pager := newCommandTestPager(t, "first\nsecond")
+	fileName := filepath.Join(t.TempDir(), "saved.txt")
+
+	assert.NilError(t, pager.executeCommand("write "+fileName))
+	contents, err := os.ReadFile(fileName)
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "first\nsecond\n")
+
+	assert.ErrorContains(t, pager.executeCommand("w "+fileName), "already exists")
+	assert.NilError(t, pager.executeCommand("w! "+fileName))
+}
+
+func TestExecuteCommand_WriteReadsAllLines(t *testing.T) {
+	pauseAfterLines := 1
+	r, err := reader.NewFromStream("paused", strings.NewReader("one\ntwo\nthree\n"), formatters.TTY,
+		reader.ReaderOptions{PauseAfterLines: &pauseAfterLines})
+	assert.NilError(t, err)
+	//revive:disable-next-line:empty-block
+	for !r.PauseStatus.Load() {
+	}
+
+	pager := NewPager(r)
+	pager.screen = twin.NewFakeScreen(20, 10)
+	fileName := filepath.Join(t.TempDir(), "saved.txt")
+
+	// Don't save just the first line
+	assert.NilError(t, pager.executeCommand("write "+fileName))
+	_, isReadingAll := pager.mode.(*PagerModeReadAll)
+	assert.Assert(t, isReadingAll)
+	_, err = os.Stat(fileName)
+	assert.Assert(t, os.IsNotExist(err))
+
+	//revive:disable-next-line:empty-block
+	for !r.ReadingDone.Load() {
+	}
+	pager.maybeDoneReadingAll()
+
+	contents, err := os.ReadFile(fileName)
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "one\ntwo\nthree\n")
+	assert.Equal(t, pager.mode.(*PagerModeInfo).Text, "Saved 3 lines to "+fileName)
+	assert.Equal(t, r.PauseAfterLines(), 1)
+}
+
+func TestExecuteCommand_Edit(t *testing.T) {
+	pager := newCommandTestPager(t, "first")
+	fileName := filepath.Join(t.TempDir(), "other.txt")
+	assert.NilError(t, os.WriteFile(fileName, []byte("other\n"), 0o600))
+
+	assert.NilError(t, pager.executeCommand("e "+fileName))
+	assert.Equal(t, len(pager.readers), 2)
+	assert.Equal(t, pager.currentReader, 1)
+	assert.NilError(t, pager.readers[1].Wait())
+	assert.Equal(t, pager.Reader().GetLine(linemetadata.Index{}).Plain(), "other")
+
+	assert.Assert(t, pager.executeCommand("e /does/not/exist") != nil)
+}
+
+func TestCompleteCommand(t *testing.T) {
+	pager := newCommandTestPager(t, "hello")
+
+	completed, candidates := pager.completeCommand("ed")
+	assert.Equal(t, completed, "edit ")
+	assert.Assert(t, candidates == nil)
+
+	completed, candidates = pager.completeCommand("wr")
+	assert.Equal(t, completed, "write")
+	assert.DeepEqual(t, candidates, []string{"write", "write!"})
+
+	completed, _ = pager.completeCommand("set wrap nol")
+	assert.Equal(t, completed, "set wrap nolinenumbers ")
+
+	completed, candidates = pager.completeCommand("set no")
+	assert.Equal(t, completed, "set no")
+	assert.DeepEqual(t, candidates, []string{"nowrap", "nolinenumbers", "nostatusbar", "noscrollbar", "nokeepnonjson", "nocolumns", "nounfoldjson"})
+
+	completed, candidates = pager.completeCommand("bogus x")
+	assert.Equal(t, completed, "bogus x")
+	assert.Assert(t, candidates == nil)
+}
+
+func TestColonCommandMode(t *testing.T) {
+	pager := newCommandTestPager(t, "hello")
+
+	pager.mode = NewPagerModeColonCommand(pager)
+	for _, char := range "set wr" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onRune('\t')
+	pager.mode.onKey(twin.KeyEnter)
+
+	assert.Assert(t, pager.WrapLongLines)
+	assert.Assert(t, pager.isViewing())
+	assert.DeepEqual(t, pager.commandHistory.entries, []string{"set wrap"})
+}
+
+// Commands starting with the single letter shortcuts must be possible to type
+func TestColonCommandModeSingleLetters(t *testing.T) {
+	pager := newCommandTestPager(t, "hello")
+
+	pager.mode = NewPagerModeColonCommand(pager)
+	pager.mode.onRune('p')
+	assert.Equal(t, pager.mode.(*PagerModeColonCommand).inputBox.text, "p")
+	for _, char := range "ipe cat" {
+		pager.mode.onRune(char)
+	}
+	assert.Equal(t, pager.mode.(*PagerModeColonCommand).inputBox.text, "pipe cat")
+
+	pager.mode.onKey(twin.KeyEscape)
+	pager.mode = NewPagerModeColonCommand(pager)
+	pager.mode.onRune('q')
+	assert.Assert(t, !pager.quit)
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Assert(t, pager.quit)
+}
+
+func TestCompletePath(t *testing.T) {
+	pager := newCommandTestPager(t, "hello")
+	dir := t.TempDir()
+	assert.NilError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0o700))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "subdir", "file.txt"), []byte("x"), 0o600))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "summary.log"), []byte("x"), 0o600))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0o600))
+
+	sep := string(os.PathSeparator)
+
+	completed, candidates := pager.completeCommand("e " + dir + sep + "su")
+	assert.Equal(t, completed, "e "+dir+sep+"su")
+	assert.DeepEqual(t, candidates, []string{"subdir" + sep, "summary.log"})
+
+	// Directories don't get a trailing space, so that we can continue into them
+	completed, _ = pager.completeCommand("e " + dir + sep + "sub")
+	assert.Equal(t, completed, "e "+dir+sep+"subdir"+sep)
+
+	completed, _ = pager.completeCommand(completed)
+	assert.Equal(t, completed, "e "+dir+sep+"subdir"+sep+"file.txt ")
+
+	// Hidden files only when asked for
+	_, candidates = pager.completeCommand("e " + dir + sep)
+	assert.DeepEqual(t, candidates, []string{"subdir" + sep, "summary.log"})
+	completed, _ = pager.completeCommand("e " + dir + sep + ".")
+	assert.Equal(t, completed, "e "+dir+sep+".hidden ")
+}
+
+func TestCommonPrefix(t *testing.T) {
+	assert.Equal(t, commonPrefix([]string{"subdir", "summary"}), "su")
+	assert.Equal(t, commonPrefix([]string{"a", "b"}), "")
+	assert.Equal(t, commonPrefix([]string{"same", "same"}), "same")
+
+	// "ä" and "å" share their first UTF-8 byte, which isn't a character
+	assert.Equal(t, commonPrefix([]string{"bär", "bår"}), "b")
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	assert.NilError(t, err)
+
+	assert.Equal(t, expandHome("~"), home)
+	assert.Equal(t, expandHome("~/x.txt"), filepath.Join(home, "x.txt"))
+	assert.Equal(t, expandHome("~x.txt"), "~x.txt")
+	assert.Equal(t, expandHome("x/~"), "x/~")
+}
+
+func TestExecuteCommand_WriteFiltered(t *testing.T) {
+	pager := newCommandTestPager(t, "\x1b[31mred\x1b[m apa\nbepa\n\x1b[32mgreen\x1b[m apa")
+	dir := t.TempDir()
+
+	assert.NilError(t, pager.executeCommand("filter apa"))
+
+	assert.NilError(t, pager.executeCommand("write "+filepath.Join(dir, "plain.txt")))
+	contents, err := os.ReadFile(filepath.Join(dir, "plain.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "red apa\ngreen apa\n")
+
+	assert.NilError(t, pager.executeCommand("write -raw "+filepath.Join(dir, "raw.txt")))
+	contents, err = os.ReadFile(filepath.Join(dir, "raw.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "\x1b[31mred\x1b[m apa\n\x1b[32mgreen\x1b[m apa\n")
+
+	assert.NilError(t, pager.executeCommand("write -all -raw "+filepath.Join(dir, "all.txt")))
+	contents, err = os.ReadFile(filepath.Join(dir, "all.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "\x1b[31mred\x1b[m apa\nbepa\n\x1b[32mgreen\x1b[m apa\n")
+
+	completed, _ := pager.completeCommand("w -raw " + dir + string(os.PathSeparator) + "pl")
+	assert.Equal(t, completed, "w -raw "+dir+string(os.PathSeparator)+"plain.txt ")
+}
+
+// The "--" dividers between groups of lines are for showing, not for saving
+func TestExecuteCommand_WriteSkipsDividers(t *testing.T) {
+	pager := newCommandTestPager(t, "a\n1\n2\n3\n4\na")
+	fileName := filepath.Join(t.TempDir(), "saved.txt")
+
+	assert.NilError(t, pager.executeCommand("set context 1"))
+	assert.NilError(t, pager.executeCommand("filter a"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 5)
+
+	assert.NilError(t, pager.executeCommand("write "+fileName))
+	contents, err := os.ReadFile(fileName)
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "a\n1\n4\na\n")
+}
+
+// Lines are written in batches, make sure none go missing between them
+func TestExecuteCommand_WriteManyLines(t *testing.T) {
+	lines := []string{}
+	for i := range 2*writeBatchSize + 5 {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	pager := newCommandTestPager(t, strings.Join(lines, "\n"))
+	fileName := filepath.Join(t.TempDir(), "saved.txt")
+
+	assert.NilError(t, pager.executeCommand("write "+fileName))
+	contents, err := os.ReadFile(fileName)
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), strings.Join(lines, "\n")+"\n")
+}
+
+func TestExecuteCommand_Highlight(t *testing.T) {
+	pager := newCommandTestPager(t, "req=42 user=7")
+
+	assert.NilError(t, pager.executeCommand("highlight req"))
+	assert.NilError(t, pager.executeCommand("hl user"))
+	assert.Equal(t, len(pager.highlights), 2)
+
+	highlights := pager.highlightsForRendering()
+	assert.Equal(t, highlights[0].Style, highlightStyles[0])
+	assert.Equal(t, highlights[1].Style, highlightStyles[1])
+
+	assert.NilError(t, pager.executeCommand("unhighlight 1"))
+	assert.Equal(t, pager.highlights[0].String(), "user")
+	assert.Error(t, pager.executeCommand("unhighlight 2"), "Expected a highlight number between 1 and 1, got: 2")
+
+	assert.NilError(t, pager.executeCommand("highlight"))
+	assert.Equal(t, len(pager.highlights), 0)
+	assert.Error(t, pager.executeCommand("unhighlight"), "No highlights to remove")
+}
+
+func TestHighlightMode(t *testing.T) {
+	pager := newCommandTestPager(t, "req=42 user=7")
+	assert.NilError(t, pager.executeCommand("highlight req"))
+
+	pager.mode = PagerModeViewing{pager: pager}
+	pager.mode.onRune('H')
+	for _, char := range "user" {
+		pager.mode.onRune(char)
+	}
+	assert.Equal(t, len(pager.highlights), 2)
+
+	// ESC drops only the highlight being typed
+	pager.mode.onKey(twin.KeyEscape)
+	assert.Equal(t, len(pager.highlights), 1)
+	assert.Equal(t, pager.highlights[0].String(), "req")
+
+	// ENTER without typing anything doesn't add anything
+	pager.mode.onRune('H')
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Equal(t, len(pager.highlights), 1)
+}
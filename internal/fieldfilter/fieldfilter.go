@@ -0,0 +1,518 @@
+// Filter JSON lines on their field values, like this:
+//
+//	.level == "error" && .latency_ms > 500
+//
+// Fields are written like in jq, ".a.b" is field b inside of field a, ".a[0]"
+// is the first element of array a, and ."odd name" is a field with spaces in
+// its name.
+//
+// Comparisons are ==, !=, <, <=, >, >= and =~ (regexp match). A field on its
+// own means it exists and isn't null or false, see also HasComparison().
+// Combine with &&, || and !, and use parentheses for grouping.
+package fieldfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Expression struct {
+	source string
+	root   node
+}
+
+type node interface {
+	evaluate(document any) bool
+}
+
+// Parse a field filter expression. Anything that isn't a valid expression is
+// an error, so that callers can fall back to treating it as a search.
+func Parse(s string) (*Expression, error) {
+	p := &parser{input: s}
+	p.skipSpaces()
+	if !p.startsExpression() {
+		return nil, fmt.Errorf("field filters start with '.', '!' or '(': %s", s)
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.position:], p.position)
+	}
+
+	return &Expression{source: strings.TrimSpace(s), root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// True if there's at least one comparison, like `.level == "error"`. Without
+// one, something like ".java" is more likely a search than a field filter.
+func (e *Expression) HasComparison() bool {
+	return hasComparison(e.root)
+}
+
+func hasComparison(n node) bool {
+	switch n := n.(type) {
+	case comparisonNode:
+		return true
+	case notNode:
+		return hasComparison(n.operand)
+	case andNode:
+		return hasComparison(n.left) || hasComparison(n.right)
+	case orNode:
+		return hasComparison(n.left) || hasComparison(n.right)
+	}
+
+	return false
+}
+
+// isJSON is false if the line isn't a JSON object. In that case matches is
+// always false, and it's up to the caller what to do with the line.
+func (e *Expression) Matches(line string) (matches bool, isJSON bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		// Fast path for non-JSON lines
+		return false, false
+	}
+
+	var document any
+	if json.Unmarshal([]byte(trimmed), &document) != nil {
+		return false, false
+	}
+
+	return e.root.evaluate(document), true
+}
+
+type pathElement struct {
+	field string
+	index int // Only used if field is empty
+}
+
+type path []pathElement
+
+// Returns false if there is no such field
+func (p path) lookup(document any) (any, bool) {
+	value := document
+	for _, element := range p {
+		if element.field != "" {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			value, ok = object[element.field]
+			if !ok {
+				return nil, false
+			}
+			continue
+		}
+
+		array, ok := value.([]any)
+		if !ok || element.index < 0 || element.index >= len(array) {
+			return nil, false
+		}
+		value = array[element.index]
+	}
+
+	return value, true
+}
+
+type existsNode struct {
+	path path
+}
+
+func (n existsNode) evaluate(document any) bool {
+	value, found := n.path.lookup(document)
+	return found && value != nil && value != false
+}
+
+type comparisonNode struct {
+	path     path
+	operator string
+	literal  any            // float64, string, bool or nil
+	regexp   *regexp.Regexp // Only for =~
+}
+
+func (n comparisonNode) evaluate(document any) bool {
+	value, found := n.path.lookup(document)
+
+	switch n.operator {
+	case "==":
+		return equals(value, n.literal)
+	case "!=":
+		return !equals(value, n.literal)
+	case "=~":
+		if !found {
+			return false
+		}
+		text, ok := asString(value)
+		return ok && n.regexp.MatchString(text)
+	}
+
+	if !found {
+		return false
+	}
+
+	comparison, ok := compare(value, n.literal)
+	if !ok {
+		return false
+	}
+
+	switch n.operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	}
+
+	panic("Unknown operator: " + n.operator)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) evaluate(document any) bool {
+	return !n.operand.evaluate(document)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) evaluate(document any) bool {
+	return n.left.evaluate(document) && n.right.evaluate(document)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) evaluate(document any) bool {
+	return n.left.evaluate(document) || n.right.evaluate(document)
+}
+
+// Numbers in strings count as numbers, log emitters aren't always consistent
+// about that
+func asNumber(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case string:
+		number, err := strconv.ParseFloat(value, 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+func asString(value any) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	}
+
+	return "", false
+}
+
+// Missing fields are equal to null
+func equals(value any, literal any) bool {
+	if number, ok := literal.(float64); ok {
+		valueNumber, ok := asNumber(value)
+		return ok && valueNumber == number
+	}
+
+	switch value.(type) {
+	case string, bool, nil:
+		return value == literal
+	}
+
+	// Objects and arrays
+	return false
+}
+
+// Numbers compare numerically and strings lexically. Returns false if the
+// types don't match.
+func compare(value any, literal any) (int, bool) {
+	switch literal := literal.(type) {
+	case float64:
+		number, ok := asNumber(value)
+		if !ok {
+			return 0, false
+		}
+		if number < literal {
+			return -1, true
+		}
+		if number > literal {
+			return 1, true
+		}
+		return 0, true
+
+	case string:
+		text, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(text, literal), true
+	}
+
+	return 0, false
+}
+
+type parser struct {
+	input    string
+	position int
+}
+
+func (p *parser) atEnd() bool {
+	return p.position >= len(p.input)
+}
+
+func (p *parser) peek() byte {
+	if p.atEnd() {
+		return 0
+	}
+	return p.input[p.position]
+}
+
+func (p *parser) skipSpaces() {
+	for !p.atEnd() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.position++
+	}
+}
+
+// Consume token if it's next. Call skipSpaces() first.
+func (p *parser) accept(token string) bool {
+	if strings.HasPrefix(p.input[p.position:], token) {
+		p.position += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *parser) startsExpression() bool {
+	switch p.peek() {
+	case '.', '!', '(':
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.accept("||") {
+			return left, nil
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.accept("&&") {
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	p.skipSpaces()
+
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.accept(")") {
+			return nil, fmt.Errorf("expected ')' at position %d", p.position)
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+// Longest first, so that "<=" isn't taken for "<"
+var operators = []string{"==", "!=", "=~", "<=", ">=", "<", ">"}
+
+func (p *parser) parseComparison() (node, error) {
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	operator := ""
+	for _, candidate := range operators {
+		if p.accept(candidate) {
+			operator = candidate
+			break
+		}
+	}
+	if operator == "" {
+		return existsNode{path: path}, nil
+	}
+
+	p.skipSpaces()
+	literal, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := comparisonNode{path: path, operator: operator, literal: literal}
+	if operator == "=~" {
+		pattern, ok := literal.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string regexp after =~ at position %d", p.position)
+		}
+		comparison.regexp, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return comparison, nil
+}
+
+func isFieldNameByte(b byte) bool {
+	return b == '_' || b == '-' || b >= 0x80 || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+func (p *parser) parsePath() (path, error) {
+	if !p.accept(".") {
+		return nil, fmt.Errorf("expected a field like .name at position %d", p.position)
+	}
+
+	result := path{}
+	for {
+		switch {
+		case p.peek() == '"':
+			name, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, pathElement{field: name})
+
+		case isFieldNameByte(p.peek()):
+			start := p.position
+			for !p.atEnd() && isFieldNameByte(p.peek()) {
+				p.position++
+			}
+			result = append(result, pathElement{field: p.input[start:p.position]})
+
+		default:
+			return nil, fmt.Errorf("expected a field name at position %d", p.position)
+		}
+
+		// Any number of array indices
+		for p.accept("[") {
+			start := p.position
+			for !p.atEnd() && p.peek() >= '0' && p.peek() <= '9' {
+				p.position++
+			}
+			index, err := strconv.Atoi(p.input[start:p.position])
+			if err != nil || !p.accept("]") {
+				return nil, fmt.Errorf("expected an array index like [0] at position %d", start)
+			}
+			result = append(result, pathElement{index: index})
+		}
+
+		if !p.accept(".") {
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) parseString() (string, error) {
+	start := p.position
+	p.position++ // Skip the opening quote
+	for !p.atEnd() && p.peek() != '"' {
+		if p.peek() == '\\' {
+			p.position++
+		}
+		p.position++
+	}
+	if !p.accept("\"") {
+		return "", fmt.Errorf("unterminated string at position %d", start)
+	}
+
+	return strconv.Unquote(p.input[start:p.position])
+}
+
+func (p *parser) parseLiteral() (any, error) {
+	if p.peek() == '"' {
+		return p.parseString()
+	}
+
+	for _, keyword := range []string{"true", "false", "null"} {
+		if p.accept(keyword) {
+			switch keyword {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+			return nil, nil
+		}
+	}
+
+	start := p.position
+	for !p.atEnd() && strings.IndexByte("+-.0123456789eE", p.peek()) >= 0 {
+		p.position++
+	}
+	number, err := strconv.ParseFloat(p.input[start:p.position], 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected a string, a number, true, false or null at position %d", start)
+	}
+
+	return number, nil
+}
@@ -0,0 +1,103 @@
+package fieldfilter
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func assertMatches(t *testing.T, expression string, line string, expected bool) {
+	t.Helper()
+
+	parsed, err := Parse(expression)
+	assert.NilError(t, err)
+
+	matches, isJSON := parsed.Matches(line)
+	assert.Assert(t, isJSON, line)
+	assert.Equal(t, matches, expected, "%s on %s", expression, line)
+}
+
+func TestMatches(t *testing.T) {
+	line := `{"level": "error", "latency_ms": 750, "user": {"id": "7", "roles": ["admin"]}, "ok": false, "odd key": 1}`
+
+	assertMatches(t, `.level == "error" && .latency_ms > 500`, line, true)
+	assertMatches(t, `.level == "error" && .latency_ms > 1000`, line, false)
+	assertMatches(t, `.level == "info" || .latency_ms >= 750`, line, true)
+	assertMatches(t, `!(.level == "error")`, line, false)
+	assertMatches(t, `.level != "info"`, line, true)
+	assertMatches(t, `.level < "f"`, line, true)
+
+	// Nested fields and arrays
+	assertMatches(t, `.user.roles[0] == "admin"`, line, true)
+	assertMatches(t, `.user.roles[1] == "admin"`, line, false)
+	assertMatches(t, `."odd key" == 1`, line, true)
+
+	// Numbers in strings are numbers
+	assertMatches(t, `.user.id == 7`, line, true)
+	assertMatches(t, `.user.id <= 6`, line, false)
+
+	// Regexps
+	assertMatches(t, `.level =~ "^err"`, line, true)
+	assertMatches(t, `.latency_ms =~ "^7"`, line, true)
+
+	// Existence
+	assertMatches(t, `.user`, line, true)
+	assertMatches(t, `.ok`, line, false)
+	assertMatches(t, `.missing`, line, false)
+	assertMatches(t, `.missing == null`, line, true)
+	assertMatches(t, `.missing != "x"`, line, true)
+	assertMatches(t, `.missing > 5`, line, false)
+
+	// AND binds harder than OR
+	assertMatches(t, `.ok || .level == "error" && .latency_ms > 1000`, line, false)
+	assertMatches(t, `.level == "error" || .ok && .missing`, line, true)
+}
+
+func TestNotJSON(t *testing.T) {
+	parsed, err := Parse(`.level == "error"`)
+	assert.NilError(t, err)
+
+	matches, isJSON := parsed.Matches("level=error")
+	assert.Assert(t, !matches)
+	assert.Assert(t, !isJSON)
+
+	_, isJSON = parsed.Matches("{broken")
+	assert.Assert(t, !isJSON)
+}
+
+func TestHasComparison(t *testing.T) {
+	for expression, expected := range map[string]bool{
+		`.level == "error"`:      true,
+		`!(.a || .b > 1)`:        true,
+		`.user && .level =~ "x"`: true,
+		`.java`:                  false,
+		`(.txt)`:                 false,
+		`!.a && .b`:              false,
+	} {
+		parsed, err := Parse(expression)
+		assert.NilError(t, err)
+		assert.Equal(t, parsed.HasComparison(), expected, expression)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expression := range []string{
+		"error",
+		".*error",
+		".level ==",
+		".level == error",
+		`.level == "error`,
+		`(.level == "error"`,
+		`.level =~ 5`,
+		`.level =~ "("`,
+		`.a[x]`,
+		".",
+	} {
+		_, err := Parse(expression)
+		assert.Assert(t, err != nil, expression)
+	}
+
+	parsed, err := Parse(`  .level == "error"  `)
+	assert.NilError(t, err)
+	assert.Equal(t, parsed.String(), `.level == "error"`)
+}
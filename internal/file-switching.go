@@ -1,12 +1,20 @@
 package internal
 
 import (
+	"fmt"
+	"slices"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
 	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/reader"
 )
 
 func (p *Pager) previousFile() {
 	p.readerLock.Lock()
 	defer p.readerLock.Unlock()
+	p.rememberPositionUnlocked()
 
 	newIndex := p.currentReader - 1
 	if newIndex < 0 {
@@ -15,15 +23,13 @@ func (p *Pager) previousFile() {
 	p.currentReader = newIndex
 	log.Tracef("Switched to previous file, index %d", p.currentReader)
 
-	select {
-	case p.readerSwitched <- struct{}{}:
-	default:
-	}
+	p.readerSwitchedUnlocked()
 }
 
 func (p *Pager) nextFile() {
 	p.readerLock.Lock()
 	defer p.readerLock.Unlock()
+	p.rememberPositionUnlocked()
 
 	newIndex := p.currentReader + 1
 	if newIndex >= len(p.readers) {
@@ -32,21 +38,117 @@ func (p *Pager) nextFile() {
 	p.currentReader = newIndex
 	log.Tracef("Switched to next file, index %d", p.currentReader)
 
-	select {
-	case p.readerSwitched <- struct{}{}:
-	default:
-	}
+	p.readerSwitchedUnlocked()
 }
 
 func (p *Pager) firstFile() {
 	p.readerLock.Lock()
 	defer p.readerLock.Unlock()
+	p.rememberPositionUnlocked()
 
 	p.currentReader = 0
 	log.Tracef("Switched to first file, index %d", p.currentReader)
 
-	select {
-	case p.readerSwitched <- struct{}{}:
-	default:
+	p.readerSwitchedUnlocked()
+}
+
+func (p *Pager) switchToFile(index int) {
+	p.readerLock.Lock()
+	defer p.readerLock.Unlock()
+	p.rememberPositionUnlocked()
+
+	p.currentReader = index
+	log.Tracef("Switched to file index %d", p.currentReader)
+
+	p.readerSwitchedUnlocked()
+}
+
+// Like "[2/3] file.txt"
+func (p *Pager) describeFileUnlocked(index int) string {
+	description := fmt.Sprintf("[%d/%d]", index+1, len(p.readers))
+	if displayName := p.readers[index].DisplayName; displayName != nil {
+		description += " " + *displayName
+	}
+
+	return description
+}
+
+// Switch to a view of all files merged by timestamp. The merged view is
+// created on first use, and is then available as the last file.
+func (p *Pager) mergedFiles() {
+	p.readerLock.Lock()
+	defer p.readerLock.Unlock()
+	p.rememberPositionUnlocked()
+
+	if p.mergedReader == nil {
+		if len(p.readers) < 2 {
+			log.Debug("Need at least two files for a merged view, ignoring")
+			return
+		}
+
+		p.mergedReader = reader.NewMerged("Merged", p.readers)
+		p.readers = append(p.readers, p.mergedReader)
+	}
+
+	p.currentReader = slices.Index(p.readers, p.mergedReader)
+	log.Tracef("Switched to merged view, index %d", p.currentReader)
+
+	p.readerSwitchedUnlocked()
+}
+
+// Open another file and switch to it
+func (p *Pager) openFile(fileName string) error {
+	formatter, options := p.newReaderOptions()
+	newReader, err := reader.NewFromFilename(fileName, formatter, options)
+	if err != nil {
+		return err
 	}
+
+	p.addReader(newReader)
+	return nil
+}
+
+// Formatter and options for readers opened while paging, matching the ones we
+// got from the command line
+func (p *Pager) newReaderOptions() (chroma.Formatter, reader.ReaderOptions) {
+	var formatter chroma.Formatter = formatters.TTY256
+	if p.chromaFormatter != nil {
+		formatter = *p.chromaFormatter
+	}
+
+	options := p.ReaderOptions
+	options.Style = p.chromaStyle
+	if options.Style == nil {
+		options.Style = styles.Fallback
+	}
+
+	return formatter, options
+}
+
+// Append a reader and switch to it
+func (p *Pager) addReader(newReader *reader.ReaderImpl) {
+	p.readerLock.Lock()
+	defer p.readerLock.Unlock()
+	p.rememberPositionUnlocked()
+
+	p.readers = append(p.readers, newReader)
+	p.currentReader = len(p.readers) - 1
+	log.Debugf("Added reader %d", p.currentReader)
+
+	if p.mergedReader != nil {
+		p.mergedReader.AddMergeSource(newReader)
+	}
+
+	p.readerSwitchedUnlocked()
+}
+
+// Show the current reader in the focused pane. Call this with readerLock held
+// after changing currentReader.
+func (p *Pager) readerSwitchedUnlocked() {
+	*p.filter = filterChain{}
+	p.cancelPendingGoto()
+	p.filteringReader.SetBackingReader(p.readers[p.currentReader])
+	p.restorePositionUnlocked()
+
+	p.notifyReaderSwitched()
 }
@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/walles/moor/v2/internal/fieldfilter"
+	"github.com/walles/moor/v2/internal/search"
+)
+
+// One filter in a chain of filters
+type filterEntry struct {
+	search search.Search
+
+	// Set instead of search for JSON field filters, like ".level == 'error'"
+	fields *fieldfilter.Expression
+
+	// Hide matching lines rather than showing them, like "grep -v"
+	negated bool
+
+	// Combine with the entries before this one using OR rather than AND
+	or bool
+}
+
+// A number of filters, combined into one predicate. AND binds harder than OR,
+// so "a AND b OR c" means "(a AND b) OR c".
+//
+// Chains are immutable once created. That way they can be shared between
+// panes and FilteringReader caches without any copying.
+type filterChain struct {
+	entries []filterEntry
+}
+
+// Parse one filter entry. A leading "|" means OR rather than AND, and a
+// leading "!" means hiding matching lines. So "|!DEBUG" means "OR NOT DEBUG".
+// After those, a "~" means fuzzy matching.
+//
+// Anything that parses as a JSON field filter and compares something, like
+// ".latency_ms > 500", is one. Without a comparison, things like ".java" or
+// "(.txt)" are searches. See the fieldfilter package for the syntax.
+//
+// To search for any of those leading characters, escape them with a
+// backslash. "\!important" finds "!important", and "|\~x" means "OR ~x".
+func parseFilterEntry(s string) filterEntry {
+	entry := filterEntry{}
+	if strings.HasPrefix(s, "|") {
+		entry.or = true
+		s = s[1:]
+	}
+	if strings.HasPrefix(s, "!") {
+		entry.negated = true
+		s = s[1:]
+	}
+
+	if strings.HasPrefix(s, `\`) {
+		// As a regexp, "\!" matches "!", so no need to remove the backslash
+		entry.search = search.For(s)
+		return entry
+	}
+
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "(") {
+		if fields, err := fieldfilter.Parse(s); err == nil && fields.HasComparison() {
+			entry.fields = fields
+			return entry
+		}
+	}
+
+	modifiers := search.Modifiers{}
+	if strings.HasPrefix(s, "~") {
+		modifiers.Pattern = search.PatternFuzzy
+		s = s[1:]
+	}
+
+	entry.search = search.ForWithModifiers(s, modifiers)
+	return entry
+}
+
+// A chain with just one entry, or an empty chain if s is empty
+func filterChainFor(s string) filterChain {
+	if s == "" {
+		return filterChain{}
+	}
+
+	return filterChain{entries: []filterEntry{parseFilterEntry(s)}}
+}
+
+// Returns a new chain with the entry at index i replaced by s. If i is just
+// past the end, s is appended.
+func (c filterChain) with(i int, s string) filterChain {
+	entries := slices.Clone(c.entries)
+	if i == len(entries) {
+		entries = append(entries, parseFilterEntry(s))
+	} else {
+		entries[i] = parseFilterEntry(s)
+	}
+
+	return filterChain{entries: entries}
+}
+
+// Returns a new chain without the entry at index i
+func (c filterChain) without(i int) filterChain {
+	return filterChain{entries: slices.Delete(slices.Clone(c.entries), i, i+1)}
+}
+
+// Returns a new chain with only the first n entries
+func (c filterChain) truncated(n int) filterChain {
+	return filterChain{entries: slices.Clone(c.entries[:n])}
+}
+
+// What to highlight to show why lines passed the filter. That's the last entry
+// that isn't negated, or nothing.
+func (c filterChain) highlight() search.Search {
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		entry := c.entries[i]
+		if entry.search.Active() && !entry.negated {
+			return entry.search
+		}
+	}
+
+	return search.Search{}
+}
+
+func (e filterEntry) active() bool {
+	return e.fields != nil || e.search.Active()
+}
+
+// Whether this entry lets the line through, taking negation into account.
+// Lines that aren't JSON pass field filters only if keepNonJSON is set, negated
+// or not.
+func (e filterEntry) matches(line string, keepNonJSON bool) bool {
+	if e.fields == nil {
+		return e.search.Matches(line) != e.negated
+	}
+
+	matches, isJSON := e.fields.Matches(line)
+	if !isJSON {
+		return keepNonJSON
+	}
+	return matches != e.negated
+}
+
+func (e filterEntry) equals(other filterEntry) bool {
+	if e.negated != other.negated || e.or != other.or || !e.search.Equals(other.search) {
+		return false
+	}
+	if e.fields == nil || other.fields == nil {
+		return e.fields == other.fields
+	}
+	return e.fields.String() == other.fields.String()
+}
+
+func (e filterEntry) String() string {
+	if e.fields != nil {
+		return e.fields.String()
+	}
+	if e.search.Modifiers().Pattern == search.PatternFuzzy {
+		return "~" + e.search.String()
+	}
+	return e.search.String()
+}
+
+func (c filterChain) Active() bool {
+	for _, entry := range c.entries {
+		if entry.active() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c filterChain) Inactive() bool {
+	return !c.Active()
+}
+
+// Entries without any pattern (yet) are ignored. Lines that aren't JSON don't
+// pass any JSON field filters.
+func (c filterChain) Matches(line string) bool {
+	return c.MatchesKeepingNonJSON(line, false)
+}
+
+// Like Matches(), but lines that aren't JSON pass all JSON field filters if
+// keepNonJSON is set
+func (c filterChain) MatchesKeepingNonJSON(line string, keepNonJSON bool) bool {
+	matches := true // Result of the current AND group
+	first := true
+	for _, entry := range c.entries {
+		if !entry.active() {
+			continue
+		}
+
+		if entry.or && !first {
+			if matches {
+				// Nothing after this OR can make us not match
+				return true
+			}
+
+			// Start a new AND group
+			matches = true
+		}
+
+		first = false
+		if !matches {
+			continue
+		}
+
+		matches = entry.matches(line, keepNonJSON)
+	}
+
+	return matches
+}
+
+func (c filterChain) Equals(other filterChain) bool {
+	return slices.EqualFunc(c.entries, other.entries, filterEntry.equals)
+}
+
+// Like "ERROR AND NOT DEBUG OR panic"
+func (c filterChain) String() string {
+	result := ""
+	for _, entry := range c.entries {
+		if !entry.active() {
+			continue
+		}
+
+		if result != "" {
+			if entry.or {
+				result += " OR "
+			} else {
+				result += " AND "
+			}
+		}
+		if entry.negated {
+			result += "NOT "
+		}
+		result += entry.String()
+	}
+
+	return result
+}
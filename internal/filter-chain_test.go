@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFilterChainMatches(t *testing.T) {
+	chain := filterChainFor("ERROR").with(1, "!timeout").with(2, "|panic")
+	assert.Equal(t, chain.String(), "ERROR AND NOT timeout OR panic")
+
+	assert.Assert(t, chain.Matches("ERROR: disk full"))
+	assert.Assert(t, !chain.Matches("ERROR: timeout"))
+	assert.Assert(t, chain.Matches("panic: timeout"))
+	assert.Assert(t, !chain.Matches("INFO: all good"))
+}
+
+func TestFilterChainEmptyEntries(t *testing.T) {
+	// While the user is typing, the last entry may be empty. That shouldn't
+	// change anything.
+	chain := filterChainFor("ERROR").with(1, "|")
+	assert.Equal(t, chain.String(), "ERROR")
+	assert.Assert(t, chain.Matches("ERROR"))
+	assert.Assert(t, !chain.Matches("INFO"))
+
+	assert.Assert(t, filterChain{}.with(0, "!").Inactive())
+}
+
+func TestFilterChainEscapes(t *testing.T) {
+	important := filterChainFor(`\!important`)
+	assert.Assert(t, important.Matches("!important"))
+	assert.Assert(t, !important.Matches("important"))
+
+	assert.Assert(t, filterChainFor(`\|x`).Matches("a|x"))
+	assert.Assert(t, !filterChainFor(`\|x`).Matches("y"))
+	assert.Assert(t, filterChainFor(`\~x`).Matches("~x"))
+	assert.Assert(t, !filterChainFor(`\~x`).Matches("abxc"))
+	assert.Assert(t, !filterChainFor(`\.level == "error"`).Matches(`{"level": "error"}`))
+
+	// Escapes work after the prefixes too
+	chain := filterChainFor("ERROR").with(1, `!\!important`)
+	assert.Assert(t, chain.Matches("ERROR important"))
+	assert.Assert(t, !chain.Matches("ERROR !important"))
+}
+
+func TestFilterChainImmutable(t *testing.T) {
+	original := filterChainFor("a").with(1, "b")
+	modified := original.with(1, "c")
+	shorter := original.without(0)
+
+	assert.Equal(t, original.String(), "a AND b")
+	assert.Equal(t, modified.String(), "a AND c")
+	assert.Equal(t, shorter.String(), "b")
+	assert.Assert(t, !original.Equals(modified))
+	assert.Assert(t, original.Equals(filterChainFor("a").with(1, "b")))
+}
+
+func TestFilterChainHighlight(t *testing.T) {
+	chain := filterChainFor("ERROR").with(1, "!timeout")
+	assert.Equal(t, chain.highlight().String(), "ERROR")
+}
+
+func TestStackedFilters(t *testing.T) {
+	pager := newCommandTestPager(t, "ERROR disk\nERROR timeout\nINFO ok\npanic now")
+
+	assert.NilError(t, pager.executeCommand("filter ERROR"))
+	assert.NilError(t, pager.executeCommand("filter !timeout"))
+	assert.NilError(t, pager.executeCommand("filter |panic"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 2)
+
+	lines := pager.Reader().GetLines(linemetadata.Index{}, 2)
+	assert.Equal(t, lines.Lines[0].Plain(), "ERROR disk")
+	assert.Equal(t, lines.Lines[1].Plain(), "panic now")
+	assert.Equal(t, lines.StatusText, "Filtered by ERROR AND NOT timeout OR panic: 2/4 lines  100%")
+
+	assert.NilError(t, pager.executeCommand("unfilter 2"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 3)
+
+	assert.Error(t, pager.executeCommand("unfilter 5"), "Expected a filter number between 1 and 2, got: 5")
+
+	assert.NilError(t, pager.executeCommand("unfilter"))
+	assert.NilError(t, pager.executeCommand("unfilter"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 4)
+	assert.Error(t, pager.executeCommand("unfilter"), "No filters to remove")
+}
+
+func TestFilterModeStacks(t *testing.T) {
+	pager := newCommandTestPager(t, "ERROR disk\nERROR timeout\nINFO ok")
+
+	pager.mode = NewPagerModeFilter(pager)
+	for _, char := range "ERROR" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Equal(t, pager.Reader().GetLineCount(), 2)
+
+	pager.mode.onRune('&')
+	for _, char := range "!disk" {
+		pager.mode.onRune(char)
+	}
+	assert.Equal(t, pager.Reader().GetLineCount(), 1)
+
+	// ESC drops only the filter being typed
+	pager.mode.onKey(twin.KeyEscape)
+	assert.Equal(t, pager.filter.String(), "ERROR")
+	assert.Equal(t, pager.Reader().GetLineCount(), 2)
+}
+
+func TestFilterContext(t *testing.T) {
+	pager := newCommandTestPager(t, "a\nb\nMATCH 1\nc\nd\ne\nf\nMATCH 2\ng")
+	pager.ShowLineNumbers = false
+	pager.showLineNumbers = false
+
+	assert.NilError(t, pager.executeCommand("filter MATCH"))
+	assert.NilError(t, pager.executeCommand("set before 1 after=2"))
+	assert.Equal(t, pager.filterContext, filterContext{before: 1, after: 2})
+
+	rendered := pager.renderLines()
+	rows := []string{}
+	for _, line := range rendered.lines {
+		rows = append(rows, renderedToString(line.cells))
+	}
+	assert.DeepEqual(t, rows, []string{
+		"b",
+		"MATCH 1",
+		"c",
+		"d",
+		"╌╌╌╌╌╌╌╌╌╌╌╌╌╌╌╌╌╌╌╌",
+		"f",
+		"MATCH 2",
+		"g",
+	})
+
+	// Original line numbers survive
+	lines := pager.Reader().GetLines(linemetadata.Index{}, 10).Lines
+	assert.Equal(t, lines[5].Plain(), "f")
+	assert.Equal(t, lines[5].Number.AsOneBased(), 7)
+
+	// Context lines are dim, matches are not
+	assert.Assert(t, rendered.lines[0].cells[0].Style.Equal(twin.StyleDefault.WithAttr(twin.AttrDim)))
+	assert.Assert(t, !rendered.lines[1].cells[0].Style.Equal(twin.StyleDefault.WithAttr(twin.AttrDim)))
+
+	// Adjacent groups are merged without a divider
+	assert.NilError(t, pager.executeCommand("set context 3"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 9)
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	chain := filterChainFor("~cnrf")
+	assert.Assert(t, chain.Matches("connection refused"))
+	assert.Assert(t, !chain.Matches("refused connection"))
+	assert.Equal(t, chain.String(), "~cnrf")
+
+	chain = chain.with(1, "!~xyz")
+	assert.Assert(t, !chain.Matches("connection refused xyz"))
+	assert.Equal(t, chain.String(), "~cnrf AND NOT ~xyz")
+}
+
+func TestJSONFieldFilter(t *testing.T) {
+	pager := newCommandTestPager(t, strings.Join([]string{
+		`{"level": "error", "latency_ms": 750}`,
+		`{"level": "error", "latency_ms": 20}`,
+		`not JSON`,
+		`{"level": "info", "latency_ms": 900}`,
+	}, "\n"))
+
+	assert.NilError(t, pager.executeCommand(`filter .level == "error" && .latency_ms > 500`))
+	assert.Equal(t, pager.filter.String(), `.level == "error" && .latency_ms > 500`)
+	assert.Assert(t, pager.search.Inactive(), "Field filters have nothing to highlight")
+	assert.Equal(t, pager.Reader().GetLineCount(), 1)
+
+	// Non-JSON lines pass through on request, negated or not
+	assert.NilError(t, pager.executeCommand("set keepnonjson"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 2)
+	assert.Equal(t, pager.Reader().GetLine(linemetadata.Index{}.NonWrappingAdd(1)).Plain(), "not JSON")
+
+	*pager.filter = filterChainFor(`!.level == "error"`)
+	lines := pager.Reader().GetLines(linemetadata.Index{}, 10).Lines
+	assert.Equal(t, len(lines), 2)
+	assert.Equal(t, lines[0].Plain(), "not JSON")
+
+	assert.NilError(t, pager.executeCommand("set nokeepnonjson"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 1)
+
+	// Regexps that aren't field filters are still searches
+	assert.Assert(t, filterChainFor(".*JSON").Matches("not JSON"))
+	assert.Assert(t, filterChainFor("(not|yes) JSON").Matches("not JSON"))
+
+	// Without a comparison, these are searches for file names and such
+	assert.Assert(t, filterChainFor(".java").Matches("Main.java"))
+	assert.Assert(t, !filterChainFor(".java").Matches("Main.kt"))
+	assert.Assert(t, filterChainFor("(.txt)").Matches("notes(.txt)"))
+	assert.Assert(t, filterChainFor("!.NET").Matches("Java"))
+	assert.Assert(t, !filterChainFor("!.NET").Matches("ASP.NET"))
+
+	// Existence checks work together with a comparison
+	chain := filterChainFor(`.latency_ms && .level != "info"`)
+	assert.Assert(t, chain.Matches(`{"level": "error", "latency_ms": 750}`))
+	assert.Assert(t, !chain.Matches(`{"level": "error"}`))
+}
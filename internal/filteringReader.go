@@ -9,17 +9,38 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/walles/moor/v2/internal/linemetadata"
 	"github.com/walles/moor/v2/internal/reader"
-	"github.com/walles/moor/v2/internal/search"
 )
 
 // Filters lines based on the search query from the pager.
 
+// How many lines to show around each filter match, like "grep -B" and "grep -A"
+type filterContext struct {
+	before int
+	after  int
+}
+
+type filteredLineKind int
+
+const (
+	filteredLineMatch   filteredLineKind = iota // Or any line when not filtering
+	filteredLineContext                         // Close to a match
+	filteredLineDivider                         // Between non-adjacent groups of lines
+)
+
 type FilteringReader struct {
 	BackingReader reader.Reader
 
-	// This is a reference so that we can track changes to the original pattern,
-	// including if it is set to nil.
-	Filter *search.Search
+	// This is a reference so that we can track changes to the original filter
+	// chain, including if it is set to nil.
+	Filter *filterChain
+
+	// Optional, nil means no context lines. A reference for the same reason
+	// as Filter.
+	Context *filterContext
+
+	// Optional, nil means dropping non-JSON lines when filtering on JSON
+	// fields. A reference for the same reason as Filter.
+	KeepNonJSON *bool
 
 	// Protects filteredLinesCache, unfilteredLineCountWhenCaching, and
 	// filterPatternWhenCaching.
@@ -28,6 +49,9 @@ type FilteringReader struct {
 	// nil means no filtering has happened yet
 	filteredLinesCache *[]reader.NumberedLine
 
+	// What each line in filteredLinesCache is
+	filteredLineKinds []filteredLineKind
+
 	// This is what the reader's line count was when we filtered. If the
 	// reader's current line count doesn't match, then our cache needs to be
 	// rebuilt.
@@ -35,7 +59,28 @@ type FilteringReader struct {
 
 	// This is the pattern that was used when we cached the lines. If it
 	// doesn't match the current pattern, then our cache needs to be rebuilt.
-	filterWhenCaching search.Search
+	filterWhenCaching filterChain
+
+	// Same as filterWhenCaching, but for the context
+	contextWhenCaching filterContext
+
+	// Same as filterWhenCaching, but for KeepNonJSON
+	keepNonJSONWhenCaching bool
+}
+
+// The divider we show between non-adjacent groups of lines, like "grep -C"
+// does
+var filterDivider = reader.NewLine("--")
+
+func (f *FilteringReader) currentContext() filterContext {
+	if f.Context == nil {
+		return filterContext{}
+	}
+	return *f.Context
+}
+
+func (f *FilteringReader) keepNonJSON() bool {
+	return f.KeepNonJSON != nil && *f.KeepNonJSON
 }
 
 // Please hold the lock when calling this method.
@@ -43,30 +88,71 @@ func (f *FilteringReader) rebuildCache() {
 	t0 := time.Now()
 
 	cache := make([]reader.NumberedLine, 0)
+	kinds := make([]filteredLineKind, 0)
 	filter := *f.Filter
+	context := f.currentContext()
+	keepNonJSON := f.keepNonJSON()
 
 	// Mark cache base conditions
 	f.unfilteredLineCountWhenCaching = f.BackingReader.GetLineCount()
 	f.filterWhenCaching = filter
+	f.contextWhenCaching = context
+	f.keepNonJSONWhenCaching = keepNonJSON
 
-	// Repopulate the cache
+	// Figure out which lines to keep
 	allBaseLines := f.BackingReader.GetLines(linemetadata.Index{}, math.MaxInt)
-	resultIndex := 0
-	for _, line := range allBaseLines.Lines {
-		if filter.Active() && !filter.Matches(line.Line.Plain(line.Index)) {
+	lineKinds := make([]*filteredLineKind, len(allBaseLines.Lines))
+	match := filteredLineMatch
+	contextKind := filteredLineContext
+	keepContextUntil := -1
+	for i, line := range allBaseLines.Lines {
+		if filter.Active() && !filter.MatchesKeepingNonJSON(line.Line.Plain(line.Index), keepNonJSON) {
 			// We have a pattern but it doesn't match
+			if i <= keepContextUntil {
+				lineKinds[i] = &contextKind
+			}
+			continue
+		}
+
+		lineKinds[i] = &match
+		for before := max(0, i-context.before); before < i; before++ {
+			if lineKinds[before] == nil {
+				lineKinds[before] = &contextKind
+			}
+		}
+		keepContextUntil = i + context.after
+	}
+
+	// Repopulate the cache
+	resultIndex := 0
+	previousKept := -1
+	for i, line := range allBaseLines.Lines {
+		if lineKinds[i] == nil {
 			continue
 		}
 
+		if (context.before > 0 || context.after > 0) && previousKept >= 0 && previousKept != i-1 {
+			cache = append(cache, reader.NumberedLine{
+				Line:   filterDivider,
+				Index:  linemetadata.IndexFromZeroBased(resultIndex),
+				Number: line.Number,
+			})
+			kinds = append(kinds, filteredLineDivider)
+			resultIndex++
+		}
+		previousKept = i
+
 		cache = append(cache, reader.NumberedLine{
 			Line:   line.Line,
 			Index:  linemetadata.IndexFromZeroBased(resultIndex),
 			Number: line.Number,
 		})
+		kinds = append(kinds, *lineKinds[i])
 		resultIndex++
 	}
 
 	f.filteredLinesCache = &cache
+	f.filteredLineKinds = kinds
 
 	log.Debugf("Filtered out %d/%d lines in %s",
 		len(allBaseLines.Lines)-len(cache), len(allBaseLines.Lines), time.Since(t0))
@@ -86,11 +172,11 @@ func (f *FilteringReader) getAllLines() []reader.NumberedLine {
 		return *f.filteredLinesCache
 	}
 
-	var currentFilterPattern search.Search
+	var currentFilterPattern filterChain
 	if (*f).Filter.Active() {
 		currentFilterPattern = *f.Filter
 	}
-	var cacheFilterPattern search.Search
+	var cacheFilterPattern filterChain
 	if f.filterWhenCaching.Active() {
 		cacheFilterPattern = f.filterWhenCaching
 	}
@@ -99,6 +185,16 @@ func (f *FilteringReader) getAllLines() []reader.NumberedLine {
 		return *f.filteredLinesCache
 	}
 
+	if f.currentContext() != f.contextWhenCaching {
+		f.rebuildCache()
+		return *f.filteredLinesCache
+	}
+
+	if f.keepNonJSON() != f.keepNonJSONWhenCaching {
+		f.rebuildCache()
+		return *f.filteredLinesCache
+	}
+
 	return *f.filteredLinesCache
 }
 
@@ -117,6 +213,55 @@ func (f *FilteringReader) shouldPassThrough() bool {
 	return false
 }
 
+// The lines we have filtered so far, or nil if we aren't filtering. Unlike the
+// FilteringReader itself, this never changes, so it's fine to use from other
+// goroutines.
+func (f *FilteringReader) snapshot() reader.Reader {
+	if f.shouldPassThrough() {
+		return nil
+	}
+
+	return filteredLines(f.getAllLines())
+}
+
+// Tells whether a line is a match, a context line or a divider
+func (f *FilteringReader) lineKind(index linemetadata.Index) filteredLineKind {
+	if f.shouldPassThrough() {
+		return filteredLineMatch
+	}
+
+	allLines := f.getAllLines()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if index.Index() < 0 || index.Index() >= len(allLines) || len(f.filteredLineKinds) != len(allLines) {
+		return filteredLineMatch
+	}
+
+	return f.filteredLineKinds[index.Index()]
+}
+
+// Indices of the lines actually matching the filter. Empty unless we're showing
+// context lines, because otherwise all lines match.
+func (f *FilteringReader) matchIndices() []int {
+	if f.shouldPassThrough() || f.currentContext() == (filterContext{}) {
+		return nil
+	}
+
+	f.getAllLines() // Make sure the cache is up to date
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	indices := []int{}
+	for i, kind := range f.filteredLineKinds {
+		if kind == filteredLineMatch {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
 func (f *FilteringReader) GetLineCount() int {
 	if f.shouldPassThrough() {
 		return f.BackingReader.GetLineCount()
@@ -185,11 +330,16 @@ func (f *FilteringReader) GetLinesPreallocated(firstLine linemetadata.Index, res
 }
 
 // In the general case, this will return a text like this:
-// "Filtered: 1234/5678 lines  22%"
+// "Filtered by ERROR AND NOT DEBUG: 1234/5678 lines  22%"
 func (f *FilteringReader) createStatus(lastLine *linemetadata.Index) string {
+	prefix := "Filtered: "
+	if f.Filter != nil && f.Filter.Active() {
+		prefix = "Filtered by " + f.Filter.String() + ": "
+	}
+
 	baseCount := f.BackingReader.GetLineCount()
 	if baseCount == 0 {
-		return "Filtered: No input lines"
+		return prefix + "No input lines"
 	}
 
 	baseCountString := "/" + linemetadata.IndexFromLength(baseCount).Format()
@@ -199,7 +349,7 @@ func (f *FilteringReader) createStatus(lastLine *linemetadata.Index) string {
 
 	if lastLine == nil {
 		// 100% because we're showing all 0 lines
-		return "Filtered: 0" + baseCountString + " lines  100%"
+		return prefix + "0" + baseCountString + " lines  100%"
 	}
 
 	acceptedCount := f.GetLineCount()
@@ -212,8 +362,8 @@ func (f *FilteringReader) createStatus(lastLine *linemetadata.Index) string {
 		lineString += "s"
 	}
 
-	return fmt.Sprintf("Filtered: %s%s %s  %d%%",
-		acceptedCountString, baseCountString, lineString, percent)
+	return fmt.Sprintf("%s%s%s %s  %d%%",
+		prefix, acceptedCountString, baseCountString, lineString, percent)
 }
 
 // SetBackingReader switches the underlying reader while holding the lock and
@@ -230,6 +380,37 @@ func (f *FilteringReader) SetBackingReader(r reader.Reader) {
 
 	// Invalidate caches so they will be rebuilt lazily on next access.
 	f.filteredLinesCache = nil
+	f.filteredLineKinds = nil
 	f.unfilteredLineCountWhenCaching = -1
-	f.filterWhenCaching = search.Search{}
+	f.filterWhenCaching = filterChain{}
+}
+
+// Already filtered lines, see FilteringReader.snapshot()
+type filteredLines []reader.NumberedLine
+
+func (lines filteredLines) GetLineCount() int {
+	return len(lines)
+}
+
+func (lines filteredLines) GetLine(index linemetadata.Index) *reader.NumberedLine {
+	if index.Index() < 0 || index.Index() >= len(lines) {
+		return nil
+	}
+	return &lines[index.Index()]
+}
+
+func (lines filteredLines) GetLines(firstLine linemetadata.Index, wantedLineCount int) reader.InputLines {
+	// Honor wantedLineCount over firstLine, just like the other readers
+	first := max(0, min(firstLine.Index(), len(lines)-wantedLineCount))
+	last := min(len(lines), first+wantedLineCount)
+	return reader.InputLines{Lines: lines[first:last]}
+}
+
+func (lines filteredLines) GetLinesPreallocated(firstLine linemetadata.Index, resultLines *[]reader.NumberedLine) (string, string) {
+	*resultLines = append((*resultLines)[:0], lines.GetLines(firstLine, cap(*resultLines)).Lines...)
+	return "", ""
+}
+
+func (lines filteredLines) ShouldShowLineCount() bool {
+	return true
 }
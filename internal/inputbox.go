@@ -13,6 +13,9 @@ type AcceptMode int
 const (
 	INPUTBOX_ACCEPT_ALL AcceptMode = iota
 	INPUTBOX_ACCEPT_POSITIVE_NUMBERS
+
+	// Line numbers, percentages like 50% and byte offsets like b1048576
+	INPUTBOX_ACCEPT_GOTO_TARGETS
 )
 
 type InputBox struct {
@@ -147,6 +150,11 @@ func (b *InputBox) handleRune(char rune) bool {
 			return false
 		}
 	}
+	if b.accept == INPUTBOX_ACCEPT_GOTO_TARGETS {
+		if !unicode.IsDigit(char) && char != '%' && char != 'b' {
+			return false
+		}
+	}
 
 	// Insert at cursor position
 	runes := []rune(b.text)
@@ -0,0 +1,98 @@
+// Show JSON lines records as indented blocks, like:
+//
+//	{
+//	  "level": "error",
+//	  "msg": "Connection refused"
+//	}
+//
+// rather than {"level":"error","msg":"Connection refused"}. Each block is still
+// one input line, so line numbers, marks and search hits keep pointing at the
+// original record.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/reader"
+)
+
+// Start over when we have this many records cached
+const maxUnfoldedRecords = 1000
+
+// Keep the current record at the top of the screen when folding or unfolding
+func (p *Pager) setUnfoldJSONRecords(unfold bool) {
+	p.UnfoldJSONRecords = unfold
+	if lineIndex := p.lineIndex(); lineIndex != nil {
+		p.scrollPosition = NewScrollPositionFromIndex(*lineIndex, "setUnfoldJSONRecords")
+	}
+}
+
+// Returns nil if the line should be shown as it is
+func (p *Pager) unfoldJSONRecord(line reader.NumberedLine) []reader.NumberedLine {
+	if !p.UnfoldJSONRecords || p.isShowingHelp {
+		return nil
+	}
+
+	plain := strings.TrimSpace(line.Plain())
+	if !strings.HasPrefix(plain, "{") && !strings.HasPrefix(plain, "[") {
+		// Cheap check before looking closer
+		return nil
+	}
+
+	rows, found := p.unfoldedRecords[plain]
+	if !found {
+		rows = p.renderJSONRecord(plain)
+		if len(p.unfoldedRecords) >= maxUnfoldedRecords {
+			p.unfoldedRecords = nil
+		}
+		if p.unfoldedRecords == nil {
+			p.unfoldedRecords = map[string][]*reader.Line{}
+		}
+		p.unfoldedRecords[plain] = rows
+	}
+
+	if len(rows) < 2 {
+		// Not JSON, or nothing to unfold
+		return nil
+	}
+
+	unfolded := make([]reader.NumberedLine, 0, len(rows))
+	for _, row := range rows {
+		unfolded = append(unfolded, reader.NumberedLine{
+			Index:  line.Index,
+			Number: line.Number,
+			Line:   row,
+		})
+	}
+	return unfolded
+}
+
+// Indent and highlight one JSON record. Returns nil if it isn't JSON.
+func (p *Pager) renderJSONRecord(record string) []*reader.Line {
+	var indented bytes.Buffer
+	err := json.Indent(&indented, []byte(record), "", "  ")
+	if err != nil {
+		return nil
+	}
+
+	text := indented.String()
+	if p.chromaStyle != nil && p.chromaFormatter != nil {
+		highlighted, err := reader.Highlight(text, *p.chromaStyle, *p.chromaFormatter, lexers.Get("json"))
+		if err != nil {
+			log.Debug("Highlighting JSON record failed: ", err)
+		} else if highlighted != nil {
+			text = *highlighted
+		}
+	}
+
+	rows := []*reader.Line{}
+	for _, row := range strings.Split(text, "\n") {
+		rows = append(rows, reader.NewLine(row))
+	}
+	return rows
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestUnfoldJSONRecords(t *testing.T) {
+	pager := newCommandTestPager(t, strings.Join([]string{
+		`{"level":"error","user":{"id":7}}`,
+		`not JSON`,
+		`[1]`,
+	}, "\n"))
+	pager.screen = twin.NewFakeScreen(40, 12)
+
+	rows := func() []string {
+		result := []string{}
+		for _, line := range pager.renderLines().lines {
+			result = append(result, strings.TrimRight(renderedToString(line.cells), " "))
+		}
+		return result
+	}
+
+	pager.mode = PagerModeViewing{pager: pager}
+	pager.mode.onRune('J')
+	assert.Assert(t, pager.UnfoldJSONRecords)
+
+	// Line numbers point at the original records
+	assert.DeepEqual(t, rows(), []string{
+		`  1 {`,
+		`      "level": "error",`,
+		`      "user": {`,
+		`        "id": 7`,
+		`      }`,
+		`    }`,
+		`  2 not JSON`,
+		`  3 [`,
+		`      1`,
+		`    ]`,
+	})
+
+	// Search hits are highlighted inside of the blocks
+	pager.search.For("id")
+	rendered := pager.renderLines()
+	assert.Assert(t, !rendered.lines[2].containsSearchHit)
+	assert.Assert(t, rendered.lines[3].containsSearchHit)
+	assert.Equal(t, rendered.lines[3].inputLineIndex.Index(), 0)
+	pager.search.Clear()
+
+	// Scrolling moves one screen row at a time
+	pager.screen = twin.NewFakeScreen(40, 6)
+	pager.scrollPosition = pager.scrollPosition.NextLine(3)
+	assert.Equal(t, rows()[0], `        "id": 7`)
+
+	// Folding keeps the current record on top
+	pager.mode.onRune('J')
+	assert.Assert(t, !pager.UnfoldJSONRecords)
+	assert.DeepEqual(t, rows(), []string{
+		`  1 {"level":"error","user":{"id":7}}`,
+		`  2 not JSON`,
+		`  3 [1]`,
+	})
+
+	pager.screen = twin.NewFakeScreen(40, 12)
+	assert.NilError(t, pager.executeCommand("set unfoldjson"))
+	assert.Equal(t, len(rows()), 10)
+}
@@ -75,6 +75,9 @@ func (p *Pager) scrollToNextSearchHit() {
 	}
 
 	if p.isViewing() && p.isScrolledToEnd() {
+		if p.scrollToSearchHitInOtherFile(SearchDirectionForward) {
+			return
+		}
 		p.mode = PagerModeNotFound{pager: p}
 		return
 	}
@@ -98,6 +101,9 @@ func (p *Pager) scrollToNextSearchHit() {
 
 	firstHitIndex := FindFirstHit(p.Reader(), p.search, firstSearchIndex, nil, SearchDirectionForward)
 	if firstHitIndex == nil {
+		if p.scrollToSearchHitInOtherFile(SearchDirectionForward) {
+			return
+		}
 		p.mode = PagerModeNotFound{pager: p}
 		return
 	}
@@ -196,6 +202,9 @@ func (p *Pager) scrollToPreviousSearchHit() {
 	case p.isViewing():
 		if p.scrollPosition.lineIndex(p).Index() == 0 {
 			// Already at the top, can't go further up
+			if p.scrollToSearchHitInOtherFile(SearchDirectionBackward) {
+				return
+			}
 			p.mode = PagerModeNotFound{pager: p}
 			return
 		}
@@ -215,6 +224,9 @@ func (p *Pager) scrollToPreviousSearchHit() {
 
 	hitIndex := FindFirstHit(p.Reader(), p.search, firstSearchIndex, nil, SearchDirectionBackward)
 	if hitIndex == nil {
+		if p.scrollToSearchHitInOtherFile(SearchDirectionBackward) {
+			return
+		}
 		p.mode = PagerModeNotFound{pager: p}
 		return
 	}
@@ -231,6 +243,87 @@ func (p *Pager) scrollToPreviousSearchHit() {
 	p.centerSearchHitsVertically()
 }
 
+// When there are no more hits in this file, continue into the next (or
+// previous) one, wrapping around after the last file. If no other file has any
+// hits, the last step is this file, from the top (or the bottom). Returns true
+// if a hit was found and we went there.
+//
+// The filter and the merged view are per file, so we only do this when not
+// filtering and not viewing the merged files. With only one file, it's up to
+// the caller to say "not found" before wrapping.
+func (p *Pager) scrollToSearchHitInOtherFile(direction SearchDirection) bool {
+	if p.isShowingHelp || p.filter.Active() {
+		return false
+	}
+
+	p.readerLock.Lock()
+	readers := p.readers
+	currentReader := p.currentReader
+	mergedReader := p.mergedReader
+	p.readerLock.Unlock()
+
+	if len(readers) < 2 || readers[currentReader] == mergedReader {
+		return false
+	}
+
+	step := 1
+	if direction == SearchDirectionBackward {
+		step = -1
+	}
+
+	for i := 1; i <= len(readers); i++ {
+		index := (currentReader + i*step + len(readers)) % len(readers)
+		r := readers[index]
+		if r == mergedReader {
+			continue
+		}
+
+		// Search from the top going forwards, or from the bottom going
+		// backwards
+		start := linemetadata.Index{}
+		if direction == SearchDirectionBackward {
+			last := linemetadata.IndexFromLength(r.GetLineCount())
+			if last == nil {
+				// No lines in this file
+				continue
+			}
+			start = *last
+		}
+
+		hitIndex := FindFirstHit(r, p.search, start, nil, direction)
+		if hitIndex == nil {
+			continue
+		}
+
+		if index != currentReader {
+			p.switchToFile(index)
+		}
+		p.scrollPosition = NewScrollPositionFromIndex(*hitIndex, "scrollToSearchHitInOtherFile")
+		p.setTargetLine(nil)
+
+		p.leftColumnZeroBased = 0
+		p.showLineNumbers = p.ShowLineNumbers
+		if direction == SearchDirectionBackward {
+			// Prefer hits to the right, like scrollToPreviousSearchHit()
+			p.scrollMaxRight()
+			if !p.searchHitIsVisible() {
+				p.scrollLeftToSearchHits()
+			}
+		} else if !p.searchHitIsVisible() {
+			p.scrollRightToSearchHits()
+		}
+		p.centerSearchHitsVertically()
+
+		p.readerLock.Lock()
+		description := p.describeFileUnlocked(index)
+		p.readerLock.Unlock()
+		p.mode = &PagerModeInfo{Pager: p, Text: "Search hit in " + description}
+		return true
+	}
+
+	return false
+}
+
 // Return true if any search hit is currently visible on screen.
 //
 // A search hit is considered visible if the first character of the hit is
@@ -252,8 +345,9 @@ func (p *Pager) searchHitIsVisible() bool {
 }
 
 func (p *Pager) centerSearchHitsVertically() {
-	if p.WrapLongLines {
-		// FIXME: Centering is not supported when wrapping, future improvement!
+	if p.WrapLongLines || p.UnfoldJSONRecords {
+		// FIXME: Centering is not supported when wrapping or unfolding, future
+		// improvement!
 		return
 	}
 
@@ -322,7 +416,7 @@ func (p *Pager) scrollMaxRight() {
 		}
 	}
 
-	screenWidth, _ := p.screen.Size()
+	screenWidth := p.contentWidth()
 
 	availableWidth := screenWidth - rendered.numberPrefixWidth
 	if widestLineWidth <= availableWidth {
@@ -356,7 +450,7 @@ func (p *Pager) scrollRightToSearchHits() bool {
 	// Check how far right we can scroll at most. Factors involved:
 	// - Screen width
 	// - Length of longest visible line
-	screenWidth, _ := p.screen.Size()
+	screenWidth := p.contentWidth()
 
 	widestLineWidth := 0 // In screen cells, some runes are double-width
 	rendered := p.renderLines()
@@ -451,7 +545,7 @@ func (p *Pager) scrollLeftToSearchHits() bool {
 	restoreLeftColumn := p.leftColumnZeroBased
 	restoreShowLineNumbers := p.showLineNumbers
 
-	screenWidth, _ := p.screen.Size()
+	screenWidth := p.contentWidth()
 
 	// If we go max left, which column will be the rightmost visible one?
 	var fullLeftRightmostVisibleColumn int
@@ -525,7 +619,8 @@ func (p *Pager) scrollLeftToSearchHits() bool {
 
 func (p *Pager) isViewing() bool {
 	_, isViewing := p.mode.(PagerModeViewing)
-	return isViewing
+	_, isUnfocused := p.mode.(PagerModeUnfocused)
+	return isViewing || isUnfocused
 }
 
 func (p *Pager) isNotFound() bool {
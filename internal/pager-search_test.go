@@ -1,10 +1,15 @@
 package internal
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/search"
 	"github.com/walles/moor/v2/twin"
 	"gotest.tools/v3/assert"
 )
@@ -340,3 +345,175 @@ func TestScrollRightToSearchHits_OnlyStartOfHitTriggers(t *testing.T) {
 
 	assert.Assert(t, !pager.scrollRightToSearchHits(), "No more search hit starts to the right, should not scroll")
 }
+
+func TestSearchModeModifiers(t *testing.T) {
+	pager := newCommandTestPager(t, "axb\na.b")
+
+	pager.mode = NewPagerModeSearch(pager, SearchDirectionForward, pager.scrollPosition)
+	for _, char := range "a.b" {
+		pager.mode.onRune(char)
+	}
+	assert.Assert(t, pager.search.Matches("axb"))
+
+	pager.mode.onRune('\x12') // CTRL-r, literal
+	pager.mode.onRune('\x14') // CTRL-t, case sensitive
+	assert.Assert(t, !pager.search.Matches("axb"))
+	assert.Assert(t, pager.search.Matches("a.b"))
+	assert.Equal(t, pager.search.Modifiers().String(), "literal, case sensitive")
+
+	pager.mode.onKey(twin.KeyEnter)
+	assert.DeepEqual(t, pager.searchHistory.entries, []string{"a.b"})
+
+	// Modifiers come back with the history entry
+	searchMode := NewPagerModeSearch(pager, SearchDirectionForward, pager.scrollPosition)
+	pager.mode = searchMode
+	pager.mode.onKey(twin.KeyUp)
+	assert.Equal(t, searchMode.inputBox.text, "a.b")
+	assert.Equal(t, searchMode.modifiers, search.Modifiers{Pattern: search.PatternLiteral, Case: search.CaseSensitive})
+	assert.Assert(t, !pager.search.Matches("axb"))
+
+	// And go away again when going back to what the user typed
+	pager.mode.onKey(twin.KeyDown)
+	assert.Equal(t, searchMode.inputBox.text, "")
+	assert.Equal(t, searchMode.modifiers, search.Modifiers{})
+}
+
+// Older moor versions read the history file too, so the modifiers go in a file
+// of their own
+func TestSearchHistoryModifiersFile(t *testing.T) {
+	t.Setenv("LESSSECURE", "")
+	fileName := filepath.Join(t.TempDir(), "search_history")
+	assert.NilError(t, os.WriteFile(fileName, []byte{}, 0o600)) // Don't import less history
+	history := BootSearchHistory(fileName)
+	history.addSearch("a.b", search.Modifiers{Pattern: search.PatternLiteral})
+	history.addSearch("plain", search.Modifiers{})
+
+	contents, err := os.ReadFile(fileName)
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "a.b\nplain\n")
+
+	loaded := BootSearchHistory(fileName)
+	assert.DeepEqual(t, loaded.entries, []string{"a.b", "plain"})
+	assert.Equal(t, loaded.modifiersFor("a.b"), search.Modifiers{Pattern: search.PatternLiteral})
+	assert.Equal(t, loaded.modifiersFor("plain"), search.Modifiers{})
+
+	// No modifiers left, no modifiers file
+	loaded.addSearch("a.b", search.Modifiers{})
+	_, err = os.Stat(modifiersFileName(fileName))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestSearchAcrossFiles(t *testing.T) {
+	pager := NewPager(
+		reader.NewFromTextForTesting("first", "hit one\nx"),
+		reader.NewFromTextForTesting("second", "nothing\nhere"),
+		reader.NewFromTextForTesting("third", "y\nhit three\nhit four"),
+	)
+	screen := twin.NewFakeScreen(40, 10)
+	pager.screen = screen
+	pager.search = search.For("hit")
+
+	// No more hits in the first file, skip the second one
+	pager.scrollToNextSearchHit()
+	assert.Equal(t, pager.currentReader, 2)
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(9)), "Search hit in [3/3] third")
+
+	// Wrap around after the last file
+	pager.mode.onRune('n')
+	assert.Equal(t, pager.currentReader, 0)
+
+	// And backwards
+	pager.mode.onRune('N')
+	assert.Equal(t, pager.currentReader, 2)
+
+	// With no hits in other files, wrap around to the top of this one
+	pager.mode = PagerModeViewing{pager: pager}
+	pager.search = search.For("three")
+	pager.scrollToEnd()
+	pager.scrollToNextSearchHit()
+	assert.Equal(t, pager.currentReader, 2)
+	assert.Assert(t, !pager.isNotFound())
+	assert.Equal(t, pager.lineIndex().Index(), 0)
+	pager.search = search.For("hit")
+
+	// Not while filtering, the filter belongs to the current file
+	pager.mode = PagerModeViewing{pager: pager}
+	*pager.filter = filterChainFor("hit")
+	pager.scrollToNextSearchHit()
+	assert.Equal(t, pager.currentReader, 2)
+	assert.Assert(t, pager.isNotFound())
+}
+
+func TestSearchAllFiles(t *testing.T) {
+	pager := NewPager(
+		reader.NewFromTextForTesting("first", "hit one\nx"),
+		reader.NewFromTextForTesting("second", "nothing\nhere"),
+		reader.NewFromTextForTesting("third", "y\nhit three\nhit four"),
+	)
+	screen := twin.NewFakeScreen(40, 10)
+	pager.screen = screen
+
+	assert.Error(t, pager.executeCommand("searchall"), "Nothing to search for, try :searchall <pattern>")
+
+	assert.NilError(t, pager.executeCommand("searchall hit"))
+	_, isFileHits := pager.mode.(*PagerModeFileHits)
+	assert.Assert(t, isFileHits)
+
+	awaitFileHits(t, pager)
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(6)), "      1 hit   [1/3] first")
+	assert.Equal(t, rowToString(screen.GetRow(7)), "      0 hits  [2/3] second")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "      2 hits  [3/3] third")
+
+	pager.mode.onRune('j')
+	pager.mode.onRune('j')
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, pager.currentReader, 2)
+}
+
+// Wait for the background counting to finish
+func awaitFileHits(t *testing.T, pager *Pager) {
+	t.Helper()
+
+	mode := pager.mode.(*PagerModeFileHits)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mode.update()
+
+		counting := false
+		for _, file := range mode.files {
+			file.counter.lock.Lock()
+			counting = counting || file.counter.counting
+			file.counter.lock.Unlock()
+		}
+		if !counting {
+			return
+		}
+
+		assert.Assert(t, time.Now().Before(deadline), "Counting never finished")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Files that haven't been read all the way should say so
+func TestSearchAllFilesPartial(t *testing.T) {
+	pauseAfterLines := 1
+	paused, err := reader.NewFromStream("paused", strings.NewReader("hit\nhit\n"), formatters.TTY,
+		reader.ReaderOptions{PauseAfterLines: &pauseAfterLines})
+	assert.NilError(t, err)
+	//revive:disable-next-line:empty-block
+	for !paused.PauseStatus.Load() {
+	}
+
+	pager := NewPager(reader.NewFromTextForTesting("done", "hit"), paused)
+	screen := twin.NewFakeScreen(40, 10)
+	pager.screen = screen
+
+	assert.NilError(t, pager.executeCommand("searchall hit"))
+	awaitFileHits(t, pager)
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(7)), "      1 hit   [1/2] done")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "     1+ hits  [2/2] paused")
+}
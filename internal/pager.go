@@ -43,16 +43,26 @@ type eventMoreLinesAvailable struct{}
 // reader.HighlightingDone() for details.
 type eventMaybeDone struct{}
 
+// The background search hit counter has a new count for us
+type eventHitsCounted struct{}
+
 // Pager is the main on-screen pager
 type Pager struct {
-	readers       []*reader.ReaderImpl // Immutable since startup, no locking needed
+	readers       []*reader.ReaderImpl // Only appended to from the UI goroutine
 	currentReader int                  // Index into the readers slice
-	readerLock    sync.Mutex           // Protects currentReader
+	readerLock    sync.Mutex           // Protects readers, currentReader and split
+
+	// All files merged by timestamp, also present in readers. Nil until the
+	// user asks for it.
+	mergedReader *reader.ReaderImpl
 
 	readerSwitched chan struct{}
 
 	// A view of the current reader, possibly filtered
-	filteringReader FilteringReader
+	filteringReader *FilteringReader
+
+	// Non-nil when the screen is split in two panes
+	split *splitScreen
 
 	screen              twin.Screen
 	quit                bool
@@ -66,10 +76,36 @@ type Pager struct {
 
 	search search.Search
 
-	// This should never be null while paging. Configured in NewPager().
+	// This should never be null while paging. Configured in NewPager() and
+	// LoadHistoryFiles().
 	searchHistory *SearchHistory
 
-	filter search.Search
+	// History for the ':' command line. Configured in NewPager() and
+	// LoadHistoryFiles().
+	commandHistory *SearchHistory
+
+	// Each pane has its own, see paneState
+	filter *filterChain
+
+	// Lines to show around filter matches, shared between panes
+	filterContext filterContext
+
+	// Whether lines that aren't JSON pass JSON field filters, shared between
+	// panes
+	keepNonJSON bool
+
+	// Always highlighted, each in its own color. Each pane has its own.
+	highlights []search.Search
+
+	// Show JSON and logfmt lines as aligned columns. Each pane has its own.
+	columns columnSettings
+
+	// Indented and highlighted JSON records, by their one line versions. See
+	// UnfoldJSONRecords.
+	unfoldedRecords map[string][]*reader.Line
+
+	// For showing "hit 12/340" in the status bar. Configured in NewPager().
+	hitCounter *hitCounter
 
 	// We used to have a "Following" field here. If you want to follow, set
 	// TargetLineNumber to linemetadata.IndexMax() instead, see below.
@@ -86,13 +122,23 @@ type Pager struct {
 	StatusBarStyle StatusBarOption
 	ShowStatusBar  bool
 
+	// Show where the viewport, search hits and bookmarks are on the right edge
+	ShowScrollbar bool
+
 	UnprintableStyle textstyles.UnprintableStyleT
 
 	WrapLongLines bool
 
+	// Show JSON lines records as indented blocks, toggle with 'J'
+	UnfoldJSONRecords bool
+
 	// Ref: https://github.com/walles/moor/issues/113
 	QuitIfOneScreen bool
 
+	// If true and there are multiple files, start out showing all of them
+	// merged by timestamp
+	MergeFiles bool
+
 	// Ref: https://github.com/walles/moor/issues/94
 	ScrollLeftHint  textstyles.CellWithMetadata
 	ScrollRightHint textstyles.CellWithMetadata
@@ -108,6 +154,10 @@ type Pager struct {
 	// pager!
 	TargetLine *linemetadata.Index
 
+	// Set while going to a percentage or a byte offset we haven't read up to
+	// yet
+	pendingGoto *pendingGoto
+
 	// If true, pager will clear the screen on return. If false, pager will
 	// clear the last line, and show the cursor.
 	DeInit bool
@@ -126,12 +176,28 @@ type Pager struct {
 	// to the right.
 	longestLineLength int
 
-	// Bookmarks that you can come back to.
+	// Bookmarks that you can come back to, per reader. Use currentBookmarks()
+	// to get the ones for the current reader.
 	//
 	// Ref: https://github.com/walles/moor/issues/175
-	bookmarks map[rune]scrollPosition
+	bookmarks map[*reader.ReaderImpl]map[rune]scrollPosition
+
+	// Bookmarks and last positions from earlier sessions. Configured in
+	// NewPager() and LoadHistoryFiles().
+	savedPositions *SavedPositions
 
 	AfterExit func() error
+
+	// Used when opening more files while paging
+	ReaderOptions reader.ReaderOptions
+
+	// Highlighting settings for files opened while paging. Initialized in
+	// StartPaging().
+	chromaStyle     *chroma.Style
+	chromaFormatter *chroma.Formatter
+
+	// ':' commands to run on startup, like "set wrap"
+	StartupCommands []string
 }
 
 type _PreHelpState struct {
@@ -157,9 +223,13 @@ Moving around
 * Alt key plus left / right arrow steps one column at a time
 * Left / right can be used to hide / show line numbers
 * Home and End for start / end of the document
-* 'g' for going to a specific line number
+* 'g' for going to a specific line number, a percentage like 50% or a byte
+  offset like b1048576
 * 'm' sets a mark, you will be asked for a letter to label it with
 * ' (single quote) jumps to the mark
+* '' (two single quotes) returns to where you left off last time you viewed a
+  file. Marks are remembered per file between sessions.
+* 'M' lists all marks so you can pick one with the arrow keys
 * CTRL-p moves to the previous line
 * CTRL-n moves to the next line
 * PageUp / 'b' and PageDown / 'f'
@@ -170,17 +240,75 @@ Moving around
 * CTRL-a moves to the leftmost position
 * RETURN moves down one line
 
-Switching files (if you opened multiple files)
-----------------------------------------------
-* Press ':' to enter file switching mode
+Commands
+--------
+Press ':' to enter a command. 'TAB' completes, up / down arrows access the
+command history.
+
+If you opened multiple files, do :n for the next file, :p for the previous
+file, :x for the first file or :m to view all files merged by their line
+timestamps. Files opened later join the merged view, except huge ones which
+are left out.
+
+* :edit <file> opens another file, press 'TAB' to complete file names
+* :write <file> saves the visible lines as plain text, :write! overwrites.
+  Add -raw to keep the ANSI codes, including any syntax highlighting, and
+  -all to save unfiltered lines. With --reformat, the reformatted lines are
+  saved.
+* :pipe <command> pipes the visible lines through a shell command and opens
+  the output as a new file. Add -all to pipe unfiltered lines, or -marks ab to
+  pipe the lines between the 'a' and 'b' marks. Press '|' as a shortcut.
+* :set wrap / nowrap / linenumbers / nolinenumbers / statusbar / nostatusbar
+* :set scrollbar / noscrollbar shows where search hits, filter matches and
+  bookmarks are on the right edge. Click the scrollbar to go there.
+* :set tabsize 4
+* :set context 2 shows two lines around each filter match, like "grep -C 2".
+  Use before / after to set just one side.
+* :filter <pattern> adds a filter, just :filter removes all filters
+* :unfilter 2 removes the second filter, just :unfilter removes the last one
+* :searchall <pattern> lists how many hits each file has, just :searchall
+  lists hits for the current search. Pick a file to go to its first hit.
+* :1234 goes to line 1234
+* :q quits, just like 'q'
+
+Split screen
+------------
+* Press 'S' to split the screen in two, or to go back to one pane
+* Press TAB to move focus between the panes
+
+The new pane shows the next file if you opened multiple files, otherwise
+another view of the current file. Each pane can be scrolled, searched,
+filtered, highlighted and put in columns on its own.
 
 Filtering
 ---------
 Type '&' to start filtering, then type your filter expression.
 
+Start the expression with '!' to hide matching lines instead, like "grep -v".
+Start it with '~' for fuzzy matching, see below. To filter on a leading '!',
+'|', '~', '.' or '(', escape it with a backslash, like '\!important'.
+
+Type '&' again to add more filters. Lines must match all of them, unless you
+start a filter with '|', which means OR. AND binds harder than OR, and the
+active filters are shown in the status bar.
+
+Filters like '.level == "error" && .latency_ms > 500' match fields in JSON
+lines. Compare with ==, !=, <, <=, >, >= or =~ (regexp), combine with &&, ||
+and !. Write nested fields like .user.id, array elements like .tags[0], and
+odd field names like ."field name". Lines that aren't JSON are hidden, do
+":set keepnonjson" to show them anyway.
+
+A filter needs at least one comparison to be a field filter, so '.java' still
+finds Java files. To check that a field exists, do '.user != null'.
+
+To see what's around the matching lines, do ":set context 3". Context lines
+are dimmed, and there's a divider between groups of lines that aren't next to
+each other.
+
 While filtering, arrow keys, PageUp, PageDown, Home and End work as usual.
 
-Press 'ESC' or RETURN to exit filtering mode.
+Press RETURN to exit filtering mode, or 'ESC' to also drop the filter you
+were typing.
 
 Searching
 ---------
@@ -190,8 +318,45 @@ Searching
 * Press up / down arrows while searching to access search history
 * Find next by typing 'n' (for "next")
 * Find previous by typing SHIFT-N or 'p' (for "previous")
+* The status bar shows which hit is on screen, like "hit 12/340". A "+" after
+  the count means more lines may come, so there may be more hits.
+* With multiple files, 'n' and 'p' continue into the next and previous files
+  when there are no more hits in this one. Not while filtering though.
 * Search is case sensitive if it contains any UPPER CASE CHARACTERS
 * Search is interpreted as a regexp if it is a valid one
+* While searching, CTRL-R switches between literal, regexp and fuzzy search,
+  CTRL-T between smart case, case sensitive and ignore case, and CTRL-W
+  toggles whole word matching. Search history remembers these settings.
+* Fuzzy search works like fzf, "cnrf" finds "connection refused". Press TAB
+  while fuzzy searching to list the best matching lines, then ↑↓ and RETURN
+  to jump to one of them.
+
+Highlighting
+------------
+* Type 'H' to highlight a pattern in its own color, next to the search hits
+* Type 'H' again to add more highlights, each gets a new color
+* :highlight <pattern> does the same, just :highlight removes all highlights
+* :unhighlight 2 removes the second highlight, just :unhighlight removes the
+  last one
+
+Columns
+-------
+* Type 'C' to show JSON lines and logfmt lines as aligned columns. SPACE
+  shows or hides a column, 'J' and 'K' move it down or up.
+* The time, level and message columns show whichever of the usual fields a
+  line has, like "ts", "time" or "timestamp". Other columns show one field,
+  write nested JSON fields like user.id.
+* :columns time level message user.id sets the columns from the command line
+* :set columns / nocolumns turns columns on or off. Lines that aren't JSON or
+  logfmt are always shown as they are.
+
+JSON Lines
+----------
+* Type 'J' to unfold JSON lines records into indented, highlighted blocks, and
+  'J' again to fold them back into one line each
+* Line numbers, marks and search hits keep pointing at the original record
+* :set unfoldjson / nounfoldjson does the same from the command line. Starting
+  with --reformat unfolds the records from the start.
 
 Reporting bugs
 --------------
@@ -235,21 +400,43 @@ func NewPager(readers ...*reader.ReaderImpl) *Pager {
 		ScrollLeftHint:              textstyles.CellWithMetadata{Rune: '<', Style: twin.StyleDefault.WithAttr(twin.AttrReverse)},
 		ScrollRightHint:             textstyles.CellWithMetadata{Rune: '>', Style: twin.StyleDefault.WithAttr(twin.AttrReverse)},
 		scrollPosition:              newScrollPosition(name),
+		bookmarks:                   make(map[*reader.ReaderImpl]map[rune]scrollPosition),
 		WithSearchHitLineBackground: true,
+		columns:                     newColumnSettings(),
 	}
 
 	pager.mode = PagerModeViewing{pager: &pager}
-	pager.filteringReader = FilteringReader{
+	pager.hitCounter = &hitCounter{}
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: readers[0], // Always start with the first reader
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
+		Context:       &pager.filterContext,
+		KeepNonJSON:   &pager.keepNonJSON,
 	}
 
-	searchHistory := BootSearchHistory("")
-	pager.searchHistory = &searchHistory
+	// In memory only, see LoadHistoryFiles()
+	pager.searchHistory = &SearchHistory{}
+	pager.commandHistory = &SearchHistory{}
+	pager.savedPositions = &SavedPositions{}
 
 	return &pager
 }
 
+// LoadHistoryFiles makes the pager remember searches, ':' commands and
+// positions in files between sessions. Without this, nothing is read from or
+// written to the user's data directory.
+func (p *Pager) LoadHistoryFiles() {
+	searchHistory := BootSearchHistory("")
+	p.searchHistory = &searchHistory
+
+	commandHistory := BootCommandHistory("")
+	p.commandHistory = &commandHistory
+
+	savedPositions := BootSavedPositions("")
+	p.savedPositions = &savedPositions
+}
+
 // How many lines are visible on screen? Depends on screen height and whether or
 // not the status bar is visible.
 func (p *Pager) visibleHeight() int {
@@ -270,6 +457,9 @@ func (p *Pager) visibleHeight() int {
 // Returns 0 if line numbers are disabled.
 func (p *Pager) getLineNumberPrefixLength(lineNumber linemetadata.Number) int {
 	if !p.showLineNumbers {
+		if p.showMarkGutter() {
+			return 1
+		}
 		return 0
 	}
 
@@ -354,6 +544,24 @@ func (p *Pager) setFooter(prefix string, filename string, status string, help st
 	}
 }
 
+func (p *Pager) scrollTowardsTargetLine() {
+	// Without the isViewing() check, following will continue while searching,
+	// and I prefer it to stop so people can see what they are searching in.
+	if !p.isViewing() || p.TargetLine == nil {
+		return
+	}
+
+	// The user wants to scroll down to a specific line number
+	if linemetadata.IndexFromLength(p.Reader().GetLineCount()).IsBefore(*p.TargetLine) {
+		// Not there yet, keep scrolling
+		p.scrollToEnd()
+	} else {
+		// We see the target, scroll to it
+		p.scrollPosition = NewScrollPositionFromIndex(*p.TargetLine, "goToTargetLine")
+		p.setTargetLine(nil)
+	}
+}
+
 // Quit leaves the help screen or quits the pager
 func (p *Pager) Quit() {
 	if !p.isShowingHelp {
@@ -395,18 +603,38 @@ func (p *Pager) moveRight(delta int) {
 	}
 }
 
+// Column and row are zero based screen coordinates
+func (p *Pager) onMouseClick(column int, row int) {
+	if !p.isViewing() {
+		return
+	}
+
+	if paneScreen, ok := p.screen.(*paneScreen); ok {
+		_, paneHeight := paneScreen.Size()
+		if row < paneScreen.firstRow() || row >= paneScreen.firstRow()+paneHeight {
+			// The other pane, never mind
+			return
+		}
+		row -= paneScreen.firstRow()
+	}
+
+	p.onScrollbarClick(column, row)
+}
+
 func (p *Pager) Reader() reader.Reader {
 	if p.isShowingHelp {
 		return _HelpReader
 	}
-	return &p.filteringReader
+	return p.filteringReader
 }
 
 func (p *Pager) handleScrolledUp() {
+	p.cancelPendingGoto()
 	p.setTargetLine(nil)
 }
 
 func (p *Pager) handleScrolledDown() {
+	p.cancelPendingGoto()
 	if p.isScrolledToEnd() {
 		// Follow output
 		reallyHigh := linemetadata.IndexMax()
@@ -448,6 +676,8 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 	log.Info("Pager starting")
 
 	defer func() {
+		p.rememberPositions()
+
 		p.readerLock.Lock()
 		r := p.readers[p.currentReader]
 		p.readerLock.Unlock()
@@ -470,7 +700,16 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 
 	p.screen = screen
 	p.mode = PagerModeViewing{pager: p}
-	p.bookmarks = make(map[rune]scrollPosition)
+	p.chromaStyle = chromaStyle
+	p.chromaFormatter = chromaFormatter
+
+	p.readerLock.Lock()
+	p.restorePositionUnlocked()
+	p.readerLock.Unlock()
+
+	if p.MergeFiles && len(p.readers) > 1 {
+		p.mergedFiles()
+	}
 
 	// Make sure the reader knows how many lines we want
 	p.setTargetLine(p.TargetLine)
@@ -492,6 +731,9 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 
 		var reenable <-chan time.Time
 
+		// More lines for the pane without focus when the screen is split
+		var otherMoreLines chan bool
+
 		for {
 			p.readerLock.Lock()
 			r := p.readers[p.currentReader]
@@ -499,12 +741,14 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 
 			select {
 			case <-p.readerSwitched:
-				// A different reader is now active
-				p.filter = search.Search{}
-
+				// A different reader is now active, or the screen was split
+				// or unsplit
 				p.readerLock.Lock()
 				r = p.readers[p.currentReader]
-				p.filteringReader.SetBackingReader(r)
+				otherMoreLines = nil
+				if p.split != nil {
+					otherMoreLines = p.readers[p.split.other.currentReader].MoreLinesAdded
+				}
 				p.readerLock.Unlock()
 
 				// Look in the right place for more lines
@@ -525,6 +769,9 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 				throttledMoreLines = nil
 				reenable = time.After(200 * time.Millisecond)
 
+			case <-otherMoreLines:
+				screen.Events() <- eventMoreLinesAvailable{}
+
 			case <-reenable:
 				// Re-enable channel
 				throttledMoreLines = r.MoreLinesAdded
@@ -556,6 +803,10 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 		}
 	}()
 
+	for _, command := range p.StartupCommands {
+		p.runCommand(command)
+	}
+
 	log.Info("Entering pager main loop...")
 
 	// Main loop
@@ -577,7 +828,7 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 			//
 			// Also, we only do this if we have exactly one reader, because
 			// that's what less does.
-			if len(p.readers) == 1 && p.QuitIfOneScreen && !p.isShowingHelp && r.ReadingDone.Load() && r.HighlightingDone.Load() {
+			if len(p.readers) == 1 && p.QuitIfOneScreen && !p.isShowingHelp && p.split == nil && r.ReadingDone.Load() && r.HighlightingDone.Load() {
 				if p.fitsOnOneScreen() {
 					// Ref:
 					// https://github.com/walles/moor/issues/113#issuecomment-1368294132
@@ -608,6 +859,10 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 		case twin.EventMouse:
 			log.Tracef("Handling mouse event %d...", event.Buttons())
 			switch event.Buttons() {
+			case twin.MouseButtonLeft:
+				column, row := event.Position()
+				p.onMouseClick(column, row)
+
 			case twin.MouseWheelUp:
 				// Clipping is done in _Redraw()
 				p.scrollPosition = p.scrollPosition.PreviousLine(1)
@@ -631,22 +886,25 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 			return
 
 		case eventMoreLinesAvailable:
-			// Without the isViewing() check, following will continue while
-			// searching, and I prefer it to stop so people can see what they
-			// are searching in.
-			if p.isViewing() && p.TargetLine != nil {
-				// The user wants to scroll down to a specific line number
-				if linemetadata.IndexFromLength(p.Reader().GetLineCount()).IsBefore(*p.TargetLine) {
-					// Not there yet, keep scrolling
-					p.scrollToEnd()
-				} else {
-					// We see the target, scroll to it
-					p.scrollPosition = NewScrollPositionFromIndex(*p.TargetLine, "goToTargetLine")
-					p.setTargetLine(nil)
-				}
+			p.scrollTowardsTargetLine()
+			p.updatePendingGoto()
+			p.maybeDoneReadingAll()
+			if p.split != nil {
+				p.swapPanes()
+				p.scrollTowardsTargetLine()
+				p.updatePendingGoto()
+				p.swapPanes()
 			}
 
 		case eventMaybeDone:
+			p.updatePendingGoto()
+			p.maybeDoneReadingAll()
+			if p.split != nil {
+				p.swapPanes()
+				p.updatePendingGoto()
+				p.swapPanes()
+			}
+
 			// Man pages come pre-formatted for the screen width, and line
 			// numbers will mess that up. So we disable line numbers if we
 			// detect a man page by its contents.
@@ -662,6 +920,9 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 		case eventSpinnerUpdate:
 			spinner = event.spinner
 
+		case eventHitsCounted:
+			// We'll be implicitly redrawn just by taking another lap in the loop
+
 		default:
 			log.Warnf("Unhandled event type: %v", event)
 		}
@@ -705,6 +966,7 @@ func (p *Pager) fitsOnOneScreenWrapped() bool {
 	fakePager.showLineNumbers = false
 
 	fakePager.WrapLongLines = p.WrapLongLines
+	fakePager.UnfoldJSONRecords = p.UnfoldJSONRecords
 	fakePager.ShowStatusBar = false // We are only interested in content lines
 	fakePager.TabSize = p.TabSize
 
@@ -720,7 +982,7 @@ func (p *Pager) fitsOnOneScreen() bool {
 		return false
 	}
 
-	if p.WrapLongLines {
+	if p.WrapLongLines || p.UnfoldJSONRecords {
 		return p.fitsOnOneScreenWrapped()
 	}
 
@@ -737,7 +999,7 @@ func (p *Pager) fitsOnOneScreen() bool {
 
 	lines := reader.GetLines(linemetadata.Index{}, reader.GetLineCount())
 	for _, line := range lines.Lines {
-		rendered := line.HighlightedTokens(twin.StyleDefault, twin.StyleDefault, search.Search{}, width+1).StyledRunes
+		rendered := line.HighlightedTokens(twin.StyleDefault, twin.StyleDefault, search.Search{}, nil, width+1).StyledRunes
 		if len(rendered) > width {
 			// This line is too long to fit on one screen line, no fit
 			return false
@@ -755,6 +1017,11 @@ func (p *Pager) ReprintAfterExit() {
 	screenLinesCount := len(renderedScreen.lines)
 
 	_, screenHeight := p.screen.Size()
+	if p.split != nil {
+		// Keep both panes
+		_, screenHeight = p.split.root.Size()
+		screenLinesCount = screenHeight
+	}
 	screenHeightWithoutFooter := screenHeight - p.DeInitFalseMargin
 	if screenLinesCount > screenHeightWithoutFooter {
 		screenLinesCount = screenHeightWithoutFooter
@@ -26,6 +26,14 @@ const blueBackgroundClearToEol = "\x1b[44m\x1b[K"   // No 0 before the K, should
 
 const samplesDir = "../sample-files"
 
+// Only the moor binary should read and write the user's history files
+func TestNewPagerKeepsHistoryInMemory(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "x"))
+	assert.Equal(t, pager.searchHistory.absFileName, "")
+	assert.Equal(t, pager.commandHistory.absFileName, "")
+	assert.Equal(t, pager.savedPositions.absFileName, "")
+}
+
 func TestUnicodeRendering(t *testing.T) {
 	reader := reader.NewFromTextForTesting("", "åäö")
 
@@ -680,3 +688,37 @@ func TestFooter(t *testing.T) {
 
 	testFooter(t, "", "line 1\nline 2", "2 lines  100%  "+help)
 }
+
+func TestMergedFiles(t *testing.T) {
+	first := reader.NewFromTextForTesting("first", "2024-01-02 10:00:00 First")
+	second := reader.NewFromTextForTesting("second", "2024-01-02 09:00:00 Second")
+
+	pager := NewPager(first, second)
+	pager.Quit()
+	pager.StartPaging(twin.NewFakeScreen(40, 10), nil, nil)
+
+	pager.mode = NewPagerModeColonCommand(pager)
+	pager.mode.onRune('m')
+	pager.mode.onKey(twin.KeyEnter)
+
+	assert.Equal(t, len(pager.readers), 3)
+	assert.Equal(t, pager.currentReader, 2)
+	assert.Equal(t, pager.readers[2], pager.mergedReader)
+
+	// File names are shown, but aren't part of the lines
+	assert.NilError(t, pager.mergedReader.Wait())
+	rendered := pager.renderLines().lines
+	assert.Assert(t, strings.HasPrefix(renderedToString(rendered[0].cells), "  1 second │ 2024-01-02 09:00:00 Second"), renderedToString(rendered[0].cells))
+	assert.Equal(t, pager.Reader().GetLine(linemetadata.Index{}).Plain(), "2024-01-02 09:00:00 Second")
+
+	// Asking again should reuse the existing merged view
+	pager.firstFile()
+	pager.mergedFiles()
+	assert.Equal(t, len(pager.readers), 3)
+	assert.Equal(t, pager.currentReader, 2)
+
+	// Files opened later should join the merged view
+	pager.addReader(reader.NewFromTextForTesting("third", "2024-01-02 08:00:00 Third"))
+	assert.NilError(t, pager.mergedReader.Wait())
+	assert.Equal(t, pager.mergedReader.GetLine(linemetadata.Index{}).Plain(), "2024-01-02 08:00:00 Third")
+}
@@ -1,66 +1,117 @@
 package internal
 
 import (
+	"strings"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/walles/moor/v2/twin"
 )
 
 type PagerModeColonCommand struct {
-	pager *Pager
+	pager               *Pager
+	inputBox            *InputBox
+	commandHistoryIndex int
+	userEditedText      string
+
+	// Shown after an ambiguous completion
+	completions []string
+}
+
+func NewPagerModeColonCommand(p *Pager) *PagerModeColonCommand {
+	return &PagerModeColonCommand{
+		pager:               p,
+		inputBox:            &InputBox{accept: INPUTBOX_ACCEPT_ALL},
+		commandHistoryIndex: len(p.commandHistory.entries), // Past the end
+	}
 }
 
 func (m *PagerModeColonCommand) drawFooter(_ string, _ string, _ string) {
 	p := m.pager
-	_, height := p.screen.Size()
 
-	pos := 0
-	for _, token := range "Go to [n]ext, [p]revious or first [x] file: " {
-		pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(token, twin.StyleDefault))
+	help := "'TAB' completes, 'ENTER' runs, 'ESC' cancels, '↑↓' navigate history"
+	p.readerLock.Lock()
+	if len(p.readers) > 1 && m.inputBox.text == "" {
+		help = "'n', 'p', 'x' or 'm' + 'ENTER' for next, previous, first file or merged view"
 	}
+	p.readerLock.Unlock()
 
-	// Add a cursor
-	p.screen.SetCell(pos, height-1, twin.NewStyledRune(' ', twin.StyleDefault.WithAttr(twin.AttrReverse)))
+	if len(m.completions) > 0 {
+		help = strings.Join(m.completions, " ")
+	}
+
+	m.inputBox.draw(p.screen, help, ":")
 }
 
-func (m *PagerModeColonCommand) onKey(key twin.KeyCode) {
-	p := m.pager
+func (m *PagerModeColonCommand) moveCommandHistoryIndex(delta int) {
+	entries := m.pager.commandHistory.entries
+	if len(entries) == 0 {
+		return
+	}
 
-	switch key {
-	case twin.KeyEscape:
-		p.mode = PagerModeViewing{pager: p}
+	m.commandHistoryIndex += delta
+	if m.commandHistoryIndex < 0 {
+		m.commandHistoryIndex = 0
+	}
+	if m.commandHistoryIndex > len(entries) {
+		m.commandHistoryIndex = len(entries) // Beyond the end of the history
+	}
 
-	default:
-		log.Tracef("Unhandled colon command event %v, treating as a viewing key event", key)
-		p.mode = PagerModeViewing{pager: p}
-		p.mode.onKey(key)
+	if m.commandHistoryIndex == len(entries) {
+		// Reset to whatever the user typed last
+		m.inputBox.setText(m.userEditedText)
+	} else {
+		// Get the history entry
+		m.inputBox.setText(entries[m.commandHistoryIndex])
 	}
 }
 
-func (m *PagerModeColonCommand) onRune(char rune) {
+// The user changed the command line
+func (m *PagerModeColonCommand) edited() {
+	m.commandHistoryIndex = len(m.pager.commandHistory.entries) // Reset history index when user types
+	m.userEditedText = m.inputBox.text
+	m.completions = nil
+}
+
+func (m *PagerModeColonCommand) onKey(key twin.KeyCode) {
 	p := m.pager
 
-	if char == 'q' {
-		// Back to viewing mode, just like ESC
-		p.mode = PagerModeViewing{pager: p}
+	if m.inputBox.handleKey(key) {
+		m.edited()
+		return
 	}
 
-	if char == 'p' {
+	switch key {
+	case twin.KeyEnter:
+		command := strings.TrimSpace(m.inputBox.text)
+		p.commandHistory.addEntry(command)
 		p.mode = PagerModeViewing{pager: p}
-		p.previousFile()
-		return
-	}
+		p.runCommand(command)
 
-	if char == 'n' {
+	case twin.KeyEscape:
 		p.mode = PagerModeViewing{pager: p}
-		p.nextFile()
-		return
+
+	case twin.KeyUp:
+		m.moveCommandHistoryIndex(-1)
+
+	case twin.KeyDown:
+		m.moveCommandHistoryIndex(1)
+
+	default:
+		log.Debugf("Unhandled colon command key event %v", key)
 	}
+}
 
-	if char == 'x' {
-		p.mode = PagerModeViewing{pager: p}
-		p.firstFile()
+func (m *PagerModeColonCommand) onRune(char rune) {
+	p := m.pager
+
+	if char == '\t' {
+		completed, completions := p.completeCommand(m.inputBox.text)
+		m.inputBox.setText(completed)
+		m.edited()
+		m.completions = completions
 		return
 	}
 
-	log.Debugf("Unhandled colon command rune %q, ignoring it", char)
+	m.inputBox.handleRune(char)
+	m.edited()
 }
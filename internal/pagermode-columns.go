@@ -0,0 +1,155 @@
+// Pick which columns to show JSON and logfmt lines as, and in which order
+
+package internal
+
+import (
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+)
+
+type columnChoice struct {
+	name    string
+	enabled bool
+}
+
+type PagerModeColumns struct {
+	pager *Pager
+
+	// Enabled columns first, in order
+	choices []columnChoice
+
+	// Index into choices
+	selected int
+
+	// Restored on ESC
+	original columnSettings
+}
+
+// The choices are the current columns, the default columns, and all fields of
+// the lines on screen
+func NewPagerModeColumns(p *Pager) *PagerModeColumns {
+	m := &PagerModeColumns{
+		pager:    p,
+		original: p.columns,
+	}
+	m.original.names = slices.Clone(p.columns.names)
+
+	for _, name := range p.columns.names {
+		m.choices = append(m.choices, columnChoice{name: name, enabled: true})
+	}
+
+	names := slices.Clone(defaultColumns)
+	if !p.isShowingHelp {
+		var firstLine linemetadata.Index
+		if p.lineIndex() != nil {
+			firstLine = *p.lineIndex()
+		}
+		for _, line := range p.Reader().GetLines(firstLine, p.visibleHeight()).Lines {
+			if fields := parseStructuredLine(line.Plain()); fields != nil {
+				names = append(names, structuredLineKeys(fields)...)
+			}
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(m.choices, func(choice columnChoice) bool { return choice.name == name }) {
+			m.choices = append(m.choices, columnChoice{name: name})
+		}
+	}
+
+	// Show what we're choosing between
+	m.apply()
+
+	return m
+}
+
+// Show the enabled columns
+func (m *PagerModeColumns) apply() {
+	names := []string{}
+	for _, choice := range m.choices {
+		if choice.enabled {
+			names = append(names, choice.name)
+		}
+	}
+
+	m.pager.setColumns(names)
+}
+
+func (m *PagerModeColumns) drawFooter(_ string, _ string, _ string) {
+	p := m.pager
+	drawPicker(p, len(m.choices), m.selected, func(i int) string {
+		if m.choices[i].enabled {
+			return "[x] " + m.choices[i].name
+		}
+		return "[ ] " + m.choices[i].name
+	})
+
+	p.setFooter("", "", "", "Press 'SPACE' to show / hide a column, 'J'/'K' to move it, 'ENTER' when done, 'ESC' to cancel")
+}
+
+// Move the selected column up or down the list
+func (m *PagerModeColumns) moveSelected(delta int) {
+	target := m.selected + delta
+	if target < 0 || target >= len(m.choices) {
+		return
+	}
+
+	m.choices[m.selected], m.choices[target] = m.choices[target], m.choices[m.selected]
+	m.selected = target
+	m.apply()
+}
+
+func (m *PagerModeColumns) cancel() {
+	p := m.pager
+	p.columns = m.original
+	p.mode = PagerModeViewing{pager: p}
+}
+
+func (m *PagerModeColumns) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyUp:
+		m.selected = max(m.selected-1, 0)
+
+	case twin.KeyDown:
+		m.selected = min(m.selected+1, len(m.choices)-1)
+
+	case twin.KeyEnter:
+		p.mode = PagerModeViewing{pager: p}
+
+	case twin.KeyEscape:
+		m.cancel()
+
+	default:
+		log.Debugf("Unhandled columns key event %v", key)
+	}
+}
+
+func (m *PagerModeColumns) onRune(char rune) {
+	switch char {
+	case 'q':
+		m.cancel()
+
+	case 'k':
+		m.onKey(twin.KeyUp)
+
+	case 'j':
+		m.onKey(twin.KeyDown)
+
+	case 'K':
+		m.moveSelected(-1)
+
+	case 'J':
+		m.moveSelected(1)
+
+	case ' ':
+		m.choices[m.selected].enabled = !m.choices[m.selected].enabled
+		m.apply()
+
+	default:
+		log.Debugf("Unhandled columns rune %q", char)
+	}
+}
@@ -0,0 +1,166 @@
+// List search hit counts for all open files, and jump to one of them
+
+package internal
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+)
+
+type fileHits struct {
+	reader      *reader.ReaderImpl
+	readerIndex int
+	description string // Like "[2/3] file.txt"
+
+	// Counts in the background, see update()
+	counter *hitCounter
+
+	// Nil if there are no hits
+	firstHit *linemetadata.Index
+
+	count int
+
+	// True if there may be more hits than we have counted so far
+	partial bool
+}
+
+type PagerModeFileHits struct {
+	pager *Pager
+
+	files []fileHits
+
+	// Index into files
+	selected int
+}
+
+// List the current search's hits in all files, the merged view excepted. The
+// hits are counted in the background.
+func NewPagerModeFileHits(p *Pager) *PagerModeFileHits {
+	p.readerLock.Lock()
+	files := []fileHits{}
+	selected := 0
+	for i, r := range p.readers {
+		if r == p.mergedReader {
+			continue
+		}
+		if i == p.currentReader {
+			selected = len(files)
+		}
+		files = append(files, fileHits{
+			reader:      r,
+			readerIndex: i,
+			description: p.describeFileUnlocked(i),
+			counter:     &hitCounter{},
+		})
+	}
+	p.readerLock.Unlock()
+
+	m := &PagerModeFileHits{
+		pager:    p,
+		files:    files,
+		selected: selected,
+	}
+	m.update()
+	return m
+}
+
+// Pick up the latest counts, and start counting any new lines
+func (m *PagerModeFileHits) update() {
+	p := m.pager
+	for i := range m.files {
+		file := &m.files[i]
+		key := hitCounterKey{reader: file.reader, search: p.search}
+		file.count, file.firstHit, file.partial = file.counter.count(
+			p.screen, key, file.reader, file.reader.ReadingDone.Load())
+	}
+}
+
+// Like "    12 hits  [2/3] file.txt"
+func (file fileHits) String() string {
+	count := fmt.Sprintf("%d", file.count)
+	if file.partial {
+		count += "+"
+	}
+
+	hits := "hits"
+	if file.count == 1 && !file.partial {
+		hits = "hit"
+	}
+
+	return fmt.Sprintf("%7s %-4s  %s", count, hits, file.description)
+}
+
+func (m *PagerModeFileHits) drawFooter(_ string, _ string, _ string) {
+	p := m.pager
+	m.update()
+	drawPicker(p, len(m.files), m.selected, func(i int) string {
+		return m.files[i].String()
+	})
+
+	p.setFooter("", "", "", "Hits for '"+p.search.String()+"', press '↑↓' to select a file, 'ENTER' to go there, 'ESC' to cancel")
+}
+
+func (m *PagerModeFileHits) goToSelected() {
+	p := m.pager
+	m.update()
+	file := m.files[m.selected]
+
+	p.switchToFile(file.readerIndex)
+	p.mode = PagerModeViewing{pager: p}
+	if file.firstHit == nil {
+		return
+	}
+
+	p.scrollPosition = NewScrollPositionFromIndex(*file.firstHit, "fileHits")
+	p.setTargetLine(nil)
+
+	p.leftColumnZeroBased = 0
+	p.showLineNumbers = p.ShowLineNumbers
+	if !p.searchHitIsVisible() {
+		p.scrollRightToSearchHits()
+	}
+	p.centerSearchHitsVertically()
+}
+
+func (m *PagerModeFileHits) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyUp:
+		m.selected = max(m.selected-1, 0)
+
+	case twin.KeyDown:
+		m.selected = min(m.selected+1, len(m.files)-1)
+
+	case twin.KeyEnter:
+		m.goToSelected()
+
+	case twin.KeyEscape:
+		p.mode = PagerModeViewing{pager: p}
+
+	default:
+		log.Debugf("Unhandled file hits key event %v", key)
+	}
+}
+
+func (m *PagerModeFileHits) onRune(char rune) {
+	p := m.pager
+
+	switch char {
+	case 'q':
+		p.mode = PagerModeViewing{pager: p}
+
+	case 'k':
+		m.onKey(twin.KeyUp)
+
+	case 'j':
+		m.onKey(twin.KeyDown)
+
+	default:
+		log.Debugf("Unhandled file hits rune %q", char)
+	}
+}
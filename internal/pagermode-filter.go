@@ -2,18 +2,21 @@ package internal
 
 import (
 	log "github.com/sirupsen/logrus"
-	"github.com/walles/moor/v2/internal/search"
 	"github.com/walles/moor/v2/twin"
 )
 
 type PagerModeFilter struct {
 	pager    *Pager
 	inputBox *InputBox
+
+	// The filter chain entry we're editing. Any earlier entries are kept.
+	entryIndex int
 }
 
 func NewPagerModeFilter(p *Pager) *PagerModeFilter {
 	m := &PagerModeFilter{
-		pager: p,
+		pager:      p,
+		entryIndex: len(p.filter.entries),
 	}
 	m.inputBox = &InputBox{
 		accept: INPUTBOX_ACCEPT_ALL,
@@ -25,12 +28,19 @@ func NewPagerModeFilter(p *Pager) *PagerModeFilter {
 }
 
 func (m PagerModeFilter) drawFooter(_ string, _ string, _ string) {
-	m.inputBox.draw(m.pager.screen, "Type to filter, 'ENTER' submits, 'ESC' cancels", "Filter: ")
+	prompt := "Filter: "
+	help := "Type to filter, start with '!' to hide matches, 'ENTER' submits, 'ESC' cancels"
+	if m.entryIndex > 0 {
+		prompt = "Add to filter " + m.pager.filter.truncated(m.entryIndex).String() + ": "
+		help = "Start with '!' to hide matches, '|' to OR, 'ENTER' submits, 'ESC' cancels"
+	}
+
+	m.inputBox.draw(m.pager.screen, help, prompt)
 }
 
 func (m *PagerModeFilter) updateFilterPattern(text string) {
-	m.pager.filter.For(text)
-	m.pager.search.For(text)
+	*m.pager.filter = m.pager.filter.with(m.entryIndex, text)
+	m.pager.search = m.pager.filter.highlight()
 }
 
 func (m *PagerModeFilter) onKey(key twin.KeyCode) {
@@ -41,11 +51,15 @@ func (m *PagerModeFilter) onKey(key twin.KeyCode) {
 	switch key {
 	case twin.KeyEnter:
 		m.pager.mode = PagerModeViewing{pager: m.pager}
+		if len(m.pager.filter.entries) > m.entryIndex && !m.pager.filter.entries[m.entryIndex].active() {
+			// Nothing typed, don't keep an empty entry around
+			*m.pager.filter = m.pager.filter.truncated(m.entryIndex)
+		}
 
 	case twin.KeyEscape:
 		m.pager.mode = PagerModeViewing{pager: m.pager}
-		m.pager.filter = search.Search{}
-		m.pager.search.Clear()
+		*m.pager.filter = m.pager.filter.truncated(m.entryIndex)
+		m.pager.search = m.pager.filter.highlight()
 
 	case twin.KeyUp, twin.KeyDown, twin.KeyPgUp, twin.KeyPgDown:
 		viewing := PagerModeViewing{pager: m.pager}
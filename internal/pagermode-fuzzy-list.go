@@ -0,0 +1,158 @@
+// List the best fuzzy search matches, and jump to one of them
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Don't list more lines than this, nobody will scroll through them anyway
+const maxFuzzyListLines = 100
+
+type fuzzyListLine struct {
+	index  linemetadata.Index
+	number linemetadata.Number
+	plain  string
+	score  int
+}
+
+type PagerModeFuzzyList struct {
+	pager *Pager
+
+	// Where to go back to on ESC
+	previousMode PagerMode
+
+	// Best match first
+	lines []fuzzyListLine
+
+	// Index into lines
+	selected int
+}
+
+// Returns nil if there are no matches to list
+func NewPagerModeFuzzyList(p *Pager, previousMode PagerMode) *PagerModeFuzzyList {
+	lines := findTopScoringLines(p.Reader(), p.search, maxFuzzyListLines)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return &PagerModeFuzzyList{
+		pager:        p,
+		previousMode: previousMode,
+		lines:        lines,
+	}
+}
+
+// Score all lines read so far, and return the best ones in order. Equal scores
+// are listed in input order.
+func findTopScoringLines(r reader.Reader, searchFor search.Search, maxLines int) []fuzzyListLine {
+	byScore := func(lines []fuzzyListLine) {
+		sort.SliceStable(lines, func(i, j int) bool {
+			return lines[i].score > lines[j].score
+		})
+	}
+
+	result := []fuzzyListLine{}
+	lineCache := searchLineCache{}
+	lineCount := r.GetLineCount()
+	for i := 0; i < lineCount; i++ {
+		line := lineCache.GetLine(r, linemetadata.IndexFromZeroBased(i), SearchDirectionForward)
+		if line == nil {
+			// Out of lines
+			break
+		}
+
+		plain := line.Plain()
+		score, ok := searchFor.Score(plain)
+		if !ok {
+			continue
+		}
+
+		result = append(result, fuzzyListLine{
+			index:  line.Index,
+			number: line.Number,
+			plain:  plain,
+			score:  score,
+		})
+
+		if len(result) >= 2*maxLines {
+			// Don't hold on to more lines than we need
+			byScore(result)
+			result = result[:maxLines]
+		}
+	}
+
+	byScore(result)
+	return result[:min(len(result), maxLines)]
+}
+
+func (m *PagerModeFuzzyList) drawFooter(_ string, _ string, _ string) {
+	p := m.pager
+	drawPicker(p, len(m.lines), m.selected, func(i int) string {
+		line := m.lines[i]
+		return fmt.Sprintf("%6s  %s", line.number.Format(), line.plain)
+	})
+
+	p.setFooter("", "", "", "Press '↑↓' to select a line, 'ENTER' to jump to it, 'ESC' to go back")
+}
+
+func (m *PagerModeFuzzyList) jumpToSelected() {
+	p := m.pager
+
+	p.scrollPosition = NewScrollPositionFromIndex(m.lines[m.selected].index, "fuzzyList")
+	p.leftColumnZeroBased = 0
+	p.showLineNumbers = p.ShowLineNumbers
+	if !p.searchHitIsVisible() {
+		p.scrollRightToSearchHits()
+	}
+	p.centerSearchHitsVertically()
+
+	p.mode = PagerModeViewing{pager: p}
+	p.setTargetLine(nil) // Viewing doesn't need all lines
+}
+
+func (m *PagerModeFuzzyList) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyUp:
+		m.selected = max(m.selected-1, 0)
+
+	case twin.KeyDown:
+		m.selected = min(m.selected+1, len(m.lines)-1)
+
+	case twin.KeyEnter:
+		m.jumpToSelected()
+
+	case twin.KeyEscape:
+		p.mode = m.previousMode
+
+	default:
+		log.Debugf("Unhandled fuzzy list key event %v", key)
+	}
+}
+
+func (m *PagerModeFuzzyList) onRune(char rune) {
+	p := m.pager
+
+	switch char {
+	case 'q':
+		p.mode = m.previousMode
+
+	case 'k':
+		m.onKey(twin.KeyUp)
+
+	case 'j':
+		m.onKey(twin.KeyDown)
+
+	default:
+		log.Debugf("Unhandled fuzzy list rune %q", char)
+	}
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestFuzzyList(t *testing.T) {
+	lines := "c o n r e f\nnothing\nconnection refused\nconref\n"
+	for i := 0; i < 20; i++ {
+		lines += "filler\n"
+	}
+	pager := NewPager(reader.NewFromTextForTesting("test", lines))
+	screen := twin.NewFakeScreen(30, 10)
+	pager.screen = screen
+
+	pager.mode.onRune('/')
+	pager.mode.onRune('\x12') // Literal
+	pager.mode.onRune('\x12') // Regexp
+	pager.mode.onRune('\x12') // Fuzzy
+	for _, char := range "conref" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onRune('\t')
+	_, isList := pager.mode.(*PagerModeFuzzyList)
+	assert.Assert(t, isList)
+
+	// Best match first
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(6)), "     4  conref")
+	assert.Equal(t, rowToString(screen.GetRow(7)), "     3  connection refused")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "     1  c o n r e f")
+
+	// ESC goes back to searching
+	pager.mode.onKey(twin.KeyEscape)
+	_, isSearch := pager.mode.(*PagerModeSearch)
+	assert.Assert(t, isSearch)
+
+	pager.mode.onRune('\t')
+	pager.mode.onRune('j')
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, pager.search.Modifiers().Pattern, search.PatternFuzzy)
+	pager.redraw("")
+	assert.Assert(t, pager.lineIndex().Index() <= 2)
+	assert.Assert(t, pager.lineIndex().Index()+pager.visibleHeight() > 2)
+}
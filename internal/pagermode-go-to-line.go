@@ -1,10 +1,14 @@
 package internal
 
 import (
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
 	"github.com/walles/moor/v2/twin"
 )
 
@@ -17,7 +21,7 @@ func NewPagerModeGotoLine(p *Pager) *PagerModeGotoLine {
 	m := &PagerModeGotoLine{
 		pager: p,
 		inputBox: InputBox{
-			accept:        INPUTBOX_ACCEPT_POSITIVE_NUMBERS,
+			accept:        INPUTBOX_ACCEPT_GOTO_TARGETS,
 			onTextChanged: nil,
 		},
 	}
@@ -25,25 +29,212 @@ func NewPagerModeGotoLine(p *Pager) *PagerModeGotoLine {
 }
 
 func (m *PagerModeGotoLine) drawFooter(_ string, _ string, _ string) {
-	m.inputBox.draw(m.pager.screen, "'ENTER' submits, 'ESC' cancels", "Go to line number: ")
+	m.inputBox.draw(m.pager.screen, "'ENTER' submits, 'ESC' cancels", "Go to line number, 50% or b1048576: ")
 }
 
-func (m *PagerModeGotoLine) updateLineNumber(text string) {
-	newLineNumber, err := strconv.Atoi(text)
+type gotoKind int
+
+const (
+	gotoLineNumber gotoKind = iota
+	gotoPercent
+	gotoByteOffset
+)
+
+type gotoTarget struct {
+	kind  gotoKind
+	value int64
+}
+
+// A position we haven't read far enough to find yet
+type pendingGoto struct {
+	target   gotoTarget
+	estimate linemetadata.Index
+
+	// We read without pausing until we know where to go, then restore this
+	reader          *reader.ReaderImpl
+	pauseAfterLines int
+}
+
+func (target gotoTarget) String() string {
+	switch target.kind {
+	case gotoPercent:
+		return fmt.Sprintf("%d%%", target.value)
+	case gotoByteOffset:
+		return fmt.Sprintf("byte %d", target.value)
+	}
+	return fmt.Sprintf("line %d", target.value)
+}
+
+// Parse "123", "50%" or "b1048576"
+func parseGotoTarget(text string) (gotoTarget, bool) {
+	target := gotoTarget{kind: gotoLineNumber}
+	if number, found := strings.CutSuffix(text, "%"); found {
+		target.kind = gotoPercent
+		text = number
+	} else if number, found := strings.CutPrefix(text, "b"); found {
+		target.kind = gotoByteOffset
+		text = number
+	}
+
+	value, err := strconv.ParseInt(text, 10, 64)
 	if err != nil {
 		log.Debugf("Got non-number goto text '%s'", text)
+		return target, false
+	}
+	target.value = value
+
+	switch target.kind {
+	case gotoLineNumber:
+		if value < 1 {
+			log.Debugf("Got non-positive goto line number: %d", value)
+			return target, false
+		}
+	case gotoPercent:
+		if value > 100 {
+			log.Debugf("Got goto percentage above 100: %d", value)
+			return target, false
+		}
+	}
+
+	return target, true
+}
+
+func (m *PagerModeGotoLine) updateLineNumber(text string) {
+	target, ok := parseGotoTarget(text)
+	if !ok {
 		return
 	}
-	if newLineNumber < 1 {
-		log.Debugf("Got non-positive goto line number: %d", newLineNumber)
+
+	if target.kind == gotoLineNumber {
+		m.pager.goToLineNumber(int(target.value))
 		return
 	}
-	targetIndex := linemetadata.IndexFromOneBased(newLineNumber)
-	m.pager.scrollPosition = NewScrollPositionFromIndex(
+
+	m.pager.goToPosition(target)
+}
+
+// Scroll to a one based line number
+func (p *Pager) goToLineNumber(lineNumber int) {
+	targetIndex := linemetadata.IndexFromOneBased(lineNumber)
+	p.scrollPosition = NewScrollPositionFromIndex(
 		targetIndex,
 		"onGotoLineKey",
 	)
-	m.pager.setTargetLine(&targetIndex)
+	p.setTargetLine(&targetIndex)
+}
+
+// Go to a percentage or a byte offset. If we haven't read that far yet, go to
+// an estimate and correct it as we read more, see updatePendingGoto().
+func (p *Pager) goToPosition(target gotoTarget) {
+	p.cancelPendingGoto()
+
+	index, exact, ok := p.resolveGotoTarget(target)
+	if !ok {
+		p.mode = &PagerModeInfo{Pager: p, Text: "Byte offsets don't work while filtering"}
+		return
+	}
+
+	if exact {
+		p.goToLineNumber(index.Index() + 1)
+		return
+	}
+
+	log.Debugf("Going to estimated line %d for %s", index.Index()+1, target)
+	p.scrollPosition = NewScrollPositionFromIndex(index, "goToPosition")
+	p.setTargetLine(&index)
+
+	p.readerLock.Lock()
+	r := p.readers[p.currentReader]
+	p.readerLock.Unlock()
+
+	p.pendingGoto = &pendingGoto{
+		target:          target,
+		estimate:        index,
+		reader:          r,
+		pauseAfterLines: r.PauseAfterLines(),
+	}
+	p.readUntilPendingGotoResolved()
+}
+
+func (p *Pager) readUntilPendingGotoResolved() {
+	p.pendingGoto.reader.SetPauseAfterLines(math.MaxInt)
+}
+
+// Stop waiting for a pending goto, and stop reading past where we would
+// otherwise have paused. Doesn't take the reader lock, so this can be called
+// with it held.
+func (p *Pager) cancelPendingGoto() {
+	if p.pendingGoto == nil {
+		return
+	}
+
+	p.pendingGoto.reader.SetPauseAfterLines(p.pendingGoto.pauseAfterLines)
+	p.pendingGoto = nil
+}
+
+// ok is false if we can't go there
+func (p *Pager) resolveGotoTarget(target gotoTarget) (index linemetadata.Index, exact bool, ok bool) {
+	r := _HelpReader
+	if !p.isShowingHelp {
+		p.readerLock.Lock()
+		r = p.readers[p.currentReader]
+		p.readerLock.Unlock()
+	}
+
+	if p.isShowingHelp || p.filter.Inactive() {
+		if target.kind == gotoByteOffset {
+			index, exact = r.LineAtByteOffset(target.value)
+			return index, exact, true
+		}
+		index, exact = r.LineAtPercent(int(target.value))
+		return index, exact, true
+	}
+
+	// Filtered lines are numbered differently from the input, so this one can
+	// only be done by line count
+	if target.kind == gotoByteOffset {
+		return linemetadata.Index{}, false, false
+	}
+
+	lineCount := p.Reader().GetLineCount()
+	if lineCount == 0 {
+		return linemetadata.Index{}, r.ReadingDone.Load(), true
+	}
+	index = linemetadata.IndexFromZeroBased(min(lineCount*int(target.value)/100, lineCount-1))
+	return index, r.ReadingDone.Load(), true
+}
+
+// Called when more lines are available, to replace an estimated position with
+// the real one once we know it
+func (p *Pager) updatePendingGoto() {
+	if p.pendingGoto == nil {
+		return
+	}
+
+	index, exact, ok := p.resolveGotoTarget(p.pendingGoto.target)
+	if !ok {
+		p.cancelPendingGoto()
+		return
+	}
+	if !exact {
+		// Reaching the estimate may have paused the reader
+		p.readUntilPendingGotoResolved()
+		return
+	}
+
+	log.Debugf("Found line %d for %s", index.Index()+1, p.pendingGoto.target)
+	p.cancelPendingGoto()
+	p.goToLineNumber(index.Index() + 1)
+}
+
+// Like "going to 50%, line ~1234 is an estimate", or empty
+func (p *Pager) pendingGotoText() string {
+	if p.pendingGoto == nil {
+		return ""
+	}
+
+	lineNumber := linemetadata.NumberFromZeroBased(p.pendingGoto.estimate.Index()).Format()
+	return fmt.Sprintf("going to %s, line ~%s is an estimate", p.pendingGoto.target, lineNumber)
 }
 
 func (m *PagerModeGotoLine) onKey(key twin.KeyCode) {
@@ -53,8 +244,9 @@ func (m *PagerModeGotoLine) onKey(key twin.KeyCode) {
 
 	switch key {
 	case twin.KeyEnter:
-		m.updateLineNumber(m.inputBox.text)
+		// Before going there, so that any info message stays
 		m.pager.mode = PagerModeViewing{pager: m.pager}
+		m.updateLineNumber(m.inputBox.text)
 
 	case twin.KeyEscape:
 		m.pager.mode = PagerModeViewing{pager: m.pager}
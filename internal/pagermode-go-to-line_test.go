@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseGotoTarget(t *testing.T) {
+	for text, expected := range map[string]gotoTarget{
+		"12":       {kind: gotoLineNumber, value: 12},
+		"50%":      {kind: gotoPercent, value: 50},
+		"0%":       {kind: gotoPercent, value: 0},
+		"b1048576": {kind: gotoByteOffset, value: 1048576},
+		"b0":       {kind: gotoByteOffset, value: 0},
+	} {
+		target, ok := parseGotoTarget(text)
+		assert.Assert(t, ok, text)
+		assert.Equal(t, target, expected, text)
+	}
+
+	for _, text := range []string{"", "0", "%", "b", "101%", "5b", "1b2", "50%%"} {
+		_, ok := parseGotoTarget(text)
+		assert.Assert(t, !ok, text)
+	}
+}
+
+// 100 lines, "1" to "100"
+func newGotoTestPager(t *testing.T) *Pager {
+	lines := []string{}
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	return newCommandTestPager(t, strings.Join(lines, "\n"))
+}
+
+func typeGotoTarget(pager *Pager, text string) {
+	pager.mode = NewPagerModeGotoLine(pager)
+	for _, char := range text {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onKey(twin.KeyEnter)
+}
+
+func TestGotoPercent(t *testing.T) {
+	pager := newGotoTestPager(t)
+
+	typeGotoTarget(pager, "50%")
+	assert.Equal(t, pager.lineIndex().Index(), 50)
+	assert.Assert(t, pager.pendingGoto == nil)
+
+	typeGotoTarget(pager, "0%")
+	assert.Equal(t, pager.lineIndex().Index(), 0)
+}
+
+func TestGotoByteOffset(t *testing.T) {
+	pager := newGotoTestPager(t)
+
+	// Lines 1-9 are two bytes each, so line 10 starts at byte 18
+	typeGotoTarget(pager, "b18")
+	assert.Equal(t, pager.lineIndex().Index(), 9)
+	assert.Assert(t, pager.pendingGoto == nil)
+}
+
+func TestGotoByteOffsetWhileFiltering(t *testing.T) {
+	pager := newGotoTestPager(t)
+	*pager.filter = filterChainFor("1")
+
+	typeGotoTarget(pager, "b18")
+	_, isInfo := pager.mode.(*PagerModeInfo)
+	assert.Assert(t, isInfo, "Byte offsets into filtered views should be refused")
+
+	// Percentages go by the filtered lines
+	assert.Equal(t, pager.Reader().GetLineCount(), 20)
+	typeGotoTarget(pager, "50%")
+	assert.Equal(t, pager.lineIndex().Index(), 10)
+}
+
+// Before we have read everything, percentages are estimates, and are corrected
+// when we know more
+func TestGotoPercentPending(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	r := reader.NewFromUncompressedStream("test", pipeReader, nil, reader.ReaderOptions{})
+	pager := NewPager(r)
+	pager.screen = twin.NewFakeScreen(20, 10)
+
+	go func() {
+		_, _ = pipeWriter.Write([]byte("1\n2\n3\n4\n"))
+	}()
+	for r.GetLineCount() < 4 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	typeGotoTarget(pager, "50%")
+	assert.Assert(t, pager.pendingGoto != nil)
+	assert.Equal(t, pager.pendingGotoText(), "going to 50%, line ~3 is an estimate")
+	assert.Equal(t, r.PauseAfterLines(), math.MaxInt, "Should read until we know where to go")
+
+	go func() {
+		_, _ = pipeWriter.Write([]byte("5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n"))
+		_ = pipeWriter.Close()
+	}()
+	for !r.ReadingDone.Load() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pager.updatePendingGoto()
+	assert.Assert(t, pager.pendingGoto == nil)
+	assert.Equal(t, pager.lineIndex().Index(), 10)
+	assert.Assert(t, r.PauseAfterLines() != math.MaxInt, "Should pause again once we got there")
+}
+
+// Scrolling away from a pending goto should make the reader pause again
+func TestGotoPercentPendingCanceled(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	r := reader.NewFromUncompressedStream("test", pipeReader, nil, reader.ReaderOptions{})
+	pager := NewPager(r)
+	pager.screen = twin.NewFakeScreen(20, 10)
+	defer func() { _ = pipeWriter.Close() }()
+
+	go func() {
+		_, _ = pipeWriter.Write([]byte("1\n2\n3\n4\n"))
+	}()
+	for r.GetLineCount() < 4 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	typeGotoTarget(pager, "50%")
+	assert.Assert(t, pager.pendingGoto != nil)
+	assert.Equal(t, r.PauseAfterLines(), math.MaxInt)
+
+	pager.handleScrolledUp()
+	assert.Assert(t, pager.pendingGoto == nil)
+	assert.Equal(t, r.PauseAfterLines(), reader.DEFAULT_PAUSE_AFTER_LINES)
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+)
+
+type PagerModeHighlight struct {
+	pager    *Pager
+	inputBox *InputBox
+
+	// The highlight we're editing. Any earlier highlights are kept.
+	highlightIndex int
+}
+
+func NewPagerModeHighlight(p *Pager) *PagerModeHighlight {
+	m := &PagerModeHighlight{
+		pager:          p,
+		highlightIndex: len(p.highlights),
+	}
+	m.inputBox = &InputBox{
+		accept: INPUTBOX_ACCEPT_ALL,
+		onTextChanged: func(text string) {
+			m.updateHighlightPattern(text)
+		},
+	}
+	return m
+}
+
+func (m PagerModeHighlight) drawFooter(_ string, _ string, _ string) {
+	prompt := "Highlight: "
+	if m.highlightIndex > 0 {
+		prompt = fmt.Sprintf("Highlight #%d: ", m.highlightIndex+1)
+	}
+
+	m.inputBox.draw(m.pager.screen, "Type to highlight, 'ENTER' submits, 'ESC' cancels, ':unhighlight' removes", prompt)
+}
+
+func (m *PagerModeHighlight) updateHighlightPattern(text string) {
+	highlights := slices.Clone(m.pager.highlights[:m.highlightIndex])
+	m.pager.highlights = append(highlights, search.For(text))
+}
+
+func (m *PagerModeHighlight) onKey(key twin.KeyCode) {
+	if m.inputBox.handleKey(key) {
+		return
+	}
+
+	switch key {
+	case twin.KeyEnter:
+		m.pager.mode = PagerModeViewing{pager: m.pager}
+		if len(m.pager.highlights) > m.highlightIndex && m.pager.highlights[m.highlightIndex].Inactive() {
+			// Nothing typed, don't keep an empty highlight around
+			m.pager.highlights = m.pager.highlights[:m.highlightIndex]
+		}
+
+	case twin.KeyEscape:
+		m.pager.mode = PagerModeViewing{pager: m.pager}
+		m.pager.highlights = m.pager.highlights[:min(m.highlightIndex, len(m.pager.highlights))]
+
+	default:
+		log.Debugf("Unhandled highlight key event %v", key)
+	}
+}
+
+func (m *PagerModeHighlight) onRune(char rune) {
+	m.inputBox.handleRune(char)
+}
@@ -24,18 +24,18 @@ func (m PagerModeJumpToMark) drawFooter(_ string, _ string, _ string) {
 
 func (m PagerModeJumpToMark) getMarkPrompt() string {
 	// Special case having zero, one or multiple marks
-	if len(m.pager.bookmarks) == 0 {
+	if len(m.pager.currentBookmarks()) == 0 {
 		return "No marks set, press 'm' to set one!"
 	}
 
-	if len(m.pager.bookmarks) == 1 {
-		for key := range m.pager.bookmarks {
+	if len(m.pager.currentBookmarks()) == 1 {
+		for key := range m.pager.currentBookmarks() {
 			return "Jump to your mark: " + string(key)
 		}
 	}
 
 	// Multiple marks, list them
-	marks := maps.Keys(m.pager.bookmarks)
+	marks := maps.Keys(m.pager.currentBookmarks())
 	sort.Slice(marks, func(i, j int) bool {
 		return marks[i] < marks[j]
 	})
@@ -67,15 +67,28 @@ func (m PagerModeJumpToMark) onKey(key twin.KeyCode) {
 }
 
 func (m PagerModeJumpToMark) onRune(char rune) {
-	if len(m.pager.bookmarks) == 0 && char == 'm' {
+	if len(m.pager.currentBookmarks()) == 0 && char == 'm' {
 		m.pager.mode = PagerModeMark(m)
 		return
 	}
 
-	destination, ok := m.pager.bookmarks[char]
-	if ok {
-		m.pager.scrollPosition = destination
+	m.pager.jumpToMark(char)
+	m.pager.mode = PagerModeViewing(m)
+}
+
+// Does nothing if the mark doesn't exist
+func (p *Pager) jumpToMark(mark rune) {
+	destination, ok := p.currentBookmarks()[mark]
+	if !ok {
+		return
 	}
 
-	m.pager.mode = PagerModeViewing(m)
+	p.scrollPosition = destination
+
+	// Marks from earlier sessions may point further down than we have read so
+	// far
+	index := destination.unclippedLineIndex()
+	if index != nil && index.Index() >= p.Reader().GetLineCount() {
+		p.setTargetLine(index)
+	}
 }
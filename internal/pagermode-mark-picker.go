@@ -0,0 +1,153 @@
+// Pick a mark to jump to from a list of all marks
+
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+	"golang.org/x/exp/maps"
+)
+
+type PagerModeMarkPicker struct {
+	pager *Pager
+
+	// Sorted
+	marks []rune
+
+	// Index into marks
+	selected int
+}
+
+// Returns nil if there are no marks to pick from
+func NewPagerModeMarkPicker(p *Pager) *PagerModeMarkPicker {
+	bookmarks := p.currentBookmarks()
+	marks := maps.Keys(bookmarks)
+	if len(marks) == 0 {
+		return nil
+	}
+	slices.Sort(marks)
+
+	return &PagerModeMarkPicker{
+		pager: p,
+		marks: marks,
+	}
+}
+
+// One line of the picker, like "a   123  first part of the line"
+func (m *PagerModeMarkPicker) describe(mark rune) string {
+	p := m.pager
+
+	position := p.currentBookmarks()[mark]
+	index := position.unclippedLineIndex()
+	if index == nil {
+		return string(mark)
+	}
+
+	label := string(mark)
+	if mark == '\'' {
+		// This one is restored from an earlier session
+		label = "''"
+	}
+
+	line := p.Reader().GetLine(*index)
+	if line == nil {
+		// Not read yet
+		return fmt.Sprintf("%-2s %6d", label, index.Index()+1)
+	}
+
+	return fmt.Sprintf("%-2s %6s  %s", label, line.Number.Format(), line.Plain())
+}
+
+func (m *PagerModeMarkPicker) drawFooter(_ string, _ string, _ string) {
+	p := m.pager
+	drawPicker(p, len(m.marks), m.selected, func(i int) string {
+		return m.describe(m.marks[i])
+	})
+
+	p.setFooter("", "", "", "Press '↑↓' to select a mark, 'ENTER' to jump to it, 'ESC' to cancel")
+}
+
+func (m *PagerModeMarkPicker) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyUp:
+		m.selected = max(m.selected-1, 0)
+
+	case twin.KeyDown:
+		m.selected = min(m.selected+1, len(m.marks)-1)
+
+	case twin.KeyEnter:
+		p.jumpToMark(m.marks[m.selected])
+		p.mode = PagerModeViewing{pager: p}
+
+	case twin.KeyEscape:
+		p.mode = PagerModeViewing{pager: p}
+
+	default:
+		log.Debugf("Unhandled mark picker key event %v", key)
+	}
+}
+
+func (m *PagerModeMarkPicker) onRune(char rune) {
+	p := m.pager
+
+	switch char {
+	case 'q':
+		p.mode = PagerModeViewing{pager: p}
+
+	case 'k':
+		m.onKey(twin.KeyUp)
+
+	case 'j':
+		m.onKey(twin.KeyDown)
+
+	default:
+		// Jump directly to the mark, just like after pressing '
+		if slices.Contains(m.marks, char) {
+			p.jumpToMark(char)
+			p.mode = PagerModeViewing{pager: p}
+		}
+	}
+}
+
+// Draw a list of choices just above the footer, with the selected one
+// highlighted
+func drawPicker(p *Pager, count int, selected int, describe func(i int) string) {
+	width, height := p.screen.Size()
+
+	// Leave at least one line of content visible above the picker
+	rowCount := min(count, height-2)
+	if rowCount < 1 {
+		rowCount = 1
+	}
+
+	// Scroll the list so that the selected entry is always visible
+	first := 0
+	if selected >= rowCount {
+		first = selected - rowCount + 1
+	}
+
+	firstRow := height - 1 - rowCount
+	for row := 0; row < rowCount; row++ {
+		index := first + row
+		style := twin.StyleDefault
+		if index == selected {
+			style = style.WithAttr(twin.AttrReverse)
+		}
+
+		pos := 0
+		for _, char := range describe(index) {
+			if pos >= width {
+				break
+			}
+			pos += p.screen.SetCell(pos, firstRow+row, twin.NewStyledRune(char, style))
+		}
+		for pos < width {
+			pos += p.screen.SetCell(pos, firstRow+row, twin.NewStyledRune(' ', style))
+		}
+	}
+}
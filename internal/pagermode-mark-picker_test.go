@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestMarkPicker(t *testing.T) {
+	lines := []string{}
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i))
+	}
+	pager := NewPager(reader.NewFromTextForTesting("test", strings.Join(lines, "\n")))
+	screen := twin.NewFakeScreen(30, 10)
+	pager.screen = screen
+
+	assert.Assert(t, NewPagerModeMarkPicker(pager) == nil)
+
+	pager.currentBookmarks()['b'] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(20), "b")
+	pager.currentBookmarks()['a'] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(5), "a")
+
+	pager.mode.onRune('M')
+	_, isPicker := pager.mode.(*PagerModeMarkPicker)
+	assert.Assert(t, isPicker)
+
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(7)), "a       5  line xxxxx")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "b      20  line xxxxxxxxxxxxxx")
+
+	pager.mode.onKey(twin.KeyDown)
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, pager.lineIndex().Index(), 19)
+
+	// Letters jump directly
+	pager.mode.onRune('M')
+	pager.mode.onRune('a')
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, pager.lineIndex().Index(), 4)
+}
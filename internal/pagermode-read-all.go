@@ -0,0 +1,90 @@
+// Wait for the reader to read all lines before doing something with them, like
+// saving them to a file. Shows progress while reading, ESC cancels.
+
+package internal
+
+import (
+	"fmt"
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+)
+
+type PagerModeReadAll struct {
+	pager *Pager
+
+	// Like "saving to x.txt"
+	what string
+
+	reader *reader.ReaderImpl
+
+	// Restored when we're done, so that we don't keep reading forever
+	pauseAfterLines int
+
+	// Called on the UI goroutine once all lines have been read
+	action func() error
+}
+
+// Read all lines of the current file, then call action. If all lines have
+// already been read, action is called right away.
+func (p *Pager) readAllThen(what string, action func() error) error {
+	p.readerLock.Lock()
+	r := p.readers[p.currentReader]
+	p.readerLock.Unlock()
+
+	if r.ReadingDone.Load() {
+		return action()
+	}
+
+	log.Infof("Reading all lines before %s...", what)
+	m := &PagerModeReadAll{
+		pager:           p,
+		what:            what,
+		reader:          r,
+		pauseAfterLines: r.PauseAfterLines(),
+		action:          action,
+	}
+	r.SetPauseAfterLines(math.MaxInt)
+	p.mode = m
+	return nil
+}
+
+// Called when more lines are available. If we're waiting for all lines to be
+// read and they are, go ahead.
+func (p *Pager) maybeDoneReadingAll() {
+	m, ok := p.mode.(*PagerModeReadAll)
+	if !ok || !m.reader.ReadingDone.Load() {
+		return
+	}
+
+	m.reader.SetPauseAfterLines(m.pauseAfterLines)
+	p.mode = PagerModeViewing{pager: p}
+
+	err := m.action()
+	if err != nil {
+		log.Infof("Failed %s: %v", m.what, err)
+		p.mode = &PagerModeInfo{Pager: p, Text: err.Error()}
+	}
+}
+
+func (m *PagerModeReadAll) drawFooter(_ string, _ string, spinner string) {
+	text := fmt.Sprintf("Reading all lines before %s: %d lines so far %s",
+		m.what, m.reader.GetLineCount(), spinner)
+	m.pager.setFooter(text, "", "", "ESC to cancel")
+}
+
+func (m *PagerModeReadAll) onKey(key twin.KeyCode) {
+	if key != twin.KeyEscape {
+		return
+	}
+
+	log.Infof("Canceled reading all lines before %s", m.what)
+	m.reader.SetPauseAfterLines(m.pauseAfterLines)
+	m.pager.mode = PagerModeViewing{pager: m.pager}
+}
+
+func (m *PagerModeReadAll) onRune(_ rune) {
+	// Wait for reading to finish or for ESC
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/search"
 	"github.com/walles/moor/v2/twin"
 )
 
@@ -19,6 +20,10 @@ type PagerModeSearch struct {
 	inputBox              *InputBox
 	searchHistoryIndex    int
 	userEditedText        string
+	userEditedModifiers   search.Modifiers
+
+	// Toggled while typing, saved with the search history entry
+	modifiers search.Modifiers
 }
 
 func NewPagerModeSearch(p *Pager, direction SearchDirection, initialScrollPosition scrollPosition) *PagerModeSearch {
@@ -31,25 +36,41 @@ func NewPagerModeSearch(p *Pager, direction SearchDirection, initialScrollPositi
 	m.inputBox = &InputBox{
 		accept: INPUTBOX_ACCEPT_ALL,
 		onTextChanged: func(text string) {
-			m.pager.search.For(text)
-
-			switch m.direction {
-			case SearchDirectionBackward:
-				m.pager.scrollToSearchHitsBackwards()
-			case SearchDirectionForward:
-				m.pager.scrollToSearchHits()
-			}
+			m.updateSearch(text)
 		},
 	}
 	return m
 }
 
+func (m *PagerModeSearch) updateSearch(text string) {
+	m.pager.search.ForWithModifiers(text, m.modifiers)
+
+	switch m.direction {
+	case SearchDirectionBackward:
+		m.pager.scrollToSearchHitsBackwards()
+	case SearchDirectionForward:
+		m.pager.scrollToSearchHits()
+	}
+}
+
 func (m PagerModeSearch) drawFooter(_ string, _ string, _ string) {
-	prompt := "Search: "
+	prompt := "Search"
 	if m.direction == SearchDirectionBackward {
-		prompt = "Search backwards: "
+		prompt = "Search backwards"
+	}
+	if modifiers := m.modifiers.String(); modifiers != "" {
+		prompt += " [" + modifiers + "]"
 	}
-	m.inputBox.draw(m.pager.screen, "Type to search, 'ENTER' submits, 'ESC' cancels, '↑↓' navigate history", prompt)
+	prompt += ": "
+
+	help := "Type to search, 'ENTER' submits, 'ESC' cancels, '↑↓' navigate history"
+	if m.inputBox.text != "" {
+		help = "'^R' literal/regexp/fuzzy, '^T' case, '^W' whole word, 'ENTER' submits, 'ESC' cancels"
+	}
+	if m.inputBox.text != "" && m.modifiers.Pattern == search.PatternFuzzy {
+		help = "'TAB' lists best matches, '^R' literal/regexp/fuzzy, 'ENTER' submits, 'ESC' cancels"
+	}
+	m.inputBox.draw(m.pager.screen, help, prompt)
 }
 
 func (m *PagerModeSearch) moveSearchHistoryIndex(delta int) {
@@ -67,10 +88,14 @@ func (m *PagerModeSearch) moveSearchHistoryIndex(delta int) {
 
 	if m.searchHistoryIndex == len(m.pager.searchHistory.entries) {
 		// Reset to whatever the user typed last
+		m.modifiers = m.userEditedModifiers
 		m.inputBox.setText(m.userEditedText)
 	} else {
 		// Get the history entry
-		m.inputBox.setText(m.pager.searchHistory.entries[m.searchHistoryIndex])
+		text := m.pager.searchHistory.entries[m.searchHistoryIndex]
+		modifiers := m.pager.searchHistory.modifiersFor(text)
+		m.modifiers = modifiers
+		m.inputBox.setText(text)
 	}
 }
 
@@ -78,23 +103,24 @@ func (m *PagerModeSearch) onKey(key twin.KeyCode) {
 	if m.inputBox.handleKey(key) {
 		m.searchHistoryIndex = len(m.pager.searchHistory.entries) // Reset history index when user types
 		m.userEditedText = m.inputBox.text
+		m.userEditedModifiers = m.modifiers
 		return
 	}
 
 	switch key {
 	case twin.KeyEnter:
-		m.pager.searchHistory.addEntry(m.inputBox.text)
+		m.pager.searchHistory.addSearch(m.inputBox.text, m.modifiers)
 		m.pager.mode = PagerModeViewing{pager: m.pager}
 		m.pager.setTargetLine(nil) // Viewing doesn't need all lines
 
 	case twin.KeyEscape:
-		m.pager.searchHistory.addEntry(m.inputBox.text)
+		m.pager.searchHistory.addSearch(m.inputBox.text, m.modifiers)
 		m.pager.mode = PagerModeViewing{pager: m.pager}
 		m.pager.scrollPosition = m.initialScrollPosition
 		m.pager.setTargetLine(nil) // Viewing doesn't need all lines
 
 	case twin.KeyPgUp, twin.KeyPgDown:
-		m.pager.searchHistory.addEntry(m.inputBox.text)
+		m.pager.searchHistory.addSearch(m.inputBox.text, m.modifiers)
 		m.pager.mode = PagerModeViewing{pager: m.pager}
 		m.pager.mode.onKey(key)
 		m.pager.setTargetLine(nil) // Viewing doesn't need all lines
@@ -112,6 +138,30 @@ func (m *PagerModeSearch) onKey(key twin.KeyCode) {
 
 func (m *PagerModeSearch) onRune(char rune) {
 	m.searchHistoryIndex = len(m.pager.searchHistory.entries) // Reset history index when user types
-	m.inputBox.handleRune(char)
+
+	switch char {
+	case '\x12': // CTRL-r, like in less
+		m.modifiers = m.modifiers.WithNextPatternMode()
+		m.updateSearch(m.inputBox.text)
+	case '\x14': // CTRL-t
+		m.modifiers = m.modifiers.WithNextCaseMode()
+		m.updateSearch(m.inputBox.text)
+	case '\x17': // CTRL-w
+		m.modifiers.WholeWord = !m.modifiers.WholeWord
+		m.updateSearch(m.inputBox.text)
+	case '\t':
+		if m.modifiers.Pattern != search.PatternFuzzy {
+			m.inputBox.handleRune(char)
+			break
+		}
+		if list := NewPagerModeFuzzyList(m.pager, m); list != nil {
+			m.pager.searchHistory.addSearch(m.inputBox.text, m.modifiers)
+			m.pager.mode = list
+		}
+	default:
+		m.inputBox.handleRune(char)
+	}
+
 	m.userEditedText = m.inputBox.text
+	m.userEditedModifiers = m.modifiers
 }
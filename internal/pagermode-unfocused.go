@@ -0,0 +1,42 @@
+// The pane without focus when the screen is split. Looks like viewing mode,
+// but never gets any input.
+
+package internal
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+)
+
+type PagerModeUnfocused struct {
+	pager *Pager
+}
+
+func (m PagerModeUnfocused) drawFooter(filenameText string, statusText string, _ string) {
+	if !m.pager.ShowStatusBar {
+		return
+	}
+
+	prefix := ""
+	m.pager.readerLock.Lock()
+	if len(m.pager.readers) > 1 {
+		prefix = fmt.Sprintf("[%d/%d] ", m.pager.currentReader+1, len(m.pager.readers))
+	}
+	m.pager.readerLock.Unlock()
+
+	if m.pager.isShowingHelp {
+		prefix = ""
+	}
+
+	m.pager.setFooter(prefix, filenameText, statusText, "Press 'TAB' to switch to this pane")
+}
+
+func (m PagerModeUnfocused) onKey(key twin.KeyCode) {
+	log.Warnf("Unfocused pane got key event %v, ignoring it", key)
+}
+
+func (m PagerModeUnfocused) onRune(char rune) {
+	log.Warnf("Unfocused pane got rune %q, ignoring it", char)
+}
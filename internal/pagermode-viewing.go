@@ -5,7 +5,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/walles/moor/v2/internal/linemetadata"
-	"github.com/walles/moor/v2/internal/search"
 	"github.com/walles/moor/v2/internal/textstyles"
 	"github.com/walles/moor/v2/twin"
 )
@@ -183,20 +182,31 @@ func (m PagerModeViewing) onRune(char rune) {
 			// that if you feel that's time well spent.
 			p.mode = NewPagerModeFilter(p)
 			p.search.Clear()
-			p.filter = search.Search{}
 		}
 
+	case 'H':
+		p.mode = NewPagerModeHighlight(p)
+		p.setTargetLine(nil)
+
+	case 'C':
+		p.mode = NewPagerModeColumns(p)
+		p.setTargetLine(nil)
+
 	case 'g':
 		p.mode = NewPagerModeGotoLine(p)
 		p.setTargetLine(nil)
 
 	case ':':
-		if len(p.readers) > 1 {
-			p.mode = &PagerModeColonCommand{pager: p}
-			p.setTargetLine(nil)
-		} else {
-			p.mode = &PagerModeInfo{Pager: p, Text: "Pass more files on the command line to be able to switch between them."}
-		}
+		p.mode = NewPagerModeColonCommand(p)
+		p.setTargetLine(nil)
+
+	case '|':
+		// Like in less, but through our ':' command line
+		colonCommand := NewPagerModeColonCommand(p)
+		colonCommand.inputBox.setText("pipe ")
+		colonCommand.edited()
+		p.mode = colonCommand
+		p.setTargetLine(nil)
 
 	// Should match the pagermode-not-found.go previous-search-hit bindings
 	case 'n':
@@ -214,6 +224,15 @@ func (m PagerModeViewing) onRune(char rune) {
 		p.mode = PagerModeJumpToMark{pager: p}
 		p.setTargetLine(nil)
 
+	case 'M':
+		picker := NewPagerModeMarkPicker(p)
+		if picker == nil {
+			p.mode = &PagerModeInfo{Pager: p, Text: "No marks set, press 'm' to set one!"}
+		} else {
+			p.mode = picker
+			p.setTargetLine(nil)
+		}
+
 	case 'w':
 		p.WrapLongLines = !p.WrapLongLines
 		if p.WrapLongLines {
@@ -222,6 +241,28 @@ func (m PagerModeViewing) onRune(char rune) {
 			p.mode = &PagerModeInfo{Pager: p, Text: "Word wrapping disabled"}
 		}
 
+	case 'J':
+		p.setUnfoldJSONRecords(!p.UnfoldJSONRecords)
+		if p.UnfoldJSONRecords {
+			p.mode = &PagerModeInfo{Pager: p, Text: "JSON records unfolded"}
+		} else {
+			p.mode = &PagerModeInfo{Pager: p, Text: "JSON records folded"}
+		}
+
+	case 'S':
+		if p.split == nil {
+			p.splitScreen()
+		} else {
+			p.unsplitScreen()
+		}
+
+	case '\t':
+		if p.split == nil {
+			p.mode = &PagerModeInfo{Pager: p, Text: "Press 'S' to split the screen first."}
+		} else {
+			p.switchPane()
+		}
+
 	case '\x14': // CTRL-t
 		p.cycleTabSize()
 
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+)
+
+// Syntax: pipe [-all] [-marks ab] <shell command>
+//
+// By default we pipe what's visible, meaning only the filtered lines if a
+// filter is active. "-all" ignores the filter, and "-marks ab" pipes the lines
+// between the 'a' and 'b' bookmarks.
+//
+// Without "-marks", we read all lines before piping them.
+//
+// The command's output (stdout and stderr) opens in a new reader.
+func (p *Pager) pipeCommand(args string) error {
+	if os.Getenv("LESSSECURE") == "1" {
+		return errors.New("Not running commands since LESSSECURE=1 is set in the environment")
+	}
+
+	all := false
+	marks := ""
+	for {
+		flag, rest, _ := strings.Cut(args, " ")
+		if flag == "-all" {
+			all = true
+		} else if flag == "-marks" {
+			marks, rest, _ = strings.Cut(strings.TrimSpace(rest), " ")
+		} else {
+			break
+		}
+		args = strings.TrimSpace(rest)
+	}
+
+	command := args
+	if command == "" {
+		return errors.New("Usage: pipe [-all] [-marks ab] <command>")
+	}
+
+	if marks != "" {
+		firstLine, lineCount, err := p.linesBetweenMarks(marks, all)
+		if err != nil {
+			return err
+		}
+
+		// The marks are on lines we have already read
+		return p.pipeLines(command, p.pipeSource(all), firstLine, lineCount)
+	}
+
+	return p.readAllThen("piping to "+command, func() error {
+		source := p.pipeSource(all)
+		return p.pipeLines(command, source, linemetadata.Index{}, source.GetLineCount())
+	})
+}
+
+// What to pipe from. The lines are written from another goroutine, so this
+// can't be the FilteringReader, which changes when we switch files.
+func (p *Pager) pipeSource(all bool) reader.Reader {
+	if !all {
+		if filtered := p.filteringReader.snapshot(); filtered != nil {
+			return filtered
+		}
+	}
+
+	p.readerLock.Lock()
+	defer p.readerLock.Unlock()
+	return p.readers[p.currentReader]
+}
+
+// Pipe lines through a shell command and open its output as a new file
+func (p *Pager) pipeLines(command string, source reader.Reader, first linemetadata.Index, lineCount int) error {
+	output, err := startPipe(command, source, first, lineCount)
+	if err != nil {
+		return err
+	}
+
+	formatter, options := p.newReaderOptions()
+	options.Lexer = nil // We don't know what the command outputs
+	p.addReader(reader.NewFromUncompressedStream("|"+command, output, formatter, options))
+	return nil
+}
+
+// Returns the first line and the line count between two bookmarks, inclusive.
+//
+// Bookmarks are in the filtered view. If unfiltered is true, the lines are
+// looked up in the unfiltered input instead.
+func (p *Pager) linesBetweenMarks(marks string, unfiltered bool) (linemetadata.Index, int, error) {
+	if utf8.RuneCountInString(marks) != 2 {
+		return linemetadata.Index{}, 0, fmt.Errorf("Expected two bookmarks, like 'ab', got: %s", marks)
+	}
+
+	indices := []linemetadata.Index{}
+	for _, mark := range marks {
+		position, ok := p.currentBookmarks()[mark]
+		if !ok {
+			return linemetadata.Index{}, 0, fmt.Errorf("No such bookmark: %c", mark)
+		}
+
+		index := position.lineIndex(p)
+		if index == nil {
+			return linemetadata.Index{}, 0, fmt.Errorf("Bookmark %c is not on any line", mark)
+		}
+		if unfiltered {
+			line := p.filteringReader.GetLine(*index)
+			if line == nil {
+				return linemetadata.Index{}, 0, fmt.Errorf("Bookmark %c is not on any line", mark)
+			}
+			unfilteredIndex := linemetadata.IndexFromZeroBased(line.Number.AsZeroBased())
+			index = &unfilteredIndex
+		}
+		indices = append(indices, *index)
+	}
+
+	first, last := indices[0], indices[1]
+	if first.IsAfter(last) {
+		first, last = last, first
+	}
+
+	return first, first.CountLinesTo(last), nil
+}
+
+// Start a shell command, feed it lineCount lines from first and return its
+// combined stdout and stderr
+func startPipe(command string, source reader.Reader, first linemetadata.Index, lineCount int) (io.Reader, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		cmd = exec.Command(shell, "-c", command)
+	}
+
+	outputReader, outputWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		_ = outputReader.Close()
+		_ = outputWriter.Close()
+		return nil, err
+	}
+
+	log.Infof("Piping %d lines through: %s", lineCount, command)
+	err = cmd.Start()
+
+	// The command has its own copy of this now. Closing ours makes sure the
+	// reader gets an EOF when the command exits.
+	_ = outputWriter.Close()
+
+	if err != nil {
+		_ = outputReader.Close()
+		return nil, fmt.Errorf("Failed to run %s: %w", command, err)
+	}
+
+	go func() {
+		defer func() {
+			PanicHandler("startPipe()/goroutine", recover(), debug.Stack())
+		}()
+
+		writer := bufio.NewWriter(stdin)
+		_, err := writeLines(writer, source, first, lineCount, false)
+		if err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			// Commands like "head" stop reading early, that's fine
+			log.Debugf("Stopped piping to %s: %v", command, err)
+		}
+		_ = stdin.Close()
+
+		err = cmd.Wait()
+		if err != nil {
+			log.Infof("Piped command %s failed: %v", command, err)
+		}
+	}()
+
+	return &closeOnError{outputReader}, nil
+}
+
+// Closes the file on EOF or any other read error, so that we don't leak file
+// descriptors
+type closeOnError struct {
+	file *os.File
+}
+
+func (c *closeOnError) Read(b []byte) (int, error) {
+	n, err := c.file.Read(b)
+	if err != nil {
+		closeErr := c.file.Close()
+		if closeErr != nil && !errors.Is(closeErr, os.ErrClosed) {
+			log.Debugf("Closing pipe failed: %v", closeErr)
+		}
+	}
+
+	return n, err
+}
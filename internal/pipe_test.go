@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func pipeOutput(t *testing.T, pager *Pager, command string) []string {
+	t.Helper()
+
+	readerCount := len(pager.readers)
+	assert.NilError(t, pager.executeCommand(command))
+	assert.Equal(t, len(pager.readers), readerCount+1)
+	assert.Equal(t, pager.currentReader, readerCount)
+
+	output := pager.readers[pager.currentReader]
+	assert.NilError(t, output.Wait())
+
+	lines := []string{}
+	for _, line := range output.GetLines(linemetadata.Index{}, 100).Lines {
+		lines = append(lines, line.Plain())
+	}
+	return lines
+}
+
+func TestPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses Unix commands")
+	}
+	t.Setenv("SHELL", "/bin/sh")
+
+	pager := newCommandTestPager(t, "apa\nbepa\ncepa\ndepa")
+
+	assert.DeepEqual(t, pipeOutput(t, pager, "pipe tr a-z A-Z"), []string{"APA", "BEPA", "CEPA", "DEPA"})
+
+	// Filtered lines only
+	pager.currentReader = 0
+	pager.readerSwitchedUnlocked()
+	assert.NilError(t, pager.executeCommand("filter ^[bc]"))
+	assert.DeepEqual(t, pipeOutput(t, pager, "pipe cat"), []string{"bepa", "cepa"})
+
+	// Stderr should be visible
+	assert.DeepEqual(t, pipeOutput(t, pager, "pipe echo oops >&2"), []string{"oops"})
+}
+
+func TestPipe_BetweenMarks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses Unix commands")
+	}
+	t.Setenv("SHELL", "/bin/sh")
+
+	pager := newCommandTestPager(t, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12")
+
+	pager.bookmarks = map[*reader.ReaderImpl]map[rune]scrollPosition{
+		pager.readers[0]: {
+			'a': newScrollPosition("a").NextLine(2),
+			'b': newScrollPosition("b"),
+		},
+	}
+
+	assert.DeepEqual(t, pipeOutput(t, pager, "pipe -all -marks ab cat"), []string{"1", "2", "3"})
+
+	// Bookmarks are per file, and we're now looking at the output
+	assert.Error(t, pager.executeCommand("pipe -marks ab cat"), "No such bookmark: a")
+
+	pager.currentReader = 0
+	assert.Error(t, pager.executeCommand("pipe -marks ax cat"), "No such bookmark: x")
+}
+
+func TestPipe_BetweenMarksFiltered(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses Unix commands")
+	}
+	t.Setenv("SHELL", "/bin/sh")
+
+	pager := newCommandTestPager(t, "1\n2\n3\n4\n5\n6\n7\n8")
+	pager.screen = twin.NewFakeScreen(20, 2) // Let the marks scroll
+	assert.NilError(t, pager.executeCommand("filter [02468]$"))
+
+	// The marks are on "4" and "6" in the filtered view
+	pager.bookmarks = map[*reader.ReaderImpl]map[rune]scrollPosition{
+		pager.readers[0]: {
+			'a': newScrollPosition("a").NextLine(1),
+			'b': newScrollPosition("b").NextLine(2),
+		},
+	}
+
+	assert.DeepEqual(t, pipeOutput(t, pager, "pipe -marks ab cat"), []string{"4", "6"})
+
+	pager.currentReader = 0
+	pager.readerSwitchedUnlocked()
+	assert.NilError(t, pager.executeCommand("filter [02468]$"))
+	assert.DeepEqual(t, pipeOutput(t, pager, "pipe -all -marks ab cat"), []string{"4", "5", "6"})
+}
+
+// Type the command rather than using the '|' shortcut
+func TestPipe_ColonCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses Unix commands")
+	}
+	t.Setenv("SHELL", "/bin/sh")
+
+	pager := newCommandTestPager(t, "apa\nbepa")
+
+	pager.mode = PagerModeViewing{pager: pager}
+	pager.mode.onRune(':')
+	for _, char := range "pipe tr a-z A-Z" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onKey(twin.KeyEnter)
+
+	assert.Equal(t, len(pager.readers), 2)
+	assert.Equal(t, pager.currentReader, 1)
+	output := pager.readers[1]
+	assert.NilError(t, output.Wait())
+	assert.Equal(t, output.GetLine(linemetadata.Index{}).Plain(), "APA")
+	assert.Equal(t, output.GetLine(linemetadata.IndexFromZeroBased(1)).Plain(), "BEPA")
+}
+
+func TestPipe_LessSecure(t *testing.T) {
+	t.Setenv("LESSSECURE", "1")
+
+	pager := newCommandTestPager(t, "apa")
+	assert.Error(t, pager.executeCommand("pipe cat"), "Not running commands since LESSSECURE=1 is set in the environment")
+	assert.Equal(t, len(pager.readers), 1)
+}
@@ -0,0 +1,250 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// Where the user was in a file when they last viewed it
+type savedPosition struct {
+	// Absolute path
+	FileName string `json:"file"`
+
+	// One based number of the top line on screen
+	LastLine int `json:"lastLine"`
+
+	// Mark -> one based line number
+	Bookmarks map[string]int `json:"bookmarks,omitempty"`
+}
+
+type SavedPositions struct {
+	// Empty means no positions file. Set by BootSavedPositions().
+	absFileName string
+
+	// Most recently updated last
+	positions []savedPosition
+}
+
+const maxSavedPositions = 640 // This should be enough for anyone
+
+// A relative path or just a file name means relative to the user's home
+// directory. Empty means follow the XDG spec for data files.
+func BootSavedPositions(fileName string) SavedPositions {
+	fileName = resolveHistoryFilePath(fileName, "moor/positions.json")
+	if fileName == "" {
+		return SavedPositions{}
+	}
+
+	bytes, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		// First run, start from scratch
+		return SavedPositions{absFileName: fileName}
+	}
+	if err != nil {
+		log.Infof("Could not load saved positions from %s: %v", fileName, err)
+		// IO Error, give up
+		return SavedPositions{}
+	}
+
+	positions := []savedPosition{}
+	err = json.Unmarshal(bytes, &positions)
+	if err != nil {
+		// Don't overwrite whatever is in there, somebody might want it
+		log.Infof("Could not parse saved positions from %s: %v", fileName, err)
+		return SavedPositions{}
+	}
+
+	log.Infof("Loaded %d saved positions from %s", len(positions), fileName)
+	return SavedPositions{
+		absFileName: fileName,
+		positions:   positions,
+	}
+}
+
+// Returns nil if we have nothing saved for this file
+func (s *SavedPositions) get(absFileName string) *savedPosition {
+	for i := len(s.positions) - 1; i >= 0; i-- {
+		if s.positions[i].FileName == absFileName {
+			return &s.positions[i]
+		}
+	}
+
+	return nil
+}
+
+// Remember a position and write all positions to disk
+func (s *SavedPositions) put(position savedPosition) {
+	s.positions = slices.DeleteFunc(s.positions, func(p savedPosition) bool {
+		return p.FileName == position.FileName
+	})
+	s.positions = append(s.positions, position)
+	for len(s.positions) > maxSavedPositions {
+		// Forget the least recently updated position
+		s.positions = s.positions[1:]
+	}
+
+	if os.Getenv("LESSSECURE") == "1" {
+		// LESSSECURE=1 means not writing anything to disk
+		return
+	}
+
+	if s.absFileName == "" {
+		// No positions file configured
+		return
+	}
+
+	bytes, err := json.Marshal(s.positions)
+	if err != nil {
+		log.Warnf("Could not serialize saved positions: %v", err)
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.absFileName), 0o700)
+	if err != nil {
+		log.Infof("Could not create directory for %s: %v", s.absFileName, err)
+		return
+	}
+
+	// Write to a temp file and rename it into place, so that nobody ever sees a
+	// half written file
+	tmpFilePath := s.absFileName + ".tmp"
+	err = os.WriteFile(tmpFilePath, bytes, 0o600)
+	if err != nil {
+		log.Infof("Could not write temp positions file %s: %v", tmpFilePath, err)
+		_ = os.Remove(tmpFilePath)
+		return
+	}
+
+	err = os.Rename(tmpFilePath, s.absFileName)
+	if err != nil {
+		log.Infof("Could not rename temp positions file %s to %s: %v", tmpFilePath, s.absFileName, err)
+	}
+}
+
+// The bookmarks for the current reader
+func (p *Pager) currentBookmarks() map[rune]scrollPosition {
+	r := p.readers[p.currentReader]
+	bookmarks, ok := p.bookmarks[r]
+	if !ok {
+		bookmarks = make(map[rune]scrollPosition)
+		p.bookmarks[r] = bookmarks
+	}
+
+	return bookmarks
+}
+
+// Save positions for the files in all panes
+func (p *Pager) rememberPositions() {
+	p.readerLock.Lock()
+	p.rememberPositionUnlocked()
+	p.readerLock.Unlock()
+
+	if p.split != nil {
+		p.swapPanes()
+		p.readerLock.Lock()
+		p.rememberPositionUnlocked()
+		p.readerLock.Unlock()
+		p.swapPanes()
+	}
+}
+
+// Save the current position and bookmarks for the current file. Call this with
+// readerLock held.
+func (p *Pager) rememberPositionUnlocked() {
+	absFileName := p.currentAbsFileName()
+	if absFileName == "" || p.isShowingHelp {
+		return
+	}
+
+	lineNumberAt := func(position scrollPosition) int {
+		index := position.lineIndex(p)
+		if index == nil {
+			return 0
+		}
+		line := p.Reader().GetLine(*index)
+		if line == nil {
+			return 0
+		}
+		return line.Number.AsOneBased()
+	}
+
+	position := savedPosition{
+		FileName:  absFileName,
+		LastLine:  lineNumberAt(p.scrollPosition),
+		Bookmarks: map[string]int{},
+	}
+	for mark, bookmark := range p.currentBookmarks() {
+		if mark == '\'' {
+			// This is where we were last time, not a real bookmark
+			continue
+		}
+		lineNumber := lineNumberAt(bookmark)
+		if lineNumber > 0 {
+			position.Bookmarks[string(mark)] = lineNumber
+		}
+	}
+
+	if position.LastLine <= 1 && len(position.Bookmarks) == 0 && p.savedPositions.get(absFileName) == nil {
+		// Nothing worth remembering
+		return
+	}
+
+	p.savedPositions.put(position)
+}
+
+// Bring back the bookmarks for the current file from earlier sessions, and
+// offer to return to where the user left off. Only done the first time we show
+// each reader. Call this with readerLock held.
+func (p *Pager) restorePositionUnlocked() {
+	if _, seen := p.bookmarks[p.readers[p.currentReader]]; seen {
+		return
+	}
+	bookmarks := p.currentBookmarks()
+
+	absFileName := p.currentAbsFileName()
+	if absFileName == "" {
+		return
+	}
+	position := p.savedPositions.get(absFileName)
+	if position == nil {
+		return
+	}
+
+	for mark, lineNumber := range position.Bookmarks {
+		runes := []rune(mark)
+		if len(runes) != 1 || lineNumber < 1 {
+			log.Debugf("Ignoring invalid saved bookmark %q -> %d for %s", mark, lineNumber, absFileName)
+			continue
+		}
+		bookmarks[runes[0]] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(lineNumber), "Saved bookmark "+mark)
+	}
+	log.Debugf("Restored %d bookmarks for %s", len(bookmarks), absFileName)
+
+	if position.LastLine > 1 {
+		bookmarks['\''] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(position.LastLine), "Last position")
+		p.mode = &PagerModeInfo{Pager: p, Text: fmt.Sprintf("Press '' to return to line %d, where you left off last time", position.LastLine)}
+	}
+}
+
+// Empty if the current reader isn't reading from a file
+func (p *Pager) currentAbsFileName() string {
+	fileName := p.readers[p.currentReader].FileName
+	if fileName == nil {
+		return ""
+	}
+
+	absFileName, err := filepath.Abs(*fileName)
+	if err != nil {
+		log.Debugf("Not saving or restoring position for %s: %v", *fileName, err)
+		return ""
+	}
+
+	return absFileName
+}
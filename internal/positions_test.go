@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestSavedPositionsRoundTrip(t *testing.T) {
+	positionsFile := filepath.Join(t.TempDir(), "positions.json")
+
+	positions := BootSavedPositions(positionsFile)
+	positions.put(savedPosition{FileName: "/a.txt", LastLine: 42, Bookmarks: map[string]int{"x": 7}})
+	positions.put(savedPosition{FileName: "/b.txt", LastLine: 5})
+	positions.put(savedPosition{FileName: "/a.txt", LastLine: 43})
+
+	reloaded := BootSavedPositions(positionsFile)
+	assert.Equal(t, len(reloaded.positions), 2)
+	assert.Equal(t, reloaded.get("/a.txt").LastLine, 43)
+	assert.Equal(t, reloaded.get("/b.txt").LastLine, 5)
+	assert.Assert(t, reloaded.get("/c.txt") == nil)
+
+	// Most recently updated last
+	assert.Equal(t, reloaded.positions[1].FileName, "/a.txt")
+}
+
+func TestSavedPositionsLessSecure(t *testing.T) {
+	t.Setenv("LESSSECURE", "1")
+	positionsFile := filepath.Join(t.TempDir(), "positions.json")
+
+	positions := BootSavedPositions(positionsFile)
+	positions.put(savedPosition{FileName: "/a.txt", LastLine: 42})
+
+	_, err := os.Stat(positionsFile)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func newPositionsTestPager(t *testing.T, fileName string, positions *SavedPositions) *Pager {
+	t.Helper()
+
+	r, err := reader.NewFromFilename(fileName, formatters.TTY16m, reader.ReaderOptions{})
+	assert.NilError(t, err)
+	assert.NilError(t, r.Wait())
+
+	pager := NewPager(r)
+	pager.screen = twin.NewFakeScreen(20, 10)
+	pager.savedPositions = positions
+	return pager
+}
+
+func TestRememberAndRestorePosition(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "big.log")
+	lines := []string{}
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	assert.NilError(t, os.WriteFile(fileName, []byte(strings.Join(lines, "\n")), 0o600))
+
+	positions := BootSavedPositions(filepath.Join(dir, "positions.json"))
+
+	// First session, set a bookmark and scroll down
+	pager := newPositionsTestPager(t, fileName, &positions)
+	pager.scrollPosition = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(10), "test")
+	pager.mode = PagerModeMark{pager: pager}
+	pager.mode.onRune('a')
+	pager.scrollPosition = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(50), "test")
+	pager.rememberPositions()
+
+	// Second session
+	reloaded := BootSavedPositions(filepath.Join(dir, "positions.json"))
+	pager = newPositionsTestPager(t, fileName, &reloaded)
+	pager.restorePositionUnlocked()
+
+	info, isInfo := pager.mode.(*PagerModeInfo)
+	assert.Assert(t, isInfo)
+	assert.Equal(t, info.Text, "Press '' to return to line 50, where you left off last time")
+	assert.Equal(t, pager.lineIndex().Index(), 0)
+
+	pager.mode.onRune('\'')
+	pager.mode.onRune('\'')
+	assert.Equal(t, pager.lineIndex().Index(), 49)
+
+	pager.mode.onRune('\'')
+	pager.mode.onRune('a')
+	assert.Equal(t, pager.lineIndex().Index(), 9)
+}
@@ -0,0 +1,475 @@
+// Huge files are indexed rather than read into memory. We keep only where each
+// chunk of lines starts, and read lines from the file when someone asks for
+// them.
+
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Regular files at least this large are indexed rather than read into memory.
+// Variable so that tests can change it.
+var minIndexedFileSize int64 = 100_000_000
+
+// Lines are indexed and read from the file this many at a time
+const indexedChunkSize = 1000
+
+// Chunks beyond this are dropped from memory, least recently used first
+const maxCachedChunks = 100
+
+type fileIndex struct {
+	// Oldest first. After the file is rotated or truncated, new lines go into
+	// a new segment, and the lines we already had stay. Protected by the
+	// reader lock.
+	segments []*indexSegment
+
+	cacheLock sync.Mutex
+	cache     map[chunkKey][]*Line
+	lru       []chunkKey // Least recently used first
+
+	// Lines from further into the file than we have indexed, see
+	// ReaderImpl.LineAtByteOffset(). Protected by the reader lock.
+	preview *indexPreview
+}
+
+// Lines we looked up at some byte offset before indexing got there. Their line
+// numbers are estimates, and the lines between the indexed ones and these come
+// out empty. Dropped when indexing gets to the offset.
+type indexPreview struct {
+	// What we were asked for, the lines start at the first line start at or
+	// after this
+	offset int64
+
+	// Estimated
+	firstLine int
+
+	lines []*Line
+}
+
+// Lines from one file, or from one go at a file that was then truncated
+type indexSegment struct {
+	// Only used with ReadAt(), which doesn't care about the file position. Nil
+	// if the file was truncated, see pinned.
+	file *os.File
+
+	// Index of the first line of this segment, counting all segments
+	firstLine int
+
+	// Where each chunk of indexedChunkSize lines starts in the file. Chunks end
+	// where the next one starts, and the last one at endOffset.
+	chunkStarts []int64
+	endOffset   int64
+
+	lineCount int
+
+	// Like "app.log rotated 12:34:56", shown after the last line of this
+	// segment. Nil for the last segment, and after truncations while not
+	// following by name.
+	tailEvent *string
+
+	// For truncated files, the chunks we had in memory when the file was
+	// truncated. The other lines are gone and come out empty. Protected by the
+	// cache lock.
+	pinned map[int][]*Line
+}
+
+// A chunk of lines in some segment
+type chunkKey struct {
+	segment int
+	chunk   int
+}
+
+// Returns nil unless stream is a regular file large enough to be worth indexing
+func newFileIndex(stream io.Reader) *fileIndex {
+	file, ok := stream.(*os.File)
+	if !ok {
+		return nil
+	}
+
+	stat, err := file.Stat()
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() < minIndexedFileSize {
+		return nil
+	}
+
+	log.Info("File is ", stat.Size(), " bytes, indexing it rather than reading it into memory")
+	return &fileIndex{
+		segments: []*indexSegment{{file: file}},
+		cache:    map[chunkKey][]*Line{},
+	}
+}
+
+// Including any preview lines. Assumes the reader lock is held.
+func (index *fileIndex) lineCount() int {
+	indexed := index.indexedLineCount()
+	if index.preview == nil {
+		return indexed
+	}
+	return max(indexed, index.preview.firstLine+len(index.preview.lines))
+}
+
+// Assumes the reader lock is held
+func (index *fileIndex) indexedLineCount() int {
+	current := index.current()
+	return current.firstLine + current.lineCount
+}
+
+// The segment we're currently adding lines to. Assumes the reader lock is held.
+func (index *fileIndex) current() *indexSegment {
+	return index.segments[len(index.segments)-1]
+}
+
+// Show separator after the last line we have. Assumes the reader write lock
+// is held.
+func (index *fileIndex) setSeparator(separator *string) {
+	for segmentNumber := len(index.segments) - 1; segmentNumber >= 0; segmentNumber-- {
+		segment := index.segments[segmentNumber]
+		if segment.lineCount == 0 {
+			continue
+		}
+
+		segment.tailEvent = separator
+		lastLine := segment.lineCount - 1
+		lines := index.cachedChunk(chunkKey{segmentNumber, lastLine / indexedChunkSize})
+		if lines != nil {
+			lines[lastLine%indexedChunkSize].tailEvent.Store(separator)
+		}
+		return
+	}
+}
+
+// Add new lines after the ones we have, from the start of file.
+//
+// If file is the same file as before, it has been truncated and the old lines
+// are gone from it. Then we keep the ones we have in memory.
+//
+// Assumes the reader write lock is held.
+func (index *fileIndex) startSegment(file *os.File) {
+	segmentNumber := len(index.segments) - 1
+	old := index.segments[segmentNumber]
+
+	if sameFile(old.file, file) {
+		index.cacheLock.Lock()
+		old.pinned = map[int][]*Line{}
+		for key, lines := range index.cache {
+			if key.segment == segmentNumber {
+				old.pinned[key.chunk] = lines
+				delete(index.cache, key)
+			}
+		}
+		index.lru = slices.DeleteFunc(index.lru, func(key chunkKey) bool { return key.segment == segmentNumber })
+		index.cacheLock.Unlock()
+
+		if old.file != file {
+			err := old.file.Close()
+			if err != nil {
+				log.Debug("Failed to close truncated indexed file: ", err)
+			}
+		}
+		old.file = nil
+	}
+
+	// Offsets into the old file mean nothing in the new one
+	index.preview = nil
+
+	index.segments = append(index.segments, &indexSegment{
+		file:      file,
+		firstLine: index.indexedLineCount(),
+	})
+}
+
+func sameFile(a *os.File, b *os.File) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a == b {
+		return true
+	}
+
+	aStat, err := a.Stat()
+	if err != nil {
+		return false
+	}
+	bStat, err := b.Stat()
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aStat, bStat)
+}
+
+// Find the segment holding a line. Assumes the reader lock is held.
+func (index *fileIndex) segmentFor(lineIndex int) (int, *indexSegment) {
+	// The first segment starting after the line, then one back
+	after := sort.Search(len(index.segments), func(i int) bool {
+		return index.segments[i].firstLine > lineIndex
+	})
+	return after - 1, index.segments[after-1]
+}
+
+// Drop the last chunk of the current segment from the cache, because its last
+// line has changed or it has more lines now. Assumes the reader lock is held.
+func (index *fileIndex) forgetLastChunk() {
+	current := index.current()
+	if current.lineCount == 0 {
+		return
+	}
+	key := chunkKey{len(index.segments) - 1, (current.lineCount - 1) / indexedChunkSize}
+
+	index.cacheLock.Lock()
+	defer index.cacheLock.Unlock()
+
+	delete(index.cache, key)
+	index.lru = slices.DeleteFunc(index.lru, func(c chunkKey) bool { return c == key })
+}
+
+// Get a line, reading it from the file unless we have it cached. Assumes the
+// reader lock is held.
+func (index *fileIndex) line(lineIndex int) *Line {
+	if lineIndex >= index.indexedLineCount() {
+		return index.previewLine(lineIndex)
+	}
+
+	segmentNumber, segment := index.segmentFor(lineIndex)
+	segmentLine := lineIndex - segment.firstLine
+	key := chunkKey{segmentNumber, segmentLine / indexedChunkSize}
+
+	lines := index.cachedChunk(key)
+	if lines == nil {
+		lines = index.readChunk(key)
+	}
+
+	return lines[segmentLine%indexedChunkSize]
+}
+
+// Like line(), but returns nil if the line isn't in memory
+func (index *fileIndex) cachedLine(lineIndex int) *Line {
+	if lineIndex >= index.indexedLineCount() {
+		return index.previewLine(lineIndex)
+	}
+
+	segmentNumber, segment := index.segmentFor(lineIndex)
+	segmentLine := lineIndex - segment.firstLine
+	lines := index.cachedChunk(chunkKey{segmentNumber, segmentLine / indexedChunkSize})
+	if lines == nil {
+		return nil
+	}
+	return lines[segmentLine%indexedChunkSize]
+}
+
+// For lines past the indexed ones. Lines before the preview come out empty.
+// Assumes the reader lock is held.
+func (index *fileIndex) previewLine(lineIndex int) *Line {
+	if index.preview == nil || lineIndex < index.preview.firstLine {
+		return &Line{}
+	}
+	return index.preview.lines[lineIndex-index.preview.firstLine]
+}
+
+// Returns nil if the chunk isn't in memory
+func (index *fileIndex) cachedChunk(key chunkKey) []*Line {
+	index.cacheLock.Lock()
+	defer index.cacheLock.Unlock()
+
+	pinned := index.segments[key.segment].pinned
+	if pinned != nil {
+		return pinned[key.chunk]
+	}
+
+	lines, found := index.cache[key]
+	if found {
+		index.lru = slices.DeleteFunc(index.lru, func(c chunkKey) bool { return c == key })
+		index.lru = append(index.lru, key)
+	}
+	return lines
+}
+
+// Assumes the reader lock is held
+func (index *fileIndex) readChunk(key chunkKey) []*Line {
+	t0 := time.Now()
+
+	segment := index.segments[key.segment]
+	first := key.chunk * indexedChunkSize
+	end := min(first+indexedChunkSize, segment.lineCount)
+	startOffset := segment.chunkStarts[key.chunk]
+	chunkEndOffset := segment.endOffset
+	if key.chunk+1 < len(segment.chunkStarts) {
+		chunkEndOffset = segment.chunkStarts[key.chunk+1]
+	}
+
+	lines := make([]Line, end-first)
+	result := make([]*Line, end-first)
+	for i := range lines {
+		result[i] = &lines[i]
+	}
+	if end == segment.lineCount && segment.tailEvent != nil {
+		result[len(result)-1].tailEvent.Store(segment.tailEvent)
+	}
+
+	if segment.file == nil {
+		// Truncated, so these lines are gone. Keep the empty lines so that
+		// they stay the same.
+		index.cacheLock.Lock()
+		defer index.cacheLock.Unlock()
+		if pinned, found := segment.pinned[key.chunk]; found {
+			// Some other goroutine got here first
+			return pinned
+		}
+		segment.pinned[key.chunk] = result
+		return result
+	}
+
+	buffer := make([]byte, chunkEndOffset-startOffset)
+	_, err := segment.file.ReadAt(buffer, startOffset)
+	if err != nil && err != io.EOF {
+		// Show empty lines, and try again next time
+		log.Warn("Failed to read lines from indexed file: ", err)
+		return result
+	}
+
+	for i := range lines {
+		raw, rest, _ := bytes.Cut(buffer, []byte{'\n'})
+		buffer = rest
+		lines[i].raw = bytes.TrimSuffix(raw, []byte{'\r'}) // Handle MSDOS line endings
+	}
+
+	index.cacheLock.Lock()
+	if cached, found := index.cache[key]; found {
+		// Some other goroutine got here first
+		index.cacheLock.Unlock()
+		return cached
+	}
+	index.cache[key] = result
+	index.lru = append(index.lru, key)
+	for len(index.lru) > maxCachedChunks {
+		delete(index.cache, index.lru[0])
+		index.lru = index.lru[1:]
+	}
+	index.cacheLock.Unlock()
+
+	log.Trace("Read indexed lines ", segment.firstLine+first, "-", segment.firstLine+end-1, " in ", time.Since(t0))
+
+	return result
+}
+
+// The line at some offset into the current segment's file, which must be
+// below endOffset. Assumes the reader lock is held.
+func (index *fileIndex) lineAtOffset(offset int64) int {
+	segment := index.current()
+
+	// The first chunk starting after the offset, then one back
+	chunk := sort.Search(len(segment.chunkStarts), func(i int) bool {
+		return segment.chunkStarts[i] > offset
+	}) - 1
+	chunk = max(chunk, 0)
+
+	// Count the lines in the chunk before the offset
+	chunkStart := segment.chunkStarts[chunk]
+	buffer := make([]byte, offset-chunkStart)
+	_, err := segment.file.ReadAt(buffer, chunkStart)
+	if err != nil && err != io.EOF {
+		log.Warn("Failed to read indexed file for finding a byte offset: ", err)
+	}
+
+	lineIndex := chunk*indexedChunkSize + bytes.Count(buffer, []byte{'\n'})
+	return segment.firstLine + min(lineIndex, segment.lineCount-1)
+}
+
+// Count a line starting at some offset, and remember where the chunks start
+func (segment *indexSegment) addLine(offset int64) {
+	if segment.lineCount%indexedChunkSize == 0 {
+		segment.chunkStarts = append(segment.chunkStarts, offset)
+	}
+	segment.lineCount++
+}
+
+// Like consumeLinesFromStream(), but only records where the chunks start
+func (reader *ReaderImpl) consumeIndexedLines(stream io.Reader) {
+	const byteBufferSize = 1024 * 1024
+
+	t0 := time.Now()
+
+	byteBuffer := make([]byte, byteBufferSize)
+	awaitingFirstByte := true
+	for {
+		readBytes, err := stream.Read(byteBuffer)
+
+		if awaitingFirstByte && readBytes > 0 {
+			select {
+			case reader.doneWaitingForFirstByte <- true:
+			default:
+			}
+
+			awaitingFirstByte = false
+		}
+
+		if readBytes > 0 {
+			reader.Lock()
+			segment := reader.index.current()
+
+			// The last line's chunk is getting more lines, or a longer last line
+			reader.index.forgetLastChunk()
+
+			if segment.lineCount == 0 || reader.endsWithNewline {
+				segment.addLine(reader.bytesCount)
+			}
+
+			byteIndex := 0
+			for {
+				relativeNewlineLocation := bytes.IndexByte(byteBuffer[byteIndex:readBytes], '\n')
+				if relativeNewlineLocation == -1 {
+					break
+				}
+
+				byteIndex += relativeNewlineLocation + 1
+				if byteIndex < readBytes {
+					segment.addLine(reader.bytesCount + int64(byteIndex))
+				}
+			}
+
+			reader.bytesCount += int64(readBytes)
+			segment.endOffset = reader.bytesCount
+			if reader.index.preview != nil && reader.bytesCount > reader.index.preview.offset {
+				// We know the real line numbers now
+				reader.index.preview = nil
+			}
+			reader.endsWithNewline = byteBuffer[readBytes-1] == '\n'
+			reader.Unlock()
+
+			select {
+			case reader.MoreLinesAdded <- true:
+			default:
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			reader.Lock()
+			if reader.Err == nil {
+				reader.Err = fmt.Errorf("error reading from input stream: %w", err)
+			}
+			reader.Unlock()
+			break
+		}
+	}
+
+	if awaitingFirstByte {
+		select {
+		case reader.doneWaitingForFirstByte <- true:
+		default:
+		}
+	}
+
+	log.Info("File indexed in ", time.Since(t0), ", have ", reader.GetLineCount(), " lines")
+}
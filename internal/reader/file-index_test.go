@@ -0,0 +1,170 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"gotest.tools/v3/assert"
+)
+
+// Index all files, not just huge ones
+func indexAllFiles(t *testing.T) {
+	original := minIndexedFileSize
+	minIndexedFileSize = 0
+	t.Cleanup(func() {
+		minIndexedFileSize = original
+	})
+}
+
+func newIndexedTestReader(t *testing.T, contents string) (*ReaderImpl, *os.File) {
+	indexAllFiles(t)
+
+	file, err := os.CreateTemp(t.TempDir(), "moor-file-index-*.txt")
+	assert.NilError(t, err)
+	_, err = file.WriteString(contents)
+	assert.NilError(t, err)
+
+	testMe, err := NewFromFilename(file.Name(), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	assert.Assert(t, testMe.index != nil)
+
+	return testMe, file
+}
+
+func plainLines(reader *ReaderImpl) []string {
+	result := []string{}
+	for _, line := range reader.GetLines(linemetadata.Index{}, reader.GetLineCount()+1).Lines {
+		result = append(result, line.Plain())
+	}
+	return result
+}
+
+func TestIndexedFileSameLinesAsInMemory(t *testing.T) {
+	for _, contents := range []string{
+		"",
+		"one line",
+		"one line\n",
+		"first\nsecond",
+		"first\n\nthird\n",
+		"dos\r\nline endings\r\n",
+		"\n\n",
+	} {
+		t.Run(fmt.Sprintf("%q", contents), func(t *testing.T) {
+			indexed, _ := newIndexedTestReader(t, contents)
+			inMemory := NewFromUncompressedStream("", strings.NewReader(contents), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+			assert.NilError(t, inMemory.Wait())
+
+			assert.DeepEqual(t, plainLines(indexed), plainLines(inMemory))
+		})
+	}
+}
+
+func TestIndexedFileManyLines(t *testing.T) {
+	lineCount := (maxCachedChunks + 5) * indexedChunkSize
+	contents := strings.Builder{}
+	for i := range lineCount {
+		contents.WriteString(fmt.Sprintf("Line %d\n", i))
+	}
+
+	testMe, _ := newIndexedTestReader(t, contents.String())
+	assert.Equal(t, testMe.GetLineCount(), lineCount)
+	assert.Equal(t, len(testMe.lines), 0, "Indexed files shouldn't be read into memory")
+	assert.Equal(t, len(testMe.index.current().chunkStarts), maxCachedChunks+5, "Only chunk starts should be indexed")
+
+	// Somewhere in the middle of the line after the first chunk
+	offset := strings.Index(contents.String(), fmt.Sprintf("Line %d\n", indexedChunkSize+1)) + 2
+	index, exact := testMe.LineAtByteOffset(int64(offset))
+	assertLineAt(t, index, exact, indexedChunkSize+1, true)
+
+	for i := range lineCount {
+		assert.Equal(t, testMe.GetLine(linemetadata.IndexFromZeroBased(i)).Plain(), fmt.Sprintf("Line %d", i))
+	}
+
+	assert.Equal(t, len(testMe.index.cache), maxCachedChunks)
+	assert.Equal(t, len(testMe.index.lru), maxCachedChunks)
+
+	// Evicted lines should be read again
+	assert.Equal(t, testMe.GetLine(linemetadata.Index{}).Plain(), "Line 0")
+}
+
+func TestIndexedFileTail(t *testing.T) {
+	testMe, file := newIndexedTestReader(t, "First\nSecond, part")
+	assert.Equal(t, testMe.GetLineCount(), 2)
+	plainLines(testMe) // Get the lines cached
+
+	_, err := file.WriteString(" two\nThird\n")
+	assert.NilError(t, err)
+
+	// Give the reader some time to react
+	for range 20 {
+		if testMe.GetLineCount() == 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	assert.DeepEqual(t, plainLines(testMe), []string{"First", "Second, part two", "Third"})
+}
+
+// Like in-memory files, indexed files keep the lines we had after truncation.
+// Those are gone from the file though, so we keep the ones we had in memory.
+func TestIndexedFileTruncated(t *testing.T) {
+	testMe, file := newIndexedTestReader(t, "First line\nSecond line\n")
+	assert.Equal(t, testMe.GetLineCount(), 2)
+	plainLines(testMe) // Get the lines in memory, like when they are on screen
+
+	assert.NilError(t, file.Truncate(0))
+	_, err := file.WriteAt([]byte("New\n"), 0)
+	assert.NilError(t, err)
+
+	// Give the reader some time to react
+	for range 30 {
+		if testMe.GetLineCount() == 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	assert.DeepEqual(t, plainLines(testMe), []string{"First line", "Second line", "New"})
+}
+
+// The old lines should still be readable from the rotated file, with a
+// separator after them
+func TestIndexedFileRotated(t *testing.T) {
+	indexAllFiles(t)
+
+	fileName := path.Join(t.TempDir(), "app.log")
+	assert.NilError(t, os.WriteFile(fileName, []byte("Old file\n"), 0o600))
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{
+		Style:      styles.Get("native"),
+		FollowName: true,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	assert.Assert(t, testMe.index != nil)
+
+	assert.NilError(t, os.Rename(fileName, fileName+".1"))
+	assert.NilError(t, os.WriteFile(fileName, []byte("New file\n"), 0o600))
+
+	// Give the reader some time to react
+	for range 30 {
+		if testMe.GetLineCount() == 2 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	allLines := testMe.GetLines(linemetadata.Index{}, 10)
+	assert.DeepEqual(t, plainLines(testMe), []string{"Old file", "New file"})
+	assert.Assert(t, strings.HasPrefix(allLines.Lines[0].Line.TailEvent(), "app.log rotated "), allLines.Lines[0].Line.TailEvent())
+	assert.Equal(t, allLines.Lines[1].Line.TailEvent(), "")
+}
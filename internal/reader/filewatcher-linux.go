@@ -0,0 +1,66 @@
+//go:build linux
+
+package reader
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Get notified about file changes through inotify(7)
+type inotifyFileWatcher struct {
+	// Non-blocking, so reads go through the Go runtime's poller rather than
+	// tying up one OS thread per tailed file
+	file *os.File
+}
+
+// Falls back to polling if inotify isn't available
+func newFileWatcher(fileName string) fileWatcher {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		log.Info("inotify not available, polling for file changes instead: ", err)
+		return pollingFileWatcher{}
+	}
+
+	// Rename and delete events are what we get when a log file is rotated
+	const mask = unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE | unix.IN_MOVE_SELF | unix.IN_DELETE_SELF
+	_, err = unix.InotifyAddWatch(fd, fileName, mask)
+	if err != nil {
+		log.Infof("Failed to inotify watch %s, polling for changes instead: %v", fileName, err)
+		_ = unix.Close(fd)
+		return pollingFileWatcher{}
+	}
+
+	log.Debugf("Watching %s for changes using inotify", fileName)
+	return &inotifyFileWatcher{file: os.NewFile(uintptr(fd), "inotify:"+fileName)}
+}
+
+func (w *inotifyFileWatcher) await(timeout time.Duration) {
+	err := w.file.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		log.Debug("Setting inotify read deadline failed, sleeping instead: ", err)
+		time.Sleep(timeout)
+		return
+	}
+
+	// We don't care about the event details, the caller will inspect the file
+	// anyway. Any events that don't fit in the buffer will just make the next
+	// call return right away.
+	buffer := make([]byte, 4096)
+	_, err = w.file.Read(buffer)
+	if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		log.Debug("Reading inotify events failed, sleeping instead: ", err)
+		time.Sleep(timeout)
+	}
+}
+
+func (w *inotifyFileWatcher) close() {
+	err := w.file.Close()
+	if err != nil {
+		log.Debug("Failed to close inotify file descriptor: ", err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package reader
+
+// On non-Linux platforms we poll. If you want to add a kqueue or
+// ReadDirectoryChangesW backend, this is the place.
+func newFileWatcher(fileName string) fileWatcher {
+	return pollingFileWatcher{}
+}
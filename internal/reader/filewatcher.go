@@ -0,0 +1,33 @@
+package reader
+
+import "time"
+
+// How long to wait for change notifications before checking a tailed file
+// anyway. Not all file systems deliver change notifications, so this is our
+// safety net.
+const tailPollInterval = 1 * time.Second
+
+// Tells a tailing reader when the file it is following may have changed.
+//
+// Create using newFileWatcher(), which picks the best backend for the current
+// platform.
+type fileWatcher interface {
+	// Block until the watched file may have changed, or until the timeout
+	// expires. Spurious wakeups are fine, callers are expected to check the
+	// file themselves after this returns.
+	await(timeout time.Duration)
+
+	close()
+}
+
+// Fallback watcher for when we have no better way of getting change
+// notifications.
+type pollingFileWatcher struct{}
+
+func (pollingFileWatcher) await(timeout time.Duration) {
+	time.Sleep(timeout)
+}
+
+func (pollingFileWatcher) close() {
+	// This method intentionally left blank
+}
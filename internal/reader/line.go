@@ -7,25 +7,50 @@ import (
 	"github.com/walles/moor/v2/twin"
 )
 
+// Something to highlight in its own style, in addition to the search hits
+type PatternHighlight struct {
+	Search search.Search
+	Style  twin.Style
+}
+
 // Returns a representation of the string split into styled tokens. Any regexp
 // matches are highlighted. A nil regexp means no highlighting.
 //
+// Matches of the highlights are styled too, the first matching highlight wins.
+// Search hits win over highlights.
+//
 // minRunesCount: at least this many runes will be included in the result. If 0,
 // do all runes. For BenchmarkRenderHugeLine() performance.
 func (line *Line) HighlightedTokens(
 	plainTextStyle twin.Style,
 	searchHitStyle twin.Style,
 	search search.Search,
+	highlights []PatternHighlight,
 	lineIndex linemetadata.Index,
 	minRunesCount int,
 ) textstyles.StyledRunesWithTrailer {
-	matchRanges := search.GetMatchRanges(line.Plain(lineIndex))
+	plain := line.Plain(lineIndex)
+	matchRanges := search.GetMatchRanges(plain)
+	highlightRanges := getHighlightRanges(plain, highlights)
 
-	fromString := textstyles.StyledRunesFromString(plainTextStyle, string(line.raw), &lineIndex, minRunesCount)
+	raw := line.raw
+	if highlighted := line.highlighted.Load(); highlighted != nil {
+		raw = *highlighted
+	}
+	fromString := textstyles.StyledRunesFromString(plainTextStyle, string(raw), &lineIndex, minRunesCount)
 	returnRunes := make([]textstyles.CellWithMetadata, 0, len(fromString.StyledRunes))
 	lastWasSearchHit := false
 	for _, token := range fromString.StyledRunes {
 		style := token.Style
+		highlighted := false
+		for i, ranges := range highlightRanges {
+			if ranges.InRange(len(returnRunes)) {
+				style = highlights[i].Style
+				highlighted = true
+				break
+			}
+		}
+
 		searchHit := matchRanges.InRange(len(returnRunes))
 		if searchHit {
 			// Highlight the search hit
@@ -37,6 +62,7 @@ func (line *Line) HighlightedTokens(
 			Style:           style,
 			IsSearchHit:     searchHit,
 			StartsSearchHit: searchHit && !lastWasSearchHit,
+			IsHighlighted:   highlighted && !searchHit,
 		})
 		lastWasSearchHit = searchHit
 	}
@@ -48,6 +74,16 @@ func (line *Line) HighlightedTokens(
 	}
 }
 
+// One MatchRanges per highlight
+func getHighlightRanges(plain string, highlights []PatternHighlight) []*search.MatchRanges {
+	ranges := make([]*search.MatchRanges, 0, len(highlights))
+	for _, highlight := range highlights {
+		ranges = append(ranges, highlight.Search.GetMatchRanges(plain))
+	}
+
+	return ranges
+}
+
 func (line *Line) HasManPageFormatting() bool {
 	return textstyles.HasManPageFormatting(string(line.raw))
 }
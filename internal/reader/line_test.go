@@ -42,7 +42,7 @@ func TestSearchHitSpanningWrapBoundary(t *testing.T) {
 	searchHitStyle := twin.StyleDefault.WithForeground(twin.NewColor16(3))
 
 	// Match runs from indices 3..8 inclusive ("345678")
-	highlighted := line.HighlightedTokens(twin.StyleDefault, searchHitStyle, search.For("345678"), linemetadata.Index{}, 0)
+	highlighted := line.HighlightedTokens(twin.StyleDefault, searchHitStyle, search.For("345678"), nil, linemetadata.Index{}, 0)
 
 	// Sanity: overall line reports having a search hit
 	assert.Assert(t, highlighted.ContainsSearchHit, "Expected overall line to contain search hit")
@@ -66,3 +66,24 @@ func TestSearchHitSpanningWrapBoundary(t *testing.T) {
 		}
 	}
 }
+
+func TestHighlightPatterns(t *testing.T) {
+	line := NewFromTextForTesting("TestHighlightPatterns", "req=42 user=7").GetLine(linemetadata.Index{}).Line
+	searchHitStyle := twin.StyleDefault.WithAttr(twin.AttrReverse)
+	reqStyle := twin.StyleDefault.WithBackground(twin.NewColor16(3))
+	userStyle := twin.StyleDefault.WithBackground(twin.NewColor16(2))
+
+	highlighted := line.HighlightedTokens(twin.StyleDefault, searchHitStyle, search.For("42"), []PatternHighlight{
+		{Search: search.For("req=[0-9]+"), Style: reqStyle},
+		{Search: search.For("user"), Style: userStyle},
+	}, linemetadata.Index{}, 0)
+
+	runes := highlighted.StyledRunes
+	assert.Equal(t, runes[0].Style, reqStyle)
+	assert.Assert(t, runes[0].IsHighlighted)
+	assert.Equal(t, runes[4].Style, searchHitStyle, "Search hits should win over highlights")
+	assert.Assert(t, !runes[4].IsHighlighted)
+	assert.Equal(t, runes[6].Style, twin.StyleDefault)
+	assert.Equal(t, runes[7].Style, userStyle)
+	assert.Equal(t, runes[12].Style, twin.StyleDefault)
+}
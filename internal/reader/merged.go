@@ -0,0 +1,451 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// How often the merged view checks its sources for new lines
+const mergePollInterval = 200 * time.Millisecond
+
+// Longer file names will be cut to this length in the merged view line prefixes
+const maxMergePrefixLength = 12
+
+// ANSI SGR foreground color codes for the merged view line prefixes. If there
+// are more sources than colors, we start over from the beginning.
+var mergePrefixColors = []int{32, 33, 34, 35, 36, 31}
+
+type mergeSource struct {
+	reader *ReaderImpl
+
+	// Shown before each line from this source
+	prefix string
+
+	// This many lines have been merged so far
+	consumed int
+
+	// The latest timestamp we found in this source. Lines without timestamps
+	// (think stack traces) get this one, so that they stay with the line
+	// above them.
+	timestamp time.Time
+
+	// Highlighting replaces all lines of the source reader, so when this
+	// changes we need to start over.
+	highlightingDone bool
+}
+
+type timestampedLine struct {
+	timestamp time.Time
+	line      *Line
+}
+
+// Merges a number of source readers into one target reader
+type merger struct {
+	target  *ReaderImpl
+	sources []*mergeSource
+
+	// Protects added and running
+	lock sync.Mutex
+
+	// Readers to start merging, see AddMergeSource()
+	added []*ReaderImpl
+
+	// Whether the run() goroutine is alive
+	running bool
+
+	// Timestamps of the lines in the target reader, in the same order
+	timestamps []time.Time
+}
+
+// NewMerged creates a reader showing the lines of all the given readers in one
+// view, ordered by the timestamps at the start of each line. Each line gets a
+// prefix telling which reader it came from, see Line.Prefix().
+//
+// Lines without timestamps stay right after the line above them in their
+// source reader.
+//
+// Indexed readers are left out, since merging needs all lines in memory.
+func NewMerged(displayName string, sources []*ReaderImpl) *ReaderImpl {
+	m := newMerger(displayName, sources)
+
+	m.lock.Lock()
+	m.startUnlocked()
+	m.lock.Unlock()
+
+	return m.target
+}
+
+// AddMergeSource adds another reader to a merged view created by NewMerged().
+// All lines get new prefixes, since the new file name may be wider than the
+// ones we have.
+func (reader *ReaderImpl) AddMergeSource(source *ReaderImpl) {
+	m := reader.merger
+	if m == nil {
+		log.Warn("Not a merged view, can't add merge source")
+		return
+	}
+
+	if !canMerge(source) {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.added = append(m.added, source)
+
+	// The new source likely has more lines for us
+	m.target.ReadingDone.Store(false)
+	m.target.tailingDone.Store(false)
+	if !m.running {
+		m.startUnlocked()
+	}
+}
+
+// Call with m.lock held
+func (m *merger) startUnlocked() {
+	m.running = true
+	go func() {
+		defer func() {
+			PanicHandler("NewMerged()/merge", recover(), debug.Stack())
+		}()
+
+		m.run()
+	}()
+}
+
+// Merging needs all lines in memory, and indexed files have too many
+func canMerge(source *ReaderImpl) bool {
+	source.RLock()
+	defer source.RUnlock()
+	if source.index == nil {
+		return true
+	}
+
+	log.Info("Leaving indexed file out of the merged view")
+	return false
+}
+
+func newMerger(displayName string, sources []*ReaderImpl) *merger {
+	readingDone := atomic.Bool{}
+	highlightingDone := atomic.Bool{}
+	highlightingDone.Store(true) // The sources do the highlighting
+	pauseStatus := atomic.Bool{}
+
+	target := &ReaderImpl{
+		DisplayName: &displayName,
+
+		// We don't do any reading of our own, so there's nothing to pause
+		pauseAfterLines:        math.MaxInt,
+		pauseAfterLinesUpdated: make(chan bool, 1),
+		PauseStatus:            &pauseStatus,
+
+		MoreLinesAdded:          make(chan bool, 1),
+		MaybeDone:               make(chan bool, 2),
+		highlightingStyle:       make(chan chroma.Style, 1),
+		doneWaitingForFirstByte: make(chan bool, 1),
+		HighlightingDone:        &highlightingDone,
+		ReadingDone:             &readingDone,
+	}
+
+	m := &merger{target: target}
+	target.merger = m
+	for _, source := range sources {
+		if canMerge(source) {
+			m.addSource(source)
+		}
+	}
+
+	return m
+}
+
+// Start merging lines from another source. All sources get new prefixes, so
+// all lines need to be merged again after this.
+func (m *merger) addSource(source *ReaderImpl) {
+	// We want all lines, otherwise we can't sort them
+	source.SetPauseAfterLines(math.MaxInt)
+
+	m.sources = append(m.sources, &mergeSource{reader: source})
+
+	readers := make([]*ReaderImpl, len(m.sources))
+	for i, mergeSource := range m.sources {
+		readers[i] = mergeSource.reader
+	}
+	for i, mergeSource := range m.sources {
+		mergeSource.prefix = mergePrefix(i, readers)
+	}
+}
+
+// Returns true if any sources were added since last time
+func (m *merger) takeAddedSources() bool {
+	m.lock.Lock()
+	added := m.added
+	m.added = nil
+	m.lock.Unlock()
+
+	for _, source := range added {
+		m.addSource(source)
+	}
+
+	return len(added) > 0
+}
+
+// Returns a colored prefix for the lines of sources[index], with the source
+// name padded to match the names of all other sources.
+func mergePrefix(index int, sources []*ReaderImpl) string {
+	names := make([]string, len(sources))
+	width := 0
+	for i, source := range sources {
+		name := fmt.Sprint(i + 1)
+		source.RLock()
+		if source.DisplayName != nil && len(*source.DisplayName) > 0 {
+			name = *source.DisplayName
+		}
+		source.RUnlock()
+
+		nameRunes := []rune(name)
+		if len(nameRunes) > maxMergePrefixLength {
+			name = string(nameRunes[:maxMergePrefixLength-1]) + "…"
+		}
+
+		names[i] = name
+		width = max(width, len([]rune(name)))
+	}
+
+	color := mergePrefixColors[index%len(mergePrefixColors)]
+	return fmt.Sprintf("\x1b[%dm%-*s │\x1b[m ", color, width, names[index])
+}
+
+// Merge until all sources are done, including tailing
+func (m *merger) run() {
+	for {
+		// Checked before the last update, so that we don't miss any lines
+		tailingDone := m.allSourcesTailingDone()
+
+		if m.update() {
+			select {
+			case m.target.MoreLinesAdded <- true:
+			default:
+			}
+		}
+
+		if !m.target.ReadingDone.Load() && m.allSourcesDone() {
+			log.Debugf("Merging done, have %d lines", m.target.GetLineCount())
+			m.target.ReadingDone.Store(true)
+			select {
+			case m.target.MaybeDone <- true:
+			default:
+			}
+		}
+
+		if tailingDone && m.stopUnlessAdded() {
+			log.Debug("All merge sources done, no more merging to do")
+			return
+		}
+
+		time.Sleep(mergePollInterval)
+	}
+}
+
+// Returns true if we stopped, false if more sources were added and we should
+// keep merging
+func (m *merger) stopUnlessAdded() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.added) > 0 {
+		return false
+	}
+
+	m.running = false
+	m.target.tailingDone.Store(true)
+	return true
+}
+
+func (m *merger) allSourcesDone() bool {
+	m.lock.Lock()
+	adding := len(m.added) > 0
+	m.lock.Unlock()
+	if adding {
+		return false
+	}
+
+	for _, source := range m.sources {
+		if !source.reader.ReadingDone.Load() {
+			return false
+		}
+		if source.consumed < source.reader.GetLineCount() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *merger) allSourcesTailingDone() bool {
+	for _, source := range m.sources {
+		if !source.reader.tailingDone.Load() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Merge any new lines from our sources into the target. Returns true if the
+// target changed.
+func (m *merger) update() bool {
+	needsRebuild := m.takeAddedSources()
+	for _, source := range m.sources {
+		highlightingDone := source.reader.HighlightingDone.Load()
+		if highlightingDone != source.highlightingDone {
+			source.highlightingDone = highlightingDone
+			needsRebuild = true
+		}
+
+		if source.reader.GetLineCount() < source.consumed {
+			// Contents were replaced
+			needsRebuild = true
+		}
+	}
+
+	if needsRebuild {
+		for _, source := range m.sources {
+			source.consumed = 0
+			source.timestamp = time.Time{}
+		}
+	}
+
+	newLines := make([][]timestampedLine, len(m.sources))
+	for i, source := range m.sources {
+		newLines[i] = source.takeNewLines()
+	}
+
+	merged := mergeByTimestamp(newLines)
+	if needsRebuild {
+		// Replace everything at once, so that the line count never drops to
+		// zero while we rebuild
+		m.replaceFrom(0, merged)
+		return true
+	}
+
+	if len(merged) == 0 {
+		return false
+	}
+
+	// Logs from multi threaded programs are often slightly out of order. Lines
+	// older than the ones we have go into place, and only the lines after them
+	// are merged again.
+	first := sort.Search(len(m.timestamps), func(i int) bool {
+		return m.timestamps[i].After(merged[0].timestamp)
+	})
+	if first < len(m.timestamps) {
+		log.Debugf("Out of order lines in merged view, merging the last %d lines again", len(m.timestamps)-first)
+
+		m.target.RLock()
+		tail := make([]timestampedLine, 0, len(m.timestamps)-first)
+		for i := first; i < len(m.timestamps); i++ {
+			tail = append(tail, timestampedLine{timestamp: m.timestamps[i], line: m.target.lines[i]})
+		}
+		m.target.RUnlock()
+
+		// The tail goes first, so that lines with the same timestamp stay in
+		// the order we got them
+		merged = mergeByTimestamp([][]timestampedLine{tail, merged})
+	}
+
+	m.replaceFrom(first, merged)
+	return true
+}
+
+// Replace all target lines from index first with the given lines
+func (m *merger) replaceFrom(first int, lines []timestampedLine) {
+	m.timestamps = m.timestamps[:first]
+	newLines := make([]*Line, 0, len(lines))
+	for _, line := range lines {
+		m.timestamps = append(m.timestamps, line.timestamp)
+		newLines = append(newLines, line.line)
+	}
+
+	m.target.Lock()
+	m.target.lines = append(m.target.lines[:first], newLines...)
+	if first < m.target.lineStartsFirst+len(m.target.lineStarts) {
+		m.target.inputBytes = m.target.lineStarts[first-m.target.lineStartsFirst]
+		m.target.lineStarts = m.target.lineStarts[:first-m.target.lineStartsFirst]
+	}
+	m.target.addLineStartsUnlocked()
+	m.target.Unlock()
+}
+
+// Get all lines we haven't merged yet, with prefixes and timestamps
+func (source *mergeSource) takeNewLines() []timestampedLine {
+	source.reader.RLock()
+	defer source.reader.RUnlock()
+
+	available := source.reader.lineCountUnlocked()
+	if !source.reader.endsWithNewline && !source.reader.ReadingDone.Load() {
+		// The last line may still grow, wait for it to be completed
+		available--
+	}
+	if available <= source.consumed {
+		return nil
+	}
+
+	result := make([]timestampedLine, 0, available-source.consumed)
+	for i := source.consumed; i < available; i++ {
+		line := source.reader.lineUnlocked(i)
+
+		// Lines from the same source keep their order, so timestamps that go
+		// backwards count as the one before them
+		timestamp, found := ParseLeadingTimestamp(line.Plain(linemetadata.IndexFromZeroBased(i)))
+		if found && timestamp.After(source.timestamp) {
+			source.timestamp = timestamp
+		}
+
+		result = append(result, timestampedLine{
+			timestamp: source.timestamp,
+			line:      &Line{raw: line.raw, prefix: source.prefix},
+		})
+	}
+	source.consumed = available
+
+	return result
+}
+
+// Merge the lines from all sources into one list, ordered by timestamp. Lines
+// from the same source keep their relative order. For equal timestamps, lines
+// from earlier sources come first.
+func mergeByTimestamp(sources [][]timestampedLine) []timestampedLine {
+	totalCount := 0
+	for _, lines := range sources {
+		totalCount += len(lines)
+	}
+
+	result := make([]timestampedLine, 0, totalCount)
+	heads := make([]int, len(sources))
+	for len(result) < totalCount {
+		best := -1
+		for i, lines := range sources {
+			if heads[i] >= len(lines) {
+				continue
+			}
+			if best == -1 || lines[heads[i]].timestamp.Before(sources[best][heads[best]].timestamp) {
+				best = i
+			}
+		}
+
+		result = append(result, sources[best][heads[best]])
+		heads[best]++
+	}
+
+	return result
+}
@@ -0,0 +1,146 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"gotest.tools/v3/assert"
+)
+
+func mergedPlainLines(t *testing.T, merged *ReaderImpl) []string {
+	t.Helper()
+
+	for !merged.ReadingDone.Load() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return withPlainPrefixes(merged.GetLines(linemetadata.Index{}, merged.GetLineCount()).Lines)
+}
+
+// Like "a │ line contents"
+func withPlainPrefixes(numberedLines []NumberedLine) []string {
+	lines := []string{}
+	for _, line := range numberedLines {
+		prefix := NewLine(line.Line.Prefix()).Plain(line.Index)
+		lines = append(lines, prefix+line.Plain())
+	}
+	return lines
+}
+
+func TestMerged(t *testing.T) {
+	server := NewFromTextForTesting("server.log", strings.Join([]string{
+		"2024-01-02 10:00:00 Server starting",
+		"2024-01-02 10:00:02 Server failed",
+		"  at Server.main()",
+		"2024-01-02 10:00:04 Server done",
+	}, "\n"))
+	client := NewFromTextForTesting("client.log", strings.Join([]string{
+		"2024-01-02 10:00:01 Client connecting",
+		"2024-01-02 10:00:03 Client gave up",
+	}, "\n"))
+
+	merged := NewMerged("Merged", []*ReaderImpl{server, client})
+
+	assert.DeepEqual(t, mergedPlainLines(t, merged), []string{
+		"server.log │ 2024-01-02 10:00:00 Server starting",
+		"client.log │ 2024-01-02 10:00:01 Client connecting",
+		"server.log │ 2024-01-02 10:00:02 Server failed",
+		"server.log │   at Server.main()",
+		"client.log │ 2024-01-02 10:00:03 Client gave up",
+		"server.log │ 2024-01-02 10:00:04 Server done",
+	})
+
+	// The prefix is not part of the line, so searching and filtering don't see
+	// it
+	assert.Equal(t, merged.GetLine(linemetadata.Index{}).Plain(), "2024-01-02 10:00:00 Server starting")
+}
+
+// Indexed files are too large for having all their lines in memory
+func TestMerged_IndexedLeftOut(t *testing.T) {
+	indexed, _ := newIndexedTestReader(t, "2024-01-02 10:00:00 Indexed\n")
+	inMemory := NewFromTextForTesting("memory.log", "2024-01-02 10:00:01 In memory")
+
+	merged := NewMerged("Merged", []*ReaderImpl{indexed, inMemory})
+
+	assert.DeepEqual(t, mergedPlainLines(t, merged), []string{
+		"memory.log │ 2024-01-02 10:00:01 In memory",
+	})
+}
+
+// Lines without timestamps before the first timestamped line should come
+// first, and equal timestamps should keep the source order.
+func TestMerged_NoTimestamps(t *testing.T) {
+	first := NewFromTextForTesting("a", "2024-01-02 10:00:00 A\nHeader A")
+	second := NewFromTextForTesting("bb", "Header B\n2024-01-02 10:00:00 B")
+
+	merged := NewMerged("Merged", []*ReaderImpl{first, second})
+
+	assert.DeepEqual(t, mergedPlainLines(t, merged), []string{
+		"bb │ Header B",
+		"a  │ 2024-01-02 10:00:00 A",
+		"a  │ Header A",
+		"bb │ 2024-01-02 10:00:00 B",
+	})
+}
+
+// Slightly out of order lines should go into place, without starting over
+func TestMerged_LateLinesGoIntoPlace(t *testing.T) {
+	server := NewFromTextForTesting("s", strings.Join([]string{
+		"2024-01-02 10:00:00 Early",
+		"2024-01-02 10:00:05 Late",
+	}, "\n"))
+	client := NewFromTextForTesting("c", "2024-01-02 10:00:02 Client")
+
+	m := newMerger("Merged", []*ReaderImpl{server, client})
+	assert.Assert(t, m.update())
+	earlyLine := m.target.lines[0]
+
+	client.Lock()
+	client.lines = append(client.lines, NewLine("2024-01-02 10:00:03 Client again"))
+	client.Unlock()
+	assert.Assert(t, m.update())
+
+	lines := withPlainPrefixes(m.target.GetLines(linemetadata.Index{}, 10).Lines)
+	assert.DeepEqual(t, lines, []string{
+		"s │ 2024-01-02 10:00:00 Early",
+		"c │ 2024-01-02 10:00:02 Client",
+		"c │ 2024-01-02 10:00:03 Client again",
+		"s │ 2024-01-02 10:00:05 Late",
+	})
+
+	// The lines before the late one were left alone
+	assert.Equal(t, m.target.lines[0], earlyLine)
+
+	assert.Assert(t, !m.update())
+}
+
+func TestMerged_StopsWhenSourcesAreDone(t *testing.T) {
+	merged := NewMerged("Merged", []*ReaderImpl{NewFromTextForTesting("a", "A")})
+
+	for range 50 {
+		if merged.tailingDone.Load() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Assert(t, merged.tailingDone.Load())
+	assert.Assert(t, merged.ReadingDone.Load())
+}
+
+// Files opened after the merged view was created should show up in it
+func TestMerged_AddMergeSource(t *testing.T) {
+	merged := NewMerged("Merged", []*ReaderImpl{NewFromTextForTesting("a", "2024-01-02 10:00:00 A")})
+	for !merged.tailingDone.Load() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// After merging has stopped, adding a source should start it again
+	merged.AddMergeSource(NewFromTextForTesting("bb", "2024-01-02 09:00:00 B"))
+
+	assert.DeepEqual(t, mergedPlainLines(t, merged), []string{
+		"bb │ 2024-01-02 09:00:00 B",
+		"a  │ 2024-01-02 10:00:00 A",
+	})
+}
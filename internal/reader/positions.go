@@ -0,0 +1,190 @@
+// Find lines by byte offset or by percentage, without waiting for all input
+
+package reader
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// How much we read at a byte offset for showing lines from there before
+// indexing gets there
+const previewBytes = 1024 * 1024
+
+// LineAtByteOffset returns the line containing some byte offset into the
+// input.
+//
+// If we haven't read that far yet, the second return value is false, and the
+// line is an estimate based on the average line length so far. For indexed
+// files, we then read the lines at the offset right away, and put them at the
+// estimated line until indexing gets there.
+func (reader *ReaderImpl) LineAtByteOffset(offset int64) (linemetadata.Index, bool) {
+	reader.RLock()
+	index, exact := reader.lineAtByteOffsetUnlocked(offset)
+	seek := !exact && reader.index != nil && !reader.ReadingDone.Load()
+	reader.RUnlock()
+
+	if seek {
+		previewIndex, found := reader.previewAt(offset)
+		if found {
+			return previewIndex, false
+		}
+	}
+
+	return index, exact
+}
+
+// Assumes the reader lock is held
+func (reader *ReaderImpl) lineAtByteOffsetUnlocked(offset int64) (linemetadata.Index, bool) {
+	lineCount := reader.lineCountUnlocked()
+	if lineCount == 0 {
+		return linemetadata.Index{}, reader.ReadingDone.Load()
+	}
+
+	if reader.index != nil {
+		// Offsets are into the current file, after any rotations
+		firstLine := reader.index.current().firstLine
+		indexedLineCount := reader.index.indexedLineCount()
+		if offset >= reader.bytesCount || firstLine == indexedLineCount {
+			return reader.estimateLineUnlocked(offset, firstLine, reader.bytesCount)
+		}
+		return linemetadata.IndexFromZeroBased(reader.index.lineAtOffset(offset)), true
+	}
+
+	// Offsets are into the current file, after any truncations or rotations
+	firstLine := reader.lineStartsFirst
+	if offset >= reader.inputBytes || firstLine+len(reader.lineStarts) < lineCount || firstLine == lineCount {
+		return reader.estimateLineUnlocked(offset, firstLine, reader.inputBytes)
+	}
+
+	// The first line starting after the offset, then one back
+	after := sort.Search(len(reader.lineStarts), func(i int) bool {
+		return reader.lineStarts[i] > offset
+	})
+	return linemetadata.IndexFromZeroBased(firstLine + max(after-1, 0)), true
+}
+
+// Read the lines at some offset we haven't indexed yet, and show them at the
+// estimated line number. Returns false if there are no lines there.
+func (reader *ReaderImpl) previewAt(offset int64) (linemetadata.Index, bool) {
+	reader.RLock()
+	preview := reader.index.preview
+	segment := reader.index.current()
+	reader.RUnlock()
+
+	if preview != nil && preview.offset == offset {
+		return linemetadata.IndexFromZeroBased(preview.firstLine), true
+	}
+
+	lines := readLinesAt(segment.file, offset)
+	if len(lines) == 0 {
+		return linemetadata.Index{}, false
+	}
+
+	reader.Lock()
+	defer reader.Unlock()
+
+	if reader.index.current() != segment || offset < reader.bytesCount {
+		// The file was rotated, or indexing got there while we were reading
+		return linemetadata.Index{}, false
+	}
+
+	reader.index.preview = nil
+	firstLine := reader.index.indexedLineCount()
+	if reader.bytesCount > 0 {
+		estimate, _ := reader.estimateLineUnlocked(offset, segment.firstLine, reader.bytesCount)
+		firstLine = max(estimate.Index(), firstLine)
+	}
+	reader.index.preview = &indexPreview{
+		offset:    offset,
+		firstLine: firstLine,
+		lines:     lines,
+	}
+	log.Debugf("Showing lines at byte %d from estimated line %d until indexing gets there", offset, firstLine+1)
+
+	return linemetadata.IndexFromZeroBased(firstLine), true
+}
+
+// Lines from the first line start at or after offset, up to a chunk of them
+func readLinesAt(file *os.File, offset int64) []*Line {
+	readFrom := max(offset-1, 0)
+	buffer := make([]byte, previewBytes)
+	readBytes, err := file.ReadAt(buffer, readFrom)
+	if err != nil && err != io.EOF {
+		log.Warn("Failed to read lines at byte offset: ", err)
+		return nil
+	}
+	atEnd := readBytes < len(buffer)
+	buffer = buffer[:readBytes]
+
+	if offset > 0 {
+		// Skip to the start of the next line. Starting at the byte before the
+		// offset means that if the offset is a line start, we get that line.
+		_, rest, found := bytes.Cut(buffer, []byte{'\n'})
+		if !found {
+			return nil
+		}
+		buffer = rest
+	}
+
+	lines := []*Line{}
+	for len(buffer) > 0 && len(lines) < indexedChunkSize {
+		raw, rest, found := bytes.Cut(buffer, []byte{'\n'})
+		if !found && !atEnd {
+			// Cut off by the end of the buffer
+			break
+		}
+		buffer = rest
+
+		lines = append(lines, &Line{raw: bytes.TrimSuffix(raw, []byte{'\r'})})
+	}
+
+	return lines
+}
+
+// For offsets past what we have read. Once we're done, that's the last line.
+// The bytes read are from firstLine on.
+func (reader *ReaderImpl) estimateLineUnlocked(offset int64, firstLine int, bytesRead int64) (linemetadata.Index, bool) {
+	lineCount := reader.lineCountUnlocked()
+	if reader.ReadingDone.Load() || bytesRead == 0 {
+		return linemetadata.IndexFromZeroBased(lineCount - 1), reader.ReadingDone.Load()
+	}
+
+	estimate := float64(firstLine) + float64(lineCount-firstLine)*float64(offset)/float64(bytesRead)
+	return linemetadata.IndexFromZeroBased(int(min(estimate, math.MaxInt32))), false
+}
+
+// LineAtPercent returns the line some percentage into the input.
+//
+// Indexed files go by byte offset, since we know their size up front. Other
+// inputs go by line count, and if we haven't read all of them yet the second
+// return value is false and the line is an estimate.
+func (reader *ReaderImpl) LineAtPercent(percent int) (linemetadata.Index, bool) {
+	reader.RLock()
+	var size int64 = -1
+	if reader.index != nil {
+		stat, err := reader.index.current().file.Stat()
+		if err == nil {
+			size = stat.Size()
+		}
+	}
+	reader.RUnlock()
+
+	if size >= 0 {
+		return reader.LineAtByteOffset(size * int64(percent) / 100)
+	}
+
+	done := reader.ReadingDone.Load()
+	lineCount := reader.GetLineCount()
+	if lineCount == 0 {
+		return linemetadata.Index{}, done
+	}
+
+	return linemetadata.IndexFromZeroBased(min(lineCount*percent/100, lineCount-1)), done
+}
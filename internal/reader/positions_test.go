@@ -0,0 +1,136 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"gotest.tools/v3/assert"
+)
+
+// Lines are 4 bytes each including the newline, so line N starts at byte 4*N
+const positionsTestText = "000\n111\n222\n333\n444\n555\n666\n777\n888\n999\n"
+
+func assertLineAt(t *testing.T, index linemetadata.Index, exact bool, expectedIndex int, expectedExact bool) {
+	t.Helper()
+	assert.Equal(t, index.Index(), expectedIndex)
+	assert.Equal(t, exact, expectedExact)
+}
+
+func TestLineAtByteOffset(t *testing.T) {
+	inMemory := NewFromTextForTesting("", positionsTestText)
+	indexed, _ := newIndexedTestReader(t, positionsTestText)
+
+	for _, testMe := range []*ReaderImpl{inMemory, indexed} {
+		index, exact := testMe.LineAtByteOffset(0)
+		assertLineAt(t, index, exact, 0, true)
+
+		index, exact = testMe.LineAtByteOffset(3) // The first newline
+		assertLineAt(t, index, exact, 0, true)
+
+		index, exact = testMe.LineAtByteOffset(4)
+		assertLineAt(t, index, exact, 1, true)
+
+		index, exact = testMe.LineAtByteOffset(39)
+		assertLineAt(t, index, exact, 9, true)
+
+		// Past the end
+		index, exact = testMe.LineAtByteOffset(1000)
+		assertLineAt(t, index, exact, 9, true)
+	}
+}
+
+func TestLineAtPercent(t *testing.T) {
+	inMemory := NewFromTextForTesting("", positionsTestText)
+	indexed, _ := newIndexedTestReader(t, positionsTestText)
+
+	for _, testMe := range []*ReaderImpl{inMemory, indexed} {
+		index, exact := testMe.LineAtPercent(0)
+		assertLineAt(t, index, exact, 0, true)
+
+		index, exact = testMe.LineAtPercent(50)
+		assertLineAt(t, index, exact, 5, true)
+
+		index, exact = testMe.LineAtPercent(100)
+		assertLineAt(t, index, exact, 9, true)
+	}
+}
+
+// Until we have read everything, positions past what we have are estimates
+func TestLineAtByteOffsetEstimate(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	testMe := NewFromUncompressedStream("", pipeReader, formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+
+	go func() {
+		_, _ = pipeWriter.Write([]byte("000\n111\n"))
+	}()
+	for testMe.GetLineCount() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Two lines in eight bytes, so byte 20 should be around line 5
+	index, exact := testMe.LineAtByteOffset(20)
+	assertLineAt(t, index, exact, 5, false)
+
+	index, exact = testMe.LineAtPercent(50)
+	assertLineAt(t, index, exact, 1, false)
+
+	assert.NilError(t, pipeWriter.Close())
+	assert.NilError(t, testMe.Wait())
+
+	index, exact = testMe.LineAtByteOffset(20)
+	assertLineAt(t, index, exact, 1, true)
+}
+
+// Byte offsets come from the input, so two byte line endings count
+func TestLineAtByteOffsetCRLF(t *testing.T) {
+	testMe := NewFromUncompressedStream("", strings.NewReader("000\r\n111\r\n222\r\n"), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, testMe.Wait())
+
+	index, exact := testMe.LineAtByteOffset(4) // The first newline
+	assertLineAt(t, index, exact, 0, true)
+
+	index, exact = testMe.LineAtByteOffset(5)
+	assertLineAt(t, index, exact, 1, true)
+
+	index, exact = testMe.LineAtByteOffset(10)
+	assertLineAt(t, index, exact, 2, true)
+}
+
+// Indexed files show the lines at offsets we haven't indexed yet right away,
+// with estimated line numbers
+func TestLineAtByteOffsetPreview(t *testing.T) {
+	contents := strings.Builder{}
+	for i := range 3 * indexedChunkSize {
+		contents.WriteString(fmt.Sprintf("Line %04d\n", i)) // 10 bytes per line
+	}
+	testMe, _ := newIndexedTestReader(t, contents.String())
+
+	// Pretend we have only indexed the first chunk
+	testMe.Lock()
+	testMe.ReadingDone.Store(false)
+	segment := testMe.index.current()
+	segment.chunkStarts = segment.chunkStarts[:1]
+	segment.lineCount = indexedChunkSize
+	segment.endOffset = 10 * indexedChunkSize
+	testMe.bytesCount = segment.endOffset
+	testMe.Unlock()
+
+	// In the middle of line 2500, so the next line start is line 2501
+	index, exact := testMe.LineAtByteOffset(25005)
+	assertLineAt(t, index, exact, 2500, false)
+	assert.Equal(t, testMe.GetLine(index).Plain(), "Line 2501")
+	assert.Equal(t, testMe.GetLineCount(), 2500+3*indexedChunkSize-2501, "Estimated first line plus the rest of the file")
+
+	// Asking again should give the same answer
+	index, exact = testMe.LineAtByteOffset(25005)
+	assertLineAt(t, index, exact, 2500, false)
+
+	// Lines between the indexed ones and the preview are empty for now
+	assert.Equal(t, testMe.GetLine(linemetadata.IndexFromZeroBased(2000)).Plain(), "")
+}
@@ -3,8 +3,6 @@ package reader
 import (
 	"bufio"
 	"bytes"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"io"
 	"math"
@@ -18,6 +16,7 @@ import (
 	"time"
 
 	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reformat"
 	"github.com/walles/moor/v2/internal/textstyles"
 	"github.com/walles/moor/v2/internal/util"
 
@@ -40,9 +39,13 @@ const DEFAULT_PAUSE_AFTER_LINES = 50_000
 var DisablePlainCachingForBenchmarking = false
 
 type ReaderOptions struct {
-	// Format JSON input
+	// Reformat input that we know how to reformat, see the reformat package
 	ShouldFormat bool
 
+	// Reformat as this kind of input, like "csv". Empty means we guess from the
+	// lexer, the file name and the contents.
+	Reformatter string
+
 	// Pause after reading this many lines, unless told otherwise.
 	// Tune at runtime using SetPauseAfterLines().
 	//
@@ -55,6 +58,10 @@ type ReaderOptions struct {
 
 	// If this is set, it will be used as the lexer for highlighting
 	Lexer chroma.Lexer
+
+	// When tailing files, reopen the file by name if it is rotated or
+	// truncated, just like "tail -F".
+	FollowName bool
 }
 
 type Reader interface {
@@ -84,6 +91,41 @@ type Reader interface {
 type Line struct {
 	raw            []byte
 	plainTextCache atomic.Pointer[string] // Use line.Plain() to access this field
+
+	// For inputs too large to highlight all at once, see viewportHighlighter.
+	// Shown instead of raw when set.
+	highlighted atomic.Pointer[[]byte]
+
+	// What happened to the file we're tailing after this line, like "app.log
+	// rotated 12:34:56". See TailEvent().
+	tailEvent atomic.Pointer[string]
+
+	// Which file this line came from in merged views, see Prefix()
+	prefix string
+}
+
+// NewLine creates a line that doesn't come from any input, like a divider
+// between groups of lines
+func NewLine(raw string) *Line {
+	return &Line{raw: []byte(raw)}
+}
+
+// Prefix returns what to show before this line, like a colored file name in
+// merged views, or "". This is not part of the line contents, so it isn't
+// searched, filtered or saved.
+func (line *Line) Prefix() string {
+	return line.prefix
+}
+
+// TailEvent returns what happened to the file we're tailing after this line,
+// like "app.log rotated 12:34:56", or "" if nothing happened. This is not part
+// of the line contents, so it isn't searched, filtered or saved.
+func (line *Line) TailEvent() string {
+	event := line.tailEvent.Load()
+	if event == nil {
+		return ""
+	}
+	return *event
 }
 
 // ReaderImpl reads a file into an array of strings.
@@ -95,8 +137,16 @@ type Line struct {
 type ReaderImpl struct {
 	sync.RWMutex
 
+	// Unused if index is set. Use lineCountUnlocked() and lineUnlocked()
+	// unless you know which one it is.
 	lines []*Line
 
+	// Set for huge files, see file-index.go
+	index *fileIndex
+
+	// Set for merged views, see merged.go
+	merger *merger
+
 	// Display name for the buffer. If not set, no buffer name will be shown.
 	//
 	// For files, this will be the basename of the file. For our help text, this
@@ -111,6 +161,21 @@ type ReaderImpl struct {
 	// How many bytes have we read so far?
 	bytesCount int64
 
+	// Where in the input each line starts, and how many bytes of input we have
+	// consumed, for LineAtByteOffset(). Unused if index is set, the index
+	// tracks this by itself.
+	lineStarts []int64
+	inputBytes int64
+
+	// The line lineStarts[0] is for. Lines before this are from before the
+	// file we're tailing was truncated or rotated, and have no offsets in the
+	// current file.
+	lineStartsFirst int
+
+	// Describes the last time the tailed file was rotated or truncated, for
+	// the status bar. Empty if nothing like that has happened.
+	tailEvent string
+
 	endsWithNewline bool
 
 	Err error
@@ -121,6 +186,10 @@ type ReaderImpl struct {
 	// Highlighting has been completed.
 	HighlightingDone *atomic.Bool
 
+	// Reading, highlighting and tailing are all done, no more lines will be
+	// added
+	tailingDone atomic.Bool
+
 	highlightingStyle chan chroma.Style
 
 	// This channel expects to be read exactly once. All other uses will lead to
@@ -141,6 +210,10 @@ type ReaderImpl struct {
 
 	// PauseStatus is true if the reader is paused, false if it is not
 	PauseStatus *atomic.Bool
+
+	// Set if the input is too large for highlighting all of it. Protected by
+	// the reader lock.
+	viewportHighlighter *viewportHighlighter
 }
 
 // InputLines contains a number of lines from the reader, plus metadata
@@ -177,10 +250,12 @@ func (reader *ReaderImpl) readStream(stream io.Reader, formatter chroma.Formatte
 
 	// Tail the file if the stream is coming from a file.
 	// Ref: https://github.com/walles/moor/issues/224
-	err := reader.tailFile()
+	err := reader.tailFile(options.FollowName)
 	if err != nil {
 		log.Warn("Failed to tail file: ", err)
 	}
+
+	reader.tailingDone.Store(true)
 }
 
 // Pause if we should pause, otherwise not. Pausing means waiting for
@@ -203,9 +278,9 @@ func (reader *ReaderImpl) assumeLockAndMaybePause() {
 	}
 }
 
-// Assume write lock held. Add a new line. If this function paused, it will
-// return the pause duration.
-func (reader *ReaderImpl) assumeLockAndAddLine(line []byte, considerAppending bool, linePool *linePool) time.Duration {
+// Assume write lock held. Add a new line, starting at byte offset start in the
+// input. If this function paused, it will return the pause duration.
+func (reader *ReaderImpl) assumeLockAndAddLine(line []byte, start int64, considerAppending bool, linePool *linePool) time.Duration {
 	// Line end
 	if len(line) > 0 && line[len(line)-1] == '\r' {
 		line = line[:len(line)-1] // Handle MSDOS line endings
@@ -219,6 +294,7 @@ func (reader *ReaderImpl) assumeLockAndAddLine(line []byte, considerAppending bo
 	if !considerAppending {
 		newLine := linePool.create(line)
 		reader.lines = append(reader.lines, newLine)
+		reader.lineStarts = append(reader.lineStarts, start)
 
 		// New line added, time for a break?
 		t0 := time.Now()
@@ -238,6 +314,7 @@ func (reader *ReaderImpl) assumeLockAndAddLine(line []byte, considerAppending bo
 
 	baseLine.raw = completeLine
 	baseLine.plainTextCache.Store(nil) // Invalidate cache
+	baseLine.highlighted.Store(nil)
 
 	return 0
 }
@@ -248,6 +325,11 @@ func (reader *ReaderImpl) assumeLockAndAddLine(line []byte, considerAppending bo
 // It is used both during the initial read of the stream until it ends, and
 // while tailing files for changes.
 func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
+	if reader.index != nil {
+		reader.consumeIndexedLines(stream)
+		return
+	}
+
 	// This value affects BenchmarkReadLargeFile() performance. Validate changes
 	// like this:
 	//
@@ -270,6 +352,7 @@ func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 				// ... and still no lines have been read, so preallocate both
 				// the lines slice...
 				reader.lines = make([]*Line, 0, lineCount)
+				reader.lineStarts = make([]int64, 0, lineCount)
 
 				// ... and the line pool.
 				linePool.pool = make([]Line, lineCount)
@@ -309,7 +392,7 @@ func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 			byteIndex += relativeNewlineLocation
 
 			considerAppending := lineStart == 0 && !reader.endsWithNewline
-			pauseDuration := reader.assumeLockAndAddLine(byteBuffer[lineStart:byteIndex], considerAppending, &linePool)
+			pauseDuration := reader.assumeLockAndAddLine(byteBuffer[lineStart:byteIndex], reader.inputBytes+int64(lineStart), considerAppending, &linePool)
 			t0 = t0.Add(pauseDuration)
 
 			lineStart = byteIndex + 1
@@ -319,11 +402,15 @@ func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 		// Handle any remaining bytes as a partial line
 		if lineStart < readBytes {
 			considerAppending := lineStart == 0 && !reader.endsWithNewline
-			pauseDuration := reader.assumeLockAndAddLine(byteBuffer[lineStart:readBytes], considerAppending, &linePool)
+			pauseDuration := reader.assumeLockAndAddLine(byteBuffer[lineStart:readBytes], reader.inputBytes+int64(lineStart), considerAppending, &linePool)
 			t0 = t0.Add(pauseDuration)
 		}
 
-		reader.endsWithNewline = inspectionReader.endedWithNewline
+		if readBytes > 0 {
+			// An empty read says nothing about how the stream ends
+			reader.endsWithNewline = inspectionReader.endedWithNewline
+		}
+		reader.inputBytes += int64(readBytes)
 
 		reader.Unlock()
 
@@ -370,7 +457,66 @@ func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 	log.Info("Stream read in ", time.Since(t0), ", have ", reader.GetLineCount(), " lines")
 }
 
-func (reader *ReaderImpl) tailFile() error {
+// Tell the user what happened to the file we're tailing, by marking the last
+// line we have so far, and make sure the next line we read starts on a new
+// line.
+//
+// Also updates the status bar text to say what happened.
+func (reader *ReaderImpl) addTailEventSeparator(event string) {
+	reader.Lock()
+	displayName := "File"
+	if reader.DisplayName != nil {
+		displayName = *reader.DisplayName
+	}
+
+	reader.tailEvent = event + " " + time.Now().Format(time.TimeOnly)
+	separator := displayName + " " + reader.tailEvent
+	if reader.index != nil {
+		reader.index.setSeparator(&separator)
+	} else if len(reader.lines) > 0 {
+		reader.lines[len(reader.lines)-1].tailEvent.Store(&separator)
+	}
+	reader.endsWithNewline = true
+	reader.Unlock()
+
+	select {
+	case reader.MoreLinesAdded <- true:
+	default:
+	}
+}
+
+// Assume write lock held. The file we're tailing was truncated or replaced, so
+// byte counts and offsets start over from the beginning of the file.
+func (reader *ReaderImpl) startOverUnlocked() {
+	reader.bytesCount = 0
+	reader.lineStarts = nil
+	reader.inputBytes = 0
+	reader.lineStartsFirst = len(reader.lines)
+}
+
+// Returns true if fileName now points to some other file than the one we have
+// open. Renamed or deleted files with no replacement yet count as not
+// replaced, we'll keep reading from those until a new file shows up.
+func fileWasReplaced(file *os.File, fileName string) bool {
+	openStats, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	pathStats, err := os.Stat(fileName)
+	if err != nil {
+		// Probably renamed, with no new file created yet
+		return false
+	}
+
+	return !os.SameFile(openStats, pathStats)
+}
+
+// If followName is true, we do what "tail -F" does and reopen the file by
+// name after rotations and truncations. Otherwise we do what "tail -f" does,
+// keep reading from the file we have open, and start over from the beginning
+// if it is truncated.
+func (reader *ReaderImpl) tailFile(followName bool) error {
 	reader.RLock()
 	fileName := reader.FileName
 	reader.RUnlock()
@@ -380,13 +526,92 @@ func (reader *ReaderImpl) tailFile() error {
 
 	log.Debugf("Tailing file %s", *fileName)
 
+	stream, _, err := ZOpen(*fileName)
+	if err != nil {
+		log.Debugf("Failed to open file %s for tailing: %s", *fileName, err.Error())
+		return nil
+	}
+
+	file, ok := stream.(*os.File)
+	if !ok {
+		err = stream.Close()
+		if err != nil {
+			log.Debugf("Giving up on tailing, failed to close non-seekable stream from %s: %s", *fileName, err.Error())
+			return nil
+		}
+		log.Debugf("Giving up on tailing, file %s is not seekable", *fileName)
+		return nil
+	}
+
+	// Once we have handed our file to the index, it's up to the index to close
+	// it
+	indexOwnsFile := false
+	defer func() {
+		if indexOwnsFile {
+			return
+		}
+
+		// Note that with followName set, this may not be the same file we
+		// started out with
+		err := file.Close()
+		if err != nil {
+			log.Debugf("Failed to close file %s after tailing: %s", *fileName, err.Error())
+		}
+	}()
+
+	reader.RLock()
+	bytesCount := reader.bytesCount
+	reader.RUnlock()
+
+	_, err = file.Seek(bytesCount, io.SeekStart)
+	if err != nil {
+		log.Debugf("Failed to seek in file %s while tailing: %s", *fileName, err.Error())
+		return nil
+	}
+
+	watcher := newFileWatcher(*fileName)
+	defer func() {
+		watcher.close()
+	}()
+
 	for {
-		// NOTE: We could use something like
-		// https://github.com/fsnotify/fsnotify instead of sleeping and polling
-		// here.
-		time.Sleep(1 * time.Second)
+		watcher.await(tailPollInterval)
+
+		if followName && fileWasReplaced(file, *fileName) {
+			// Get whatever was written to the old file before it was replaced
+			reader.consumeLinesFromStream(file)
+
+			newFile, err := os.Open(*fileName)
+			if err != nil {
+				log.Debugf("Failed to open replacement file %s, will retry: %s", *fileName, err.Error())
+				continue
+			}
+			log.Infof("File %s was replaced, following the new file", *fileName)
 
-		fileStats, err := os.Stat(*fileName)
+			if !indexOwnsFile {
+				err = file.Close()
+				if err != nil {
+					log.Debugf("Failed to close replaced file %s: %s", *fileName, err.Error())
+				}
+			}
+			file = newFile
+
+			watcher.close()
+			watcher = newFileWatcher(*fileName)
+
+			reader.addTailEventSeparator("rotated")
+
+			reader.Lock()
+			reader.startOverUnlocked()
+			if reader.index != nil {
+				// The index keeps reading the old lines from the old file
+				reader.index.startSegment(file)
+				indexOwnsFile = true
+			}
+			reader.Unlock()
+		}
+
+		fileStats, err := file.Stat()
 		if err != nil {
 			log.Debugf("Failed to stat file %s while tailing, giving up: %s", *fileName, err.Error())
 			return nil
@@ -396,53 +621,48 @@ func (reader *ReaderImpl) tailFile() error {
 		bytesCount := reader.bytesCount
 		reader.RUnlock()
 
-		if bytesCount == -1 {
-			log.Debugf("Bytes count unknown for %s, stop tailing", *fileName)
-			return nil
-		}
-
 		if fileStats.Size() == bytesCount {
 			log.Tracef("File %s unchanged at %d bytes, continue tailing", *fileName, fileStats.Size())
 			continue
 		}
 
 		if fileStats.Size() < bytesCount {
-			log.Debugf("File %s shrunk from %d to %d bytes, stop tailing",
+			log.Infof("File %s truncated from %d to %d bytes, reading it again from the start",
 				*fileName, bytesCount, fileStats.Size())
-			return nil
-		}
-
-		// File grew, read the new lines
-		stream, _, err := ZOpen(*fileName)
-		if err != nil {
-			log.Debugf("Failed to open file %s for re-reading while tailing: %s", *fileName, err.Error())
-			return nil
-		}
 
-		seekable, ok := stream.(io.ReadSeekCloser)
-		if !ok {
-			err = stream.Close()
+			_, err = file.Seek(0, io.SeekStart)
 			if err != nil {
-				log.Debugf("Giving up on tailing, failed to close non-seekable stream from %s: %s", *fileName, err.Error())
+				log.Debugf("Failed to rewind truncated file %s, giving up: %s", *fileName, err.Error())
 				return nil
 			}
-			log.Debugf("Giving up on tailing, file %s is not seekable", *fileName)
-			return nil
-		}
-		_, err = seekable.Seek(bytesCount, io.SeekStart)
-		if err != nil {
-			log.Debugf("Failed to seek in file %s while tailing: %s", *fileName, err.Error())
-			return nil
-		}
 
-		log.Tracef("File %s up from %d bytes to %d bytes, reading more lines...", *fileName, bytesCount, fileStats.Size())
+			if followName {
+				reader.addTailEventSeparator("truncated")
+			}
 
-		reader.consumeLinesFromStream(seekable)
-		err = seekable.Close()
-		if err != nil {
-			// This can lead to file handle leaks
-			return fmt.Errorf("failed to close file %s after tailing: %w", *fileName, err)
+			reader.Lock()
+			reader.startOverUnlocked()
+
+			// Don't glue the first new line onto whatever we had before
+			reader.endsWithNewline = true
+
+			if reader.index != nil {
+				// The lines we indexed are gone from the file, so the index
+				// keeps what it has in memory and starts over after that
+				reader.index.startSegment(file)
+				indexOwnsFile = true
+			}
+
+			if !followName {
+				// Just like "tail -f", no separator, but do tell the user
+				reader.tailEvent = "truncated " + time.Now().Format(time.TimeOnly)
+			}
+			reader.Unlock()
 		}
+
+		log.Tracef("File %s changed from %d bytes to %d bytes, reading more lines...", *fileName, bytesCount, fileStats.Size())
+
+		reader.consumeLinesFromStream(file)
 	}
 }
 
@@ -475,6 +695,25 @@ func NewFromStream(displayName string, reader io.Reader, formatter chroma.Format
 	return mReader, nil
 }
 
+// NewFromUncompressedStream is like NewFromStream(), but without checking the
+// stream for compression. That check blocks until the first bytes arrive, which
+// this function doesn't.
+func NewFromUncompressedStream(displayName string, reader io.Reader, formatter chroma.Formatter, options ReaderOptions) *ReaderImpl {
+	mReader := newReaderFromStream(reader, nil, formatter, options)
+
+	if len(displayName) > 0 {
+		mReader.Lock()
+		mReader.DisplayName = &displayName
+		mReader.Unlock()
+	}
+
+	if options.Style != nil {
+		mReader.SetStyleForHighlighting(*options.Style)
+	}
+
+	return mReader
+}
+
 // newReaderFromStream creates a new stream reader
 //
 // originalFileName is used for counting the lines in the file. nil for
@@ -514,6 +753,8 @@ func newReaderFromStream(reader io.Reader, originalFileName *string, formatter c
 
 		PauseStatus: &pauseStatus,
 
+		index: newFileIndex(reader),
+
 		MoreLinesAdded:          make(chan bool, 1),
 		MaybeDone:               make(chan bool, 2),
 		highlightingStyle:       make(chan chroma.Style, 1),
@@ -545,10 +786,14 @@ func newReaderFromStream(reader io.Reader, originalFileName *string, formatter c
 func NewFromTextForTesting(name string, text string) *ReaderImpl {
 	noExternalNewlines := strings.Trim(text, "\n")
 	lines := []*Line{}
+	lineStarts := []int64{}
+	var textBytes int64
 	if len(noExternalNewlines) > 0 {
 		for _, lineString := range strings.Split(noExternalNewlines, "\n") {
 			line := Line{raw: []byte(lineString)}
 			lines = append(lines, &line)
+			lineStarts = append(lineStarts, textBytes)
+			textBytes += int64(len(lineString)) + 1
 		}
 	}
 	readingDone := atomic.Bool{}
@@ -557,6 +802,8 @@ func NewFromTextForTesting(name string, text string) *ReaderImpl {
 	highlightingDone.Store(true) // No highlighting to do = nothing left = Done!
 	returnMe := &ReaderImpl{
 		lines:                   lines,
+		lineStarts:              lineStarts,
+		inputBytes:              textBytes,
 		ReadingDone:             &readingDone,
 		HighlightingDone:        &highlightingDone,
 		doneWaitingForFirstByte: make(chan bool, 1),
@@ -564,6 +811,7 @@ func NewFromTextForTesting(name string, text string) *ReaderImpl {
 	if name != "" {
 		returnMe.DisplayName = &name
 	}
+	returnMe.tailingDone.Store(true)
 
 	return returnMe
 }
@@ -700,42 +948,48 @@ func (reader *ReaderImpl) Wait() error {
 	return reader.Err
 }
 
-func textAsString(reader *ReaderImpl, shouldFormat bool) string {
+// Returns the reader contents, reformatted if we were asked to. The kind is
+// what we think the contents are, like "json" or "csv", or "" if we don't
+// know.
+func textAsString(reader *ReaderImpl, options ReaderOptions) (string, string) {
 	reader.RLock()
 
 	text := []byte{}
-	for _, line := range reader.lines {
-		text = append(text, line.raw...)
+	for i := range reader.lineCountUnlocked() {
+		text = append(text, reader.lineUnlocked(i).raw...)
 		text = append(text, '\n')
 	}
+	fileName := ""
+	if reader.FileName != nil {
+		fileName = *reader.FileName
+	}
 	reader.RUnlock()
 
-	var jsonData any
-	err := json.Unmarshal(text, &jsonData)
-	if err != nil {
-		// Not JSON, return the text as-is
-		return string(text)
+	lexerName := ""
+	if options.Lexer != nil {
+		lexerName = options.Lexer.Config().Name
 	}
 
-	if !shouldFormat {
-		log.Info("Try the --reformat flag for automatic JSON reformatting")
-		return string(text)
+	kind := reformat.Pick(options.Reformatter, lexerName, fileName, string(text))
+	if kind == "" {
+		return string(text), ""
 	}
 
-	// Pretty print the JSON
-	prettyJSON, err := json.MarshalIndent(jsonData, "", "  ")
-	if err != nil {
-		log.Debug("Failed to pretty print JSON: ", err)
-		return string(text)
+	if !options.ShouldFormat {
+		if kind == "json" {
+			log.Info("Try the --reformat flag for automatic JSON reformatting")
+		}
+		return string(text), kind
 	}
 
-	log.Debug("Got the --reformat flag, reformatted JSON input")
-	return string(prettyJSON)
-}
+	formatted, err := reformat.Reformat(kind, string(text))
+	if err != nil {
+		log.Debug("Failed to reformat input as ", kind, ": ", err)
+		return string(text), kind
+	}
 
-func isXml(text string) bool {
-	err := xml.Unmarshal([]byte(text), new(any))
-	return err == nil
+	log.Debug("Got the --reformat flag, reformatted input as ", kind)
+	return formatted, kind
 }
 
 // We expect this to be executed in a goroutine
@@ -743,59 +997,86 @@ func highlightFromMemory(reader *ReaderImpl, formatter chroma.Formatter, options
 	// Is the buffer small enough?
 	var byteCount int64
 	reader.RLock()
+	if reader.index != nil {
+		// Indexed files are way too large, and we don't want to read them
+		byteCount = reader.bytesCount
+		reader.RUnlock()
+		highlightViewport(reader, formatter, options, byteCount)
+		return
+	}
 	for _, line := range reader.lines {
 		byteCount += int64(len(line.raw))
 
 		if byteCount > MAX_HIGHLIGHT_SIZE {
-			log.Info("File too large for highlighting: ", byteCount)
 			reader.RUnlock()
+			highlightViewport(reader, formatter, options, byteCount)
 			return
 		}
 	}
 	reader.RUnlock()
 
-	text := textAsString(reader, options.ShouldFormat)
+	text, kind := textAsString(reader, options)
 
 	if len(text) == 0 {
 		log.Debug("Buffer is empty, not highlighting")
 		return
 	}
 
-	if options.Lexer == nil && json.Valid([]byte(text)) {
-		log.Info("Buffer is valid JSON, highlighting as JSON")
-		options.Lexer = lexers.Get("json")
-	} else if options.Lexer == nil && isXml(text) {
-		log.Info("Buffer is valid XML, highlighting as XML")
-		options.Lexer = lexers.Get("xml")
+	if options.Lexer == nil && kind != "" {
+		log.Info("Buffer looks like ", kind, ", highlighting as ", kind)
+		options.Lexer = lexers.Get(kind)
+	}
+
+	highlighted := highlightText(text, formatter, options)
+	if highlighted != nil {
+		reader.setText(*highlighted)
+		return
+	}
+
+	if options.ShouldFormat && kind != "" {
+		// Not highlighted, but maybe reformatted
+		reader.setText(text)
 	}
+}
 
+// Returns nil if no highlighting was done
+func highlightText(text string, formatter chroma.Formatter, options ReaderOptions) *string {
 	if options.Lexer == nil {
 		log.Debug("No lexer set, not highlighting")
-		return
+		return nil
 	}
 
 	if options.Style == nil {
 		log.Debug("No style set, not highlighting")
-		return
+		return nil
 	}
 
 	if formatter == nil {
 		log.Debug("No formatter set, not highlighting")
-		return
+		return nil
 	}
 
 	highlighted, err := Highlight(text, *options.Style, formatter, options.Lexer)
 	if err != nil {
 		log.Warn("Highlighting failed: ", err)
-		return
+		return nil
 	}
 
-	if highlighted == nil {
-		// No highlighting would be done, never mind
+	return highlighted
+}
+
+// Too large for highlighting all of it, highlight what's on screen instead.
+// See HighlightVisible().
+func highlightViewport(reader *ReaderImpl, formatter chroma.Formatter, options ReaderOptions, byteCount int64) {
+	if options.Lexer == nil || options.Lexer.Config().Name == "plaintext" || options.Style == nil || formatter == nil {
+		log.Info("File too large for highlighting: ", byteCount)
 		return
 	}
 
-	reader.setText(*highlighted)
+	log.Info("File too large for highlighting all at once, highlighting what's on screen: ", byteCount)
+	reader.Lock()
+	reader.viewportHighlighter = newViewportHighlighter(reader, *options.Style, formatter, options.Lexer)
+	reader.Unlock()
 }
 
 // createStatusUnlocked() assumes that its caller is holding the read lock
@@ -805,7 +1086,8 @@ func (reader *ReaderImpl) createStatusUnlocked(lastLine linemetadata.Index) (str
 		displayName = *reader.DisplayName
 	}
 
-	if len(reader.lines) == 0 {
+	lineCount := reader.lineCountUnlocked()
+	if lineCount == 0 {
 		empty := "<empty>"
 		if len(displayName) > 0 {
 			return displayName, ": " + empty
@@ -815,13 +1097,13 @@ func (reader *ReaderImpl) createStatusUnlocked(lastLine linemetadata.Index) (str
 
 	linesCount := ""
 	percent := ""
-	if len(reader.lines) == 1 {
+	if lineCount == 1 {
 		linesCount = "1 line"
 		percent = "100%"
 	} else {
 		// More than one line
-		linesCount = util.FormatInt(len(reader.lines)) + " lines"
-		percent = fmt.Sprintf("%.0f%%", math.Floor(100*float64(lastLine.Index()+1)/float64(len(reader.lines))))
+		linesCount = util.FormatInt(lineCount) + " lines"
+		percent = fmt.Sprintf("%.0f%%", math.Floor(100*float64(lastLine.Index()+1)/float64(lineCount)))
 	}
 
 	if !reader.ShouldShowLineCount() {
@@ -844,6 +1126,13 @@ func (reader *ReaderImpl) createStatusUnlocked(lastLine linemetadata.Index) (str
 		return_me += percent
 	}
 
+	if len(reader.tailEvent) > 0 {
+		if len(return_me) > 0 {
+			return_me += "  "
+		}
+		return_me += reader.tailEvent
+	}
+
 	if len(displayName) > 0 {
 		return displayName, return_me
 	}
@@ -864,9 +1153,34 @@ func (reader *ReaderImpl) GetLineCount() int {
 	reader.RLock()
 	defer reader.RUnlock()
 
+	return reader.lineCountUnlocked()
+}
+
+// Assumes the reader lock is held
+func (reader *ReaderImpl) lineCountUnlocked() int {
+	if reader.index != nil {
+		return reader.index.lineCount()
+	}
 	return len(reader.lines)
 }
 
+// Assumes the reader lock is held, and that the index is within bounds. For
+// indexed files, this may read the line from disk.
+func (reader *ReaderImpl) lineUnlocked(index int) *Line {
+	if reader.index != nil {
+		return reader.index.line(index)
+	}
+	return reader.lines[index]
+}
+
+// Like lineUnlocked(), but returns nil rather than reading from disk
+func (reader *ReaderImpl) loadedLineUnlocked(index int) *Line {
+	if reader.index != nil {
+		return reader.index.cachedLine(index)
+	}
+	return reader.lines[index]
+}
+
 func (reader *ReaderImpl) ShouldShowLineCount() bool {
 	if reader.ReadingDone.Load() {
 		// We are done, the number won't change, show it!
@@ -903,6 +1217,12 @@ func (line *Line) Plain(index linemetadata.Index) string {
 	return plain
 }
 
+// The line as it was read, including any ANSI escape codes. Don't modify the
+// returned slice.
+func (line *Line) Raw() []byte {
+	return line.raw
+}
+
 // GetLine gets a line. If the requested line number is out of bounds, nil is returned.
 func (reader *ReaderImpl) GetLine(index linemetadata.Index) *NumberedLine {
 	reader.RLock()
@@ -929,12 +1249,12 @@ func (reader *ReaderImpl) GetLine(index linemetadata.Index) *NumberedLine {
 		reader.RLock()
 	}
 
-	if !index.IsWithinLength(len(reader.lines)) {
+	if !index.IsWithinLength(reader.lineCountUnlocked()) {
 		reader.RUnlock()
 		return nil
 	}
 
-	returnLine := reader.lines[index.Index()]
+	returnLine := reader.lineUnlocked(index.Index())
 	reader.RUnlock()
 
 	return &NumberedLine{
@@ -983,7 +1303,7 @@ func clipRangeToLength(start linemetadata.Index, wantedCount int, maxIndex int)
 // GetLines gets the indicated lines from the input
 func (reader *ReaderImpl) GetLines(firstLine linemetadata.Index, wantedLineCount int) InputLines {
 	reader.RLock()
-	lineCount := len(reader.lines)
+	lineCount := reader.lineCountUnlocked()
 	if lineCount == 0 || wantedLineCount == 0 {
 		filenameText, statusText := reader.createStatusUnlocked(firstLine)
 		reader.RUnlock()
@@ -1019,7 +1339,8 @@ func (reader *ReaderImpl) GetLinesPreallocated(firstLine linemetadata.Index, res
 
 	reader.RLock()
 
-	if len(reader.lines) == 0 || cap(*resultLines) == 0 {
+	lineCount := reader.lineCountUnlocked()
+	if lineCount == 0 || cap(*resultLines) == 0 {
 		filenameText, statusText := reader.createStatusUnlocked(firstLine)
 		reader.RUnlock()
 
@@ -1027,15 +1348,15 @@ func (reader *ReaderImpl) GetLinesPreallocated(firstLine linemetadata.Index, res
 	}
 
 	// Prevent reading past the end of the available lines
-	firstLineIndex, lastLineIndex := clipRangeToLength(firstLine, cap(*resultLines), len(reader.lines)-1)
+	firstLineIndex, lastLineIndex := clipRangeToLength(firstLine, cap(*resultLines), lineCount-1)
 
 	filenameText, statusText := reader.createStatusUnlocked(linemetadata.IndexFromZeroBased(lastLineIndex))
 
-	for loopIndex, returnLine := range reader.lines[firstLineIndex : lastLineIndex+1] {
+	for lineIndex := firstLineIndex; lineIndex <= lastLineIndex; lineIndex++ {
 		*resultLines = append(*resultLines, NumberedLine{
-			Index:  linemetadata.IndexFromZeroBased(firstLineIndex + loopIndex),
-			Number: linemetadata.NumberFromZeroBased(firstLineIndex + loopIndex),
-			Line:   returnLine,
+			Index:  linemetadata.IndexFromZeroBased(lineIndex),
+			Number: linemetadata.NumberFromZeroBased(lineIndex),
+			Line:   reader.lineUnlocked(lineIndex),
 		})
 	}
 
@@ -1110,6 +1431,13 @@ func (reader *ReaderImpl) setText(text string) {
 
 	reader.Lock()
 	reader.lines = lines
+	if reader.lineStartsFirst+len(reader.lineStarts) != len(lines) {
+		// Not just highlighted but new text, so the lines start elsewhere
+		reader.lineStarts = reader.lineStarts[:0]
+		reader.inputBytes = 0
+		reader.lineStartsFirst = 0
+		reader.addLineStartsUnlocked()
+	}
 	reader.Unlock()
 
 	log.Trace("Reader done, contents explicitly set")
@@ -1120,6 +1448,18 @@ func (reader *ReaderImpl) setText(text string) {
 	}
 }
 
+// Assume write lock held. Compute line starts for any lines we don't have them
+// for, for inputs that don't come in bytes, like text or merged readers.
+func (reader *ReaderImpl) addLineStartsUnlocked() {
+	for i := reader.lineStartsFirst + len(reader.lineStarts); i < len(reader.lines); i++ {
+		reader.lineStarts = append(reader.lineStarts, reader.inputBytes)
+
+		// Highlighting adds escape codes to the lines, so count the plain text
+		plain := reader.lines[i].Plain(linemetadata.IndexFromZeroBased(i))
+		reader.inputBytes += int64(len(plain)) + 1
+	}
+}
+
 func (reader *ReaderImpl) setPauseStatus(paused bool) {
 	if !reader.PauseStatus.CompareAndSwap(!paused, paused) {
 		// Pause status already had that value, we're done
@@ -1129,6 +1469,14 @@ func (reader *ReaderImpl) setPauseStatus(paused bool) {
 	log.Debugf("Reader pause status changed to %t", paused)
 }
 
+// PauseAfterLines returns how many lines we'll read before pausing, see
+// SetPauseAfterLines().
+func (reader *ReaderImpl) PauseAfterLines() int {
+	reader.RLock()
+	defer reader.RUnlock()
+	return reader.pauseAfterLines
+}
+
 func (reader *ReaderImpl) SetPauseAfterLines(lines int) {
 	if lines < 0 {
 		log.Warnf("Tried to set pause-after-lines to %d, ignoring", lines)
@@ -364,6 +364,42 @@ func TestFormatJsonArray(t *testing.T) {
 	assert.Equal(t, len(lines.Lines), 5)
 }
 
+func TestFormatCsvWithoutLexer(t *testing.T) {
+	testMe, err := NewFromStream(
+		"CSV test",
+		strings.NewReader("name,count\nkalle,1\n"),
+		formatters.TTY,
+		ReaderOptions{
+			Style:        styles.Get("native"),
+			ShouldFormat: true,
+			Reformatter:  "csv",
+		})
+	assert.NilError(t, err)
+
+	assert.NilError(t, testMe.Wait())
+
+	lines := testMe.GetLines(linemetadata.Index{}, 10)
+	assert.Equal(t, lines.Lines[0].Plain(), "name   count")
+	assert.Equal(t, lines.Lines[1].Plain(), "kalle  1")
+	assert.Equal(t, len(lines.Lines), 2)
+}
+
+func TestFormatXmlNeedsTheFlag(t *testing.T) {
+	xmlStream := strings.NewReader("<a><b>text</b></a>")
+	testMe, err := NewFromStream(
+		"XML test",
+		xmlStream,
+		formatters.TTY,
+		ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+
+	assert.NilError(t, testMe.Wait())
+
+	lines := testMe.GetLines(linemetadata.Index{}, 10)
+	assert.Equal(t, lines.Lines[0].Plain(), "<a><b>text</b></a>")
+	assert.Equal(t, len(lines.Lines), 1)
+}
+
 // If people keep appending to the currently opened file we should display those
 // changes.
 func TestReadUpdatingFile(t *testing.T) {
@@ -569,6 +605,94 @@ func TestReadUpdatingFile_HalfUtf8(t *testing.T) {
 	assert.Equal(t, int(testMe.bytesCount), len([]byte("här")))
 }
 
+// If a file we are tailing gets truncated we should start over from the
+// beginning of the file, just like "tail -f".
+func TestReadUpdatingFile_Truncated(t *testing.T) {
+	file, err := os.CreateTemp("", "moor-TestReadUpdatingFile_Truncated-*.txt")
+	assert.NilError(t, err)
+	defer os.Remove(file.Name()) //nolint:errcheck
+
+	_, err = file.WriteString("First line\nSecond line\n")
+	assert.NilError(t, err)
+
+	// Start a reader on that file
+	testMe, err := NewFromFilename(file.Name(), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+
+	// Wait for the reader to finish reading
+	assert.NilError(t, testMe.Wait())
+	assert.Equal(t, testMe.GetLineCount(), 2)
+
+	// Truncate the file and write something shorter into it
+	assert.NilError(t, file.Truncate(0))
+	_, err = file.WriteAt([]byte("New\n"), 0)
+	assert.NilError(t, err)
+
+	// Give the reader some time to react
+	for range 30 {
+		allLines := testMe.GetLines(linemetadata.Index{}, 10)
+		if len(allLines.Lines) == 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The old lines should still be there, with the new one after them
+	allLines := testMe.GetLines(linemetadata.Index{}, 10)
+	assert.Equal(t, len(allLines.Lines), 3, "Expected three lines after truncation, got %d", len(allLines.Lines))
+	assert.Equal(t, allLines.Lines[0].Plain(), "First line")
+	assert.Equal(t, allLines.Lines[1].Plain(), "Second line")
+	assert.Equal(t, allLines.Lines[2].Plain(), "New")
+
+	testMe.RLock()
+	assert.Equal(t, int(testMe.bytesCount), len([]byte("New\n")))
+	testMe.RUnlock()
+
+	// Byte offsets are into the file as it is now
+	index, exact := testMe.LineAtByteOffset(2)
+	assert.Equal(t, index.Index(), 2)
+	assert.Assert(t, exact)
+}
+
+// With FollowName set, a rotated file should be reopened by name, with a
+// separator between the old and the new contents.
+func TestReadUpdatingFile_FollowNameRotated(t *testing.T) {
+	dir := t.TempDir()
+	fileName := path.Join(dir, "app.log")
+	assert.NilError(t, os.WriteFile(fileName, []byte("Old file\n"), 0o600))
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{
+		Style:      styles.Get("native"),
+		FollowName: true,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	assert.Equal(t, testMe.GetLineCount(), 1)
+
+	// Rotate the log file
+	assert.NilError(t, os.Rename(fileName, fileName+".1"))
+	assert.NilError(t, os.WriteFile(fileName, []byte("New file\n"), 0o600))
+
+	// Give the reader some time to react
+	for range 30 {
+		allLines := testMe.GetLines(linemetadata.Index{}, 10)
+		if len(allLines.Lines) == 2 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The separator isn't a line of its own, so the line numbers stay the same
+	allLines := testMe.GetLines(linemetadata.Index{}, 10)
+	assert.Equal(t, len(allLines.Lines), 2, "Expected old line and new line, got %d lines", len(allLines.Lines))
+	assert.Equal(t, allLines.Lines[0].Plain(), "Old file")
+	assert.Assert(t, strings.HasPrefix(allLines.Lines[0].Line.TailEvent(), "app.log rotated "), allLines.Lines[0].Line.TailEvent())
+	assert.Equal(t, allLines.Lines[1].Plain(), "New file")
+	assert.Equal(t, allLines.Lines[1].Line.TailEvent(), "")
+
+	assert.Assert(t, strings.Contains(allLines.StatusText, "rotated "), allLines.StatusText)
+}
+
 func TestClipRangeToLength(t *testing.T) {
 	// Within bounds
 	i0, i1 := clipRangeToLength(linemetadata.Index{}, 1, 20)
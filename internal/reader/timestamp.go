@@ -0,0 +1,58 @@
+package reader
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Matches ISO 8601 style timestamps at the start of a line, optionally in
+// square brackets. Examples:
+//
+//	2024-01-02T15:04:05Z
+//	2024-01-02 15:04:05,123
+//	[2024-01-02T15:04:05.123456+01:00]
+var isoTimestampRegex = regexp.MustCompile(
+	`^\[?(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?)(Z|[+-]\d{2}:?\d{2})?`)
+
+// Matches syslog style timestamps at the start of a line. These have no year.
+// Example:
+//
+//	Jan  2 15:04:05
+var syslogTimestampRegex = regexp.MustCompile(
+	`^\[?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2})`)
+
+// Parse a timestamp from the start of a (plain text) line.
+//
+// Returns false if the line doesn't start with a timestamp we recognize.
+// Timestamps without time zone information are assumed to be in UTC.
+func ParseLeadingTimestamp(line string) (time.Time, bool) {
+	if match := isoTimestampRegex.FindStringSubmatch(line); match != nil {
+		dateTime := strings.Replace(match[1], ",", ".", 1)
+		dateTime = strings.Replace(dateTime, " ", "T", 1)
+
+		zone := match[2]
+		if zone == "" {
+			zone = "Z"
+		} else if zone != "Z" && !strings.Contains(zone, ":") {
+			// "+0100" -> "+01:00"
+			zone = zone[:3] + ":" + zone[3:]
+		}
+
+		parsed, err := time.Parse(time.RFC3339Nano, dateTime+zone)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return parsed, true
+	}
+
+	if match := syslogTimestampRegex.FindStringSubmatch(line); match != nil {
+		parsed, err := time.Parse(time.Stamp, match[1])
+		if err != nil {
+			return time.Time{}, false
+		}
+		return parsed, true
+	}
+
+	return time.Time{}, false
+}
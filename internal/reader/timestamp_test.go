@@ -0,0 +1,44 @@
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func assertTimestamp(t *testing.T, line string, expected time.Time) {
+	t.Helper()
+
+	parsed, ok := ParseLeadingTimestamp(line)
+	assert.Assert(t, ok, "Expected a timestamp in: %q", line)
+	assert.Assert(t, parsed.Equal(expected), "Expected %s, got %s from %q", expected, parsed, line)
+}
+
+func TestParseLeadingTimestamp(t *testing.T) {
+	base := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	assertTimestamp(t, "2024-01-02T15:04:05Z Hello", base)
+	assertTimestamp(t, "2024-01-02 15:04:05 INFO Hello", base)
+	assertTimestamp(t, "[2024-01-02 15:04:05] Hello", base)
+	assertTimestamp(t, "2024-01-02 15:04:05,123 Hello", base.Add(123*time.Millisecond))
+	assertTimestamp(t, "2024-01-02T15:04:05.5Z", base.Add(500*time.Millisecond))
+	assertTimestamp(t, "2024-01-02T16:04:05+01:00 Hello", base)
+	assertTimestamp(t, "2024-01-02T16:04:05+0100 Hello", base)
+
+	assertTimestamp(t, "Jan  2 15:04:05 myhost sshd[123]: Hello",
+		time.Date(0, 1, 2, 15, 4, 5, 0, time.UTC))
+}
+
+func TestParseLeadingTimestamp_NoTimestamp(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"Hello",
+		"  at com.example.Main(Main.java:12)",
+		"Hello 2024-01-02T15:04:05Z",
+		"2024-01-02",
+	} {
+		_, ok := ParseLeadingTimestamp(line)
+		assert.Assert(t, !ok, "Expected no timestamp in: %q", line)
+	}
+}
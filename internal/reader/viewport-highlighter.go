@@ -0,0 +1,259 @@
+package reader
+
+import (
+	"bytes"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// Inputs larger than MAX_HIGHLIGHT_SIZE are highlighted in chunks of this many
+// lines, around what's on screen
+const highlightChunkSize = 500
+
+// Lexed before each chunk and then thrown away. This gives the lexer a chance
+// to find out whether the chunk starts inside of a comment or a string.
+const highlightChunkLeadIn = 100
+
+// Older chunks go back to being unhighlighted, so that memory usage stays
+// bounded no matter how large the input is
+const maxHighlightedChunks = 20
+
+// Highlights the parts of large inputs that are on screen, see
+// ReaderImpl.HighlightVisible().
+type viewportHighlighter struct {
+	reader *ReaderImpl
+
+	style     chroma.Style
+	formatter chroma.Formatter
+	lexer     chroma.Lexer
+
+	lock sync.Mutex
+
+	// Chunk numbers, least recently used first
+	highlighted []int
+
+	// How many lines each highlighted chunk had when it was highlighted. Chunks
+	// at the end of the input get highlighted again when they grow.
+	highlightedLineCounts map[int]int
+
+	// Chunk numbers the UI wants highlighted, most important first
+	wanted []int
+
+	// Started when the UI wants something highlighted, exits when there's
+	// nothing more to do
+	workerRunning bool
+
+	// highlightChunkSize and maxHighlightedChunks, except in tests
+	chunkSize int
+	maxChunks int
+}
+
+func newViewportHighlighter(reader *ReaderImpl, style chroma.Style, formatter chroma.Formatter, lexer chroma.Lexer) *viewportHighlighter {
+	return &viewportHighlighter{
+		reader:                reader,
+		style:                 style,
+		formatter:             formatter,
+		lexer:                 lexer,
+		highlightedLineCounts: map[int]int{},
+		chunkSize:             highlightChunkSize,
+		maxChunks:             maxHighlightedChunks,
+	}
+}
+
+// Highlight the chunks holding these lines, plus one chunk on each side so
+// that scrolling a bit doesn't show uncolored lines. Returns immediately, the
+// highlighting is done in the background.
+//
+// The lines are expected in order. They may be far apart when filtering, so
+// only the chunks actually holding them are highlighted.
+//
+// This does nothing unless the input was too large for highlighting all of
+// it.
+func (reader *ReaderImpl) HighlightVisible(visible []linemetadata.Index) {
+	reader.RLock()
+	highlighter := reader.viewportHighlighter
+	reader.RUnlock()
+	if highlighter == nil || len(visible) == 0 {
+		return
+	}
+
+	chunkSize := highlighter.chunkSize
+
+	// Visible chunks first, then the ones around them
+	wanted := []int{}
+	addChunk := func(chunk int) {
+		if chunk >= 0 && !slices.Contains(wanted, chunk) {
+			wanted = append(wanted, chunk)
+		}
+	}
+	for _, line := range visible {
+		addChunk(line.Index() / chunkSize)
+	}
+	addChunk(visible[0].Index()/chunkSize - 1)
+	addChunk(visible[len(visible)-1].Index()/chunkSize + 1)
+
+	// Wanting more than we can keep would make the chunks evict each other
+	// forever
+	wanted = wanted[:min(len(wanted), highlighter.maxChunks)]
+
+	highlighter.lock.Lock()
+	defer highlighter.lock.Unlock()
+
+	highlighter.wanted = wanted
+	if highlighter.workerRunning {
+		// It will find our new wishes in nextChunk()
+		return
+	}
+
+	highlighter.workerRunning = true
+	go func() {
+		defer func() {
+			PanicHandler("viewportHighlighter.work()", recover(), debug.Stack())
+		}()
+
+		highlighter.work()
+	}()
+}
+
+// Highlight wanted chunks until there are no more
+func (h *viewportHighlighter) work() {
+	for {
+		chunk, found := h.nextChunk()
+		if !found {
+			// HighlightVisible() will start a new worker when needed
+			return
+		}
+
+		h.highlightChunk(chunk)
+	}
+}
+
+// The first wanted chunk that isn't highlighted, or is but has grown since. If
+// there is none, this marks the worker as not running.
+func (h *viewportHighlighter) nextChunk() (int, bool) {
+	lineCount := h.reader.GetLineCount()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, chunk := range h.wanted {
+		chunkLineCount := min(h.chunkSize, lineCount-chunk*h.chunkSize)
+		if chunkLineCount <= 0 {
+			// Past the end of the input
+			continue
+		}
+
+		if h.highlightedLineCounts[chunk] < chunkLineCount {
+			return chunk, true
+		}
+
+		if !slices.Contains(h.highlighted, chunk) {
+			// Highlighting this chunk failed, don't try again
+			continue
+		}
+
+		if h.lostHighlighting(chunk) {
+			return chunk, true
+		}
+
+		// Recently used, don't evict this one
+		h.highlighted = slices.DeleteFunc(h.highlighted, func(c int) bool { return c == chunk })
+		h.highlighted = append(h.highlighted, chunk)
+	}
+
+	h.workerRunning = false
+	return 0, false
+}
+
+// Indexed files drop lines from memory, and their highlighting with them
+func (h *viewportHighlighter) lostHighlighting(chunk int) bool {
+	h.reader.RLock()
+	defer h.reader.RUnlock()
+
+	if h.reader.index == nil {
+		return false
+	}
+	return h.reader.lineUnlocked(chunk*h.chunkSize).highlighted.Load() == nil
+}
+
+func (h *viewportHighlighter) highlightChunk(chunk int) {
+	// Grab the raw lines of the chunk, and the lead-in
+	h.reader.RLock()
+	start := chunk * h.chunkSize
+	end := min(start+h.chunkSize, h.reader.lineCountUnlocked())
+	leadInStart := max(0, start-highlightChunkLeadIn)
+	lines := make([]*Line, 0, end-leadInStart)
+	var text bytes.Buffer
+	for i := leadInStart; i < end; i++ {
+		line := h.reader.lineUnlocked(i)
+		lines = append(lines, line)
+		text.Write(line.raw)
+		text.WriteByte('\n')
+	}
+	h.reader.RUnlock()
+
+	h.lock.Lock()
+	h.highlightedLineCounts[chunk] = end - start
+	h.lock.Unlock()
+
+	highlighted, err := Highlight(text.String(), h.style, h.formatter, h.lexer)
+	if err != nil {
+		log.Debugf("Highlighting chunk %d failed: %v", chunk, err)
+		return
+	}
+	if highlighted == nil {
+		// Nothing to highlight
+		return
+	}
+
+	highlightedLines := strings.Split(*highlighted, "\n")
+	if len(highlightedLines) < len(lines) {
+		log.Debugf("Highlighting chunk %d returned %d lines, expected %d", chunk, len(highlightedLines), len(lines))
+		return
+	}
+
+	for i := start - leadInStart; i < len(lines); i++ {
+		highlightedLine := []byte(highlightedLines[i])
+		lines[i].highlighted.Store(&highlightedLine)
+	}
+
+	h.lock.Lock()
+	h.highlighted = slices.DeleteFunc(h.highlighted, func(c int) bool { return c == chunk })
+	h.highlighted = append(h.highlighted, chunk)
+	evicted := []int{}
+	for len(h.highlighted) > h.maxChunks {
+		evicted = append(evicted, h.highlighted[0])
+		delete(h.highlightedLineCounts, h.highlighted[0])
+		h.highlighted = h.highlighted[1:]
+	}
+	h.lock.Unlock()
+
+	h.reader.RLock()
+	for _, evictedChunk := range evicted {
+		evictedStart := evictedChunk * h.chunkSize
+		evictedEnd := min(evictedStart+h.chunkSize, h.reader.lineCountUnlocked())
+		for i := evictedStart; i < evictedEnd; i++ {
+			line := h.reader.loadedLineUnlocked(i)
+			if line == nil {
+				// Indexed line not in memory, so not highlighted either
+				continue
+			}
+			line.highlighted.Store(nil)
+		}
+	}
+	h.reader.RUnlock()
+
+	log.Tracef("Highlighted chunk %d, lines %d-%d", chunk, start, end-1)
+
+	// Tell the UI to redraw
+	select {
+	case h.reader.MoreLinesAdded <- true:
+	default:
+	}
+}
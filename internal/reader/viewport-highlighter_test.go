@@ -0,0 +1,126 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+// Small chunks keep the tests fast, especially with -race
+const testChunkSize = 10
+const testMaxChunks = 3
+
+// One Go line per chunk line, for chunkCount chunks
+func newViewportHighlighterTestReader(chunkCount int) *ReaderImpl {
+	lines := []string{}
+	for range chunkCount * testChunkSize {
+		lines = append(lines, `var x = "hello"`)
+	}
+	reader := NewFromTextForTesting("test.go", strings.Join(lines, "\n"))
+	reader.viewportHighlighter = newViewportHighlighter(reader, *styles.Get("native"), formatters.TTY16m, lexers.Get("go"))
+	reader.viewportHighlighter.chunkSize = testChunkSize
+	reader.viewportHighlighter.maxChunks = testMaxChunks
+	return reader
+}
+
+func isHighlighted(reader *ReaderImpl, index int) bool {
+	reader.RLock()
+	defer reader.RUnlock()
+	return reader.lines[index].highlighted.Load() != nil
+}
+
+func isWorkerRunning(reader *ReaderImpl) bool {
+	reader.viewportHighlighter.lock.Lock()
+	defer reader.viewportHighlighter.lock.Unlock()
+	return reader.viewportHighlighter.workerRunning
+}
+
+func TestHighlightVisible(t *testing.T) {
+	reader := newViewportHighlighterTestReader(10)
+
+	// Line 52 is in chunk 5, so chunks 4-6 should get highlighted
+	reader.HighlightVisible([]linemetadata.Index{linemetadata.IndexFromZeroBased(52), linemetadata.IndexFromZeroBased(53)})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for isWorkerRunning(reader) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for highlighting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Assert(t, isHighlighted(reader, 4*testChunkSize))
+	assert.Assert(t, isHighlighted(reader, 7*testChunkSize-1))
+	assert.Assert(t, !isHighlighted(reader, 4*testChunkSize-1))
+	assert.Assert(t, !isHighlighted(reader, 7*testChunkSize))
+
+	line := reader.GetLine(linemetadata.IndexFromZeroBased(52))
+	assert.Equal(t, line.Plain(), `var x = "hello"`, "Highlighting should not change the text")
+
+	styled := line.HighlightedTokens(twin.StyleDefault, twin.StyleDefault, search.Search{}, nil, 0).StyledRunes
+	assert.Assert(t, !styled[0].Style.Equal(twin.StyleDefault), "Keywords should be highlighted")
+
+	// The worker is done, but should start again when we scroll
+	reader.HighlightVisible([]linemetadata.Index{linemetadata.IndexFromZeroBased(92), linemetadata.IndexFromZeroBased(93)})
+	for !isHighlighted(reader, 9*testChunkSize) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for highlighting after scrolling")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Filtered lines can be far apart. Only their chunks should be highlighted, and
+// no more of them than we can keep.
+func TestHighlightVisibleFiltered(t *testing.T) {
+	reader := newViewportHighlighterTestReader(10)
+
+	visible := []linemetadata.Index{}
+	for _, line := range []int{5, 25, 55, 85} {
+		visible = append(visible, linemetadata.IndexFromZeroBased(line))
+	}
+	reader.HighlightVisible(visible)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for isWorkerRunning(reader) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the worker to stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Assert(t, isHighlighted(reader, 5))
+	assert.Assert(t, isHighlighted(reader, 25))
+	assert.Assert(t, isHighlighted(reader, 55))
+	assert.Assert(t, !isHighlighted(reader, 15), "Chunks between visible lines should be left alone")
+	assert.Assert(t, !isHighlighted(reader, 85), "More chunks than we can keep should be skipped")
+}
+
+func TestHighlightVisibleEviction(t *testing.T) {
+	reader := newViewportHighlighterTestReader(testMaxChunks + 2)
+	highlighter := reader.viewportHighlighter
+
+	for chunk := range testMaxChunks + 1 {
+		highlighter.highlightChunk(chunk)
+	}
+
+	assert.Assert(t, !isHighlighted(reader, 0), "Oldest chunk should have been evicted")
+	assert.Assert(t, isHighlighted(reader, testChunkSize))
+	assert.Assert(t, isHighlighted(reader, testMaxChunks*testChunkSize))
+	assert.Equal(t, len(highlighter.highlighted), testMaxChunks)
+}
+
+func TestHighlightVisibleSmallInput(t *testing.T) {
+	// No viewport highlighter, this should do nothing
+	reader := NewFromTextForTesting("test.go", `var x = "hello"`)
+	reader.HighlightVisible([]linemetadata.Index{{}})
+	assert.Assert(t, !isHighlighted(reader, 0))
+}
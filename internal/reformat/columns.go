@@ -0,0 +1,67 @@
+// CSV and TSV aligned into columns
+
+package reformat
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+const columnSeparator = "  "
+
+func formatCSV(text string) (string, error) {
+	return alignColumns(text, ',')
+}
+
+func formatTSV(text string) (string, error) {
+	return alignColumns(text, '\t')
+}
+
+func alignColumns(text string, separator rune) (string, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1 // Rows may have different lengths
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", err
+	}
+
+	widths := []int{}
+	for _, record := range records {
+		for i, field := range record {
+			// Multi line values would mess up the layout
+			field = strings.Join(strings.Fields(field), " ")
+			record[i] = field
+
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], uniseg.StringWidth(field))
+		}
+	}
+
+	if len(widths) < 2 {
+		return "", errors.New("Need at least two columns")
+	}
+
+	result := strings.Builder{}
+	for _, record := range records {
+		line := strings.Builder{}
+		for i, field := range record {
+			line.WriteString(field)
+			if i < len(record)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-uniseg.StringWidth(field)))
+				line.WriteString(columnSeparator)
+			}
+		}
+		result.WriteString(strings.TrimRight(line.String(), " "))
+		result.WriteByte('\n')
+	}
+
+	return result.String(), nil
+}
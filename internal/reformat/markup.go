@@ -0,0 +1,84 @@
+// JSON and XML formatting
+
+package reformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+func formatJSON(text string) (string, error) {
+	var jsonData any
+	err := json.Unmarshal([]byte(text), &jsonData)
+	if err != nil {
+		return "", err
+	}
+
+	prettyJSON, err := json.MarshalIndent(jsonData, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(prettyJSON), nil
+}
+
+// Prefixed names as they were written, rather than with their namespace URLs
+func rawName(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
+
+func formatXML(text string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(text))
+
+	var result strings.Builder
+	encoder := xml.NewEncoder(&result)
+	encoder.Indent("", "  ")
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch typed := token.(type) {
+		case xml.CharData:
+			trimmed := bytes.TrimSpace(typed)
+			if len(trimmed) == 0 {
+				// Old indentation, we'll make our own
+				continue
+			}
+			token = xml.CharData(trimmed)
+
+		case xml.StartElement:
+			attributes := make([]xml.Attr, len(typed.Attr))
+			for i, attribute := range typed.Attr {
+				attributes[i] = xml.Attr{Name: rawName(attribute.Name), Value: attribute.Value}
+			}
+			token = xml.StartElement{Name: rawName(typed.Name), Attr: attributes}
+
+		case xml.EndElement:
+			token = xml.EndElement{Name: rawName(typed.Name)}
+		}
+
+		err = encoder.EncodeToken(token)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := encoder.Flush()
+	if err != nil {
+		return "", err
+	}
+
+	return result.String(), nil
+}
@@ -0,0 +1,191 @@
+// Minified JavaScript and CSS, expanded into one statement or declaration per
+// line
+
+package reformat
+
+import (
+	"strings"
+)
+
+// Lines shorter than this are assumed to be formatted already
+const minifiedLineLength = 300
+
+func formatJS(text string) (string, error) {
+	return expandMinified(text, true), nil
+}
+
+func formatCSS(text string) (string, error) {
+	return expandMinified(text, false), nil
+}
+
+func expandMinified(text string, isJS bool) string {
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if len(line) < minifiedLineLength {
+			result = append(result, line)
+			continue
+		}
+		expander := minifiedExpander{input: line, isJS: isJS}
+		result = append(result, expander.expand()...)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+type minifiedExpander struct {
+	input string
+	isJS  bool
+
+	position   int
+	depth      int // Of {} blocks
+	parenDepth int // Inside of (), we don't break after ;
+
+	lines   []string
+	current strings.Builder
+}
+
+func (e *minifiedExpander) newline() {
+	line := strings.TrimRight(e.current.String(), " \t")
+	e.current.Reset()
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	e.lines = append(e.lines, line)
+}
+
+func (e *minifiedExpander) write(s string) {
+	if e.current.Len() == 0 {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return
+		}
+		e.current.WriteString(strings.Repeat("  ", e.depth))
+	}
+	e.current.WriteString(s)
+}
+
+// The last non-blank character written on the current line, or 0 if there is
+// none
+func (e *minifiedExpander) lastWritten() byte {
+	line := strings.TrimRight(e.current.String(), " \t")
+	if line == "" {
+		return 0
+	}
+	return line[len(line)-1]
+}
+
+// The rest of the input after the current position, without leading blanks
+func (e *minifiedExpander) lookahead() string {
+	return strings.TrimLeft(e.input[e.position+1:], " \t")
+}
+
+func (e *minifiedExpander) expand() []string {
+	for e.position = 0; e.position < len(e.input); e.position++ {
+		char := e.input[e.position]
+		switch {
+		case char == '"' || char == '\'' || (char == '`' && e.isJS):
+			e.copyQuoted(char)
+
+		case strings.HasPrefix(e.input[e.position:], "/*"):
+			e.copyUntil("*/")
+
+		case e.isJS && strings.HasPrefix(e.input[e.position:], "//"):
+			// Runs to the end of the line
+			e.write(e.input[e.position:])
+			e.position = len(e.input)
+
+		case e.isJS && char == '/' && e.regexCanStart():
+			e.copyQuoted('/')
+
+		case char == '(' || char == '[':
+			e.parenDepth++
+			e.write(string(char))
+
+		case char == ')' || char == ']':
+			e.parenDepth = max(0, e.parenDepth-1)
+			e.write(string(char))
+
+		case char == '{':
+			e.write("{")
+			e.depth++
+			e.newline()
+
+		case char == '}':
+			e.newline()
+			e.depth = max(0, e.depth-1)
+			e.write("}")
+			if !e.continuesAfterBrace() {
+				e.newline()
+			}
+
+		case char == ';' && e.parenDepth == 0:
+			e.write(";")
+			e.newline()
+
+		default:
+			e.write(string(char))
+		}
+	}
+	e.newline()
+
+	return e.lines
+}
+
+// Like "}," or "} else {", where the closing brace doesn't end the statement
+func (e *minifiedExpander) continuesAfterBrace() bool {
+	rest := e.lookahead()
+	if rest == "" {
+		return false
+	}
+	if strings.ContainsRune(",;)]", rune(rest[0])) {
+		return true
+	}
+	if !e.isJS {
+		return false
+	}
+	for _, keyword := range []string{"else", "catch", "finally", "while"} {
+		if strings.HasPrefix(rest, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// A / starts a regex unless it follows something that can be divided
+func (e *minifiedExpander) regexCanStart() bool {
+	last := e.lastWritten()
+	if last == 0 {
+		return true
+	}
+	return strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", rune(last))
+}
+
+// Copy a string or a regex as it is, including both delimiters
+func (e *minifiedExpander) copyQuoted(quote byte) {
+	start := e.position
+	for e.position++; e.position < len(e.input); e.position++ {
+		char := e.input[e.position]
+		if char == '\\' {
+			e.position++
+			continue
+		}
+		if char == quote {
+			break
+		}
+	}
+	e.position = min(e.position, len(e.input)-1)
+	e.write(e.input[start : e.position+1])
+}
+
+func (e *minifiedExpander) copyUntil(end string) {
+	length := strings.Index(e.input[e.position+len(end):], end)
+	if length == -1 {
+		e.write(e.input[e.position:])
+		e.position = len(e.input)
+		return
+	}
+	stop := e.position + len(end) + length + len(end)
+	e.write(e.input[e.position:stop])
+	e.position = stop - 1
+}
@@ -0,0 +1,94 @@
+// Package reformat pretty prints input that is hard to read as it is, like
+// minified JSON or CSV with columns of different widths.
+//
+// Formatters are registered by kind, like "json" or "csv". See Register().
+package reformat
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A Formatter returns its input reformatted, or an error if the input isn't
+// of its kind. Input that is fine as it is can be returned unchanged.
+type Formatter func(text string) (string, error)
+
+var formatters = map[string]Formatter{}
+
+// Lowercase Chroma lexer names and file extensions, mapped to kinds
+var aliases = map[string]string{}
+
+// Register a formatter for a kind of input. The aliases are Chroma lexer names
+// and file extensions that should pick this formatter.
+func Register(kind string, formatter Formatter, aliasesForKind ...string) {
+	formatters[kind] = formatter
+	for _, alias := range aliasesForKind {
+		aliases[strings.ToLower(alias)] = kind
+	}
+}
+
+func init() {
+	Register("json", formatJSON, "JSON", ".json")
+	Register("xml", formatXML, "XML", ".xml")
+	Register("yaml", formatYAML, "YAML", ".yaml", ".yml")
+	Register("toml", formatTOML, "TOML", ".toml")
+	Register("csv", formatCSV, "CSV", ".csv")
+	Register("tsv", formatTSV, ".tsv", ".tab")
+	Register("js", formatJS, "JavaScript", ".js", ".mjs")
+	Register("css", formatCSS, "CSS", ".css")
+}
+
+// Kinds lists all registered kinds, sorted
+func Kinds() []string {
+	kinds := []string{}
+	for kind := range formatters {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func Get(kind string) Formatter {
+	return formatters[kind]
+}
+
+// Pick a kind for some input. An explicitly requested kind wins, then the
+// lexer (from --lang or the file name), then the file name extension, and
+// finally a look at the contents. Returns "" if nothing matched.
+func Pick(requested string, lexerName string, fileName string, text string) string {
+	if requested != "" {
+		return requested
+	}
+
+	if kind, found := aliases[strings.ToLower(lexerName)]; found && lexerName != "" {
+		return kind
+	}
+
+	if kind, found := aliases[strings.ToLower(filepath.Ext(fileName))]; found && fileName != "" {
+		return kind
+	}
+
+	if json.Valid([]byte(text)) {
+		return "json"
+	}
+
+	if xml.Unmarshal([]byte(text), new(any)) == nil {
+		return "xml"
+	}
+
+	return ""
+}
+
+// Reformat text as some kind of input
+func Reformat(kind string, text string) (string, error) {
+	formatter := Get(kind)
+	if formatter == nil {
+		return "", fmt.Errorf("Unknown kind of input <%s>, pick one of: %s", kind, strings.Join(Kinds(), ", "))
+	}
+
+	return formatter(text)
+}
@@ -0,0 +1,194 @@
+package reformat
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func reformatted(t *testing.T, kind string, text string) string {
+	t.Helper()
+	result, err := Reformat(kind, text)
+	assert.NilError(t, err)
+	return result
+}
+
+func TestPick(t *testing.T) {
+	assert.Equal(t, Pick("csv", "JSON", "x.json", `{}`), "csv")
+	assert.Equal(t, Pick("", "YAML", "x.txt", "a: 1"), "yaml")
+	assert.Equal(t, Pick("", "", "data/x.TSV", "a\tb"), "tsv")
+	assert.Equal(t, Pick("", "", "", `{"a": 1}`), "json")
+	assert.Equal(t, Pick("", "", "", `<a><b/></a>`), "xml")
+	assert.Equal(t, Pick("", "", "", "hello"), "")
+}
+
+func TestReformatUnknownKind(t *testing.T) {
+	_, err := Reformat("cobol", "text")
+	assert.ErrorContains(t, err, "css, csv, js, json")
+}
+
+func TestFormatXML(t *testing.T) {
+	assert.Equal(t,
+		reformatted(t, "xml", `<a x:y="1"><b>text</b>  <c/></a>`),
+		strings.Join([]string{
+			`<a x:y="1">`,
+			`  <b>text</b>`,
+			`  <c></c>`,
+			`</a>`,
+		}, "\n"))
+}
+
+func TestFormatXMLBroken(t *testing.T) {
+	_, err := Reformat("xml", `<a><b></a>`)
+	assert.Assert(t, err != nil)
+}
+
+func TestFormatCSV(t *testing.T) {
+	assert.Equal(t,
+		reformatted(t, "csv", "name,count\nkalle,1\n\"a, b\",12345\n"),
+		"name   count\nkalle  1\na, b   12345\n")
+}
+
+func TestFormatTSVWide(t *testing.T) {
+	// The wide characters take two columns each
+	assert.Equal(t,
+		reformatted(t, "tsv", "漢字\tx\na\ty\n"),
+		"漢字  x\na     y\n")
+}
+
+func TestFormatCSVSingleColumn(t *testing.T) {
+	_, err := Reformat("csv", "just\nsome\ntext\n")
+	assert.ErrorContains(t, err, "two columns")
+}
+
+func TestFormatTOML(t *testing.T) {
+	assert.Equal(t,
+		reformatted(t, "toml", strings.Join([]string{
+			`title="moor"`,
+			`[owner]`,
+			`  name   ="Johan" # Comment`,
+			`text="""`,
+			`a=b`,
+			`"""`,
+		}, "\n")),
+		strings.Join([]string{
+			`title = "moor"`,
+			``,
+			`[owner]`,
+			`  name = "Johan" # Comment`,
+			`text = """`,
+			`a=b`,
+			`"""`,
+		}, "\n"))
+}
+
+func TestFormatYAML(t *testing.T) {
+	assert.Equal(t,
+		reformatted(t, "yaml", strings.Join([]string{
+			`name: moor`,
+			`tags: [pager, "a, b"]`,
+			`- {x: 1, y: {z: []}}`,
+			`- key: {b: 2}`,
+			`{a: 1}`,
+		}, "\n")),
+		strings.Join([]string{
+			`name: moor`,
+			`tags:`,
+			`  - pager`,
+			`  - "a, b"`,
+			`- x: 1`,
+			`  y:`,
+			`    z: []`,
+			`- key:`,
+			`    b: 2`,
+			`a: 1`,
+		}, "\n"))
+}
+
+// Flow collection lookalikes in block scalars and quoted strings are text, not
+// YAML
+func TestFormatYAMLLeavesStringsAlone(t *testing.T) {
+	text := strings.Join([]string{
+		`literal: |`,
+		`  {a: 1}`,
+		``,
+		`  [b, c]`,
+		`- folded: >-`,
+		`    {d: 2}`,
+		`  e: {f: 3}`,
+		`quoted: "first line`,
+		`  {g: 4} \"still quoted`,
+		`  last line"`,
+		`single: 'it''s`,
+		`  [h]'`,
+		`after: {i: 5}`,
+	}, "\n")
+	assert.Equal(t,
+		reformatted(t, "yaml", text),
+		strings.Join([]string{
+			`literal: |`,
+			`  {a: 1}`,
+			``,
+			`  [b, c]`,
+			`- folded: >-`,
+			`    {d: 2}`,
+			`  e:`,
+			`    f: 3`,
+			`quoted: "first line`,
+			`  {g: 4} \"still quoted`,
+			`  last line"`,
+			`single: 'it''s`,
+			`  [h]'`,
+			`after:`,
+			`  i: 5`,
+		}, "\n"))
+}
+
+func TestFormatYAMLLeavesUnparsableLinesAlone(t *testing.T) {
+	text := "a: {b: [1, 2}\nempty: {}\nmultiline: {c: 1,\n  d: 2}"
+	assert.Equal(t, reformatted(t, "yaml", text), text)
+}
+
+func TestFormatJSShortLinesUnchanged(t *testing.T) {
+	text := "function f() { return 1; }"
+	assert.Equal(t, reformatted(t, "js", text), text)
+}
+
+func TestFormatJS(t *testing.T) {
+	padding := strings.Repeat("x", minifiedLineLength)
+	text := `function f(a){for(var i=0;i<a;i++){g("{;}",/;}/)}if(a){b()}else{c()};var ` + padding + `=1}`
+
+	assert.Equal(t,
+		reformatted(t, "js", text),
+		strings.Join([]string{
+			`function f(a){`,
+			`  for(var i=0;i<a;i++){`,
+			`    g("{;}",/;}/)`,
+			`  }`,
+			`  if(a){`,
+			`    b()`,
+			`  }else{`,
+			`    c()`,
+			`  };`,
+			`  var ` + padding + `=1`,
+			`}`,
+		}, "\n"))
+}
+
+func TestFormatCSS(t *testing.T) {
+	padding := strings.Repeat("x", minifiedLineLength)
+	text := `a{color:red;background:url("a;b")}/* c{} */.` + padding + `{margin:0}`
+
+	assert.Equal(t,
+		reformatted(t, "css", text),
+		strings.Join([]string{
+			`a{`,
+			`  color:red;`,
+			`  background:url("a;b")`,
+			`}`,
+			`/* c{} */.` + padding + `{`,
+			`  margin:0`,
+			`}`,
+		}, "\n"))
+}
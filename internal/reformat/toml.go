@@ -0,0 +1,108 @@
+// TOML with normalized spacing
+
+package reformat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Spaces around the = in key = value lines, and an empty line before each
+// [table] header
+func formatTOML(text string) (string, error) {
+	result := []string{}
+	inMultilineString := "" // The closing delimiter if we're in one
+	for _, line := range strings.Split(text, "\n") {
+		if inMultilineString != "" {
+			result = append(result, line)
+			if strings.Contains(line, inMultilineString) {
+				inMultilineString = ""
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if isTOMLHeader(trimmed) {
+			if len(result) > 0 && result[len(result)-1] != "" {
+				result = append(result, "")
+			}
+			result = append(result, trimmed)
+			continue
+		}
+
+		equals := tomlKeyEnd(trimmed)
+		if equals == -1 || !isTOMLKey(strings.TrimSpace(trimmed[:equals])) {
+			// Comments, empty lines and multi line arrays
+			result = append(result, strings.TrimRight(line, " \t"))
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		key := strings.TrimSpace(trimmed[:equals])
+		value := strings.TrimSpace(trimmed[equals+1:])
+		result = append(result, indent+key+" = "+value)
+
+		for _, delimiter := range []string{`"""`, `'''`} {
+			if strings.Count(value, delimiter) == 1 {
+				inMultilineString = delimiter
+			}
+		}
+	}
+
+	return strings.Join(result, "\n"), nil
+}
+
+// Index of the first = outside of quotes, or -1 if there is none
+func tomlKeyEnd(line string) int {
+	var quote rune
+	for i, char := range line {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// Like [table] or [[array.of.tables]]
+func isTOMLHeader(line string) bool {
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return false
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+	if strings.HasPrefix(name, "[") {
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+	}
+	return isTOMLKey(strings.TrimSpace(name))
+}
+
+// Bare, quoted or dotted keys
+func isTOMLKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	var quote rune
+	for _, char := range key {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '_' || char == '-' || char == '.' || char == ' ' || unicode.IsLetter(char) || unicode.IsDigit(char):
+			// Fine
+		default:
+			return false
+		}
+	}
+	return quote == 0
+}
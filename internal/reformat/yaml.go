@@ -0,0 +1,382 @@
+// YAML flow collections, like {name: moor, tags: [pager, go]}, expanded into
+// block style:
+//
+//	name: moor
+//	tags:
+//	  - pager
+//	  - go
+//
+// This rewrites one line at a time, so flow collections spanning multiple lines
+// are left as they are.
+
+package reformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+type yamlNode struct {
+	scalar string // Used unless this is a mapping or a sequence
+
+	isMapping  bool
+	isSequence bool
+	keys       []string // Only for mappings
+	values     []*yamlNode
+}
+
+func formatYAML(text string) (string, error) {
+	result := []string{}
+
+	// Lines indented more than this are part of a block scalar, like the
+	// lines after "key: |". Negative when we're not in a block scalar.
+	blockScalarIndent := -1
+
+	// Set while we're in a quoted string spanning multiple lines
+	var openQuote byte
+
+	for _, line := range strings.Split(text, "\n") {
+		if openQuote != 0 {
+			result = append(result, line)
+			if closesYAMLQuote(line, openQuote) {
+				openQuote = 0
+			}
+			continue
+		}
+
+		if blockScalarIndent >= 0 {
+			content := strings.TrimLeft(line, " ")
+			if content == "" || len(line)-len(content) > blockScalarIndent {
+				result = append(result, line)
+				continue
+			}
+			blockScalarIndent = -1
+		}
+
+		value, valueIndent := splitYAMLValue(line)
+		if isYAMLBlockScalarHeader(value) {
+			blockScalarIndent = valueIndent
+			result = append(result, line)
+			continue
+		}
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			if !closesYAMLQuote(value[1:], value[0]) {
+				openQuote = value[0]
+			}
+			result = append(result, line)
+			continue
+		}
+
+		result = append(result, expandYAMLLine(line)...)
+	}
+
+	return strings.Join(result, "\n"), nil
+}
+
+// The value of a line, after any "- " and "key: " prefixes. The indent is
+// where the innermost key or sequence item starts, block scalar lines must be
+// indented more than that.
+func splitYAMLValue(line string) (string, int) {
+	content := strings.TrimLeft(line, " ")
+	indent := len(line) - len(content)
+	for {
+		rest, found := strings.CutPrefix(content, "- ")
+		if !found {
+			break
+		}
+		rest = strings.TrimLeft(rest, " ")
+		indent += len(content) - len(rest)
+		content = rest
+	}
+
+	if _, value, found := cutYAMLKey(content); found {
+		return value, indent
+	}
+	return content, indent
+}
+
+// Like "|", ">-" or "|2 # comment"
+func isYAMLBlockScalarHeader(value string) bool {
+	if !strings.HasPrefix(value, "|") && !strings.HasPrefix(value, ">") {
+		return false
+	}
+
+	header, _, _ := strings.Cut(value[1:], "#")
+	return strings.Trim(header, "+-0123456789 ") == ""
+}
+
+// True if a quoted string continuing on this line ends here
+func closesYAMLQuote(line string, quote byte) bool {
+	parser := yamlParser{input: string(quote) + line}
+	_, err := parser.parseScalar("")
+	return err == nil
+}
+
+// Lines that are, or end in, a flow collection are expanded. Other lines are
+// returned as they are.
+func expandYAMLLine(line string) []string {
+	content := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(content)]
+
+	prefix := ""
+	flow := content
+	if rest, found := strings.CutPrefix(content, "- "); found {
+		prefix = "- "
+		flow = strings.TrimLeft(rest, " ")
+	}
+	if !strings.HasPrefix(flow, "{") && !strings.HasPrefix(flow, "[") {
+		// Like "key: {...}" or "- key: {...}"
+		key, value, found := cutYAMLKey(flow)
+		if !found {
+			return []string{line}
+		}
+		prefix += key + ": "
+		flow = value
+	}
+
+	if !strings.HasPrefix(flow, "{") && !strings.HasPrefix(flow, "[") {
+		return []string{line}
+	}
+
+	parser := yamlParser{input: flow}
+	node, err := parser.parseValue("")
+	if err != nil {
+		return []string{line}
+	}
+	parser.skipSpaces()
+	if !parser.atEnd() && !strings.HasPrefix(parser.input[parser.position:], "#") {
+		return []string{line}
+	}
+
+	if node.isEmpty() {
+		return []string{line}
+	}
+
+	switch prefix {
+	case "":
+		return node.lines(indent)
+	case "- ":
+		return sequenceItemLines(node, indent)
+	}
+
+	// key: {...}, nested below where the key starts
+	keyIndent := indent
+	if strings.HasPrefix(prefix, "- ") {
+		keyIndent += "  "
+	}
+	return append([]string{indent + strings.TrimSuffix(prefix, " ")}, node.lines(keyIndent+"  ")...)
+}
+
+// Split "key: value" into its parts, with a quoted or plain key
+func cutYAMLKey(content string) (string, string, bool) {
+	keyEnd := 0
+	if strings.HasPrefix(content, `"`) || strings.HasPrefix(content, "'") {
+		closing := strings.IndexByte(content[1:], content[0])
+		if closing == -1 {
+			return "", "", false
+		}
+		keyEnd = closing + 2
+		if !strings.HasPrefix(content[keyEnd:], ": ") {
+			return "", "", false
+		}
+	} else {
+		keyEnd = strings.Index(content, ": ")
+		if keyEnd <= 0 {
+			return "", "", false
+		}
+	}
+
+	return content[:keyEnd], strings.TrimLeft(content[keyEnd+2:], " "), true
+}
+
+func (node *yamlNode) isEmpty() bool {
+	return (node.isMapping || node.isSequence) && len(node.values) == 0
+}
+
+// The node as block style lines
+func (node *yamlNode) lines(indent string) []string {
+	result := []string{}
+	for i, value := range node.values {
+		if node.isSequence {
+			result = append(result, sequenceItemLines(value, indent)...)
+			continue
+		}
+
+		key := node.keys[i]
+		if value.isMapping || value.isSequence {
+			if value.isEmpty() {
+				result = append(result, indent+key+": "+value.flowString())
+				continue
+			}
+			result = append(result, indent+key+":")
+			result = append(result, value.lines(indent+"  ")...)
+			continue
+		}
+
+		if value.scalar == "" {
+			result = append(result, indent+key+":")
+		} else {
+			result = append(result, indent+key+": "+value.scalar)
+		}
+	}
+
+	return result
+}
+
+// Like "- value", with collections starting on the same line as the dash
+func sequenceItemLines(node *yamlNode, indent string) []string {
+	if !(node.isMapping || node.isSequence) || node.isEmpty() {
+		return []string{indent + "- " + node.flowString()}
+	}
+
+	lines := node.lines(indent + "  ")
+	lines[0] = indent + "- " + strings.TrimPrefix(lines[0], indent+"  ")
+	return lines
+}
+
+// For scalars and empty collections
+func (node *yamlNode) flowString() string {
+	switch {
+	case node.isMapping:
+		return "{}"
+	case node.isSequence:
+		return "[]"
+	}
+	return node.scalar
+}
+
+type yamlParser struct {
+	input    string
+	position int
+}
+
+func (p *yamlParser) atEnd() bool {
+	return p.position >= len(p.input)
+}
+
+func (p *yamlParser) peek() byte {
+	if p.atEnd() {
+		return 0
+	}
+	return p.input[p.position]
+}
+
+func (p *yamlParser) skipSpaces() {
+	for !p.atEnd() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.position++
+	}
+}
+
+// Parse a collection or a scalar. Plain scalars end at any of the stop
+// characters.
+func (p *yamlParser) parseValue(stop string) (*yamlNode, error) {
+	p.skipSpaces()
+
+	switch p.peek() {
+	case '{':
+		return p.parseCollection('}')
+	case '[':
+		return p.parseCollection(']')
+	}
+
+	scalar, err := p.parseScalar(stop)
+	if err != nil {
+		return nil, err
+	}
+	return &yamlNode{scalar: scalar}, nil
+}
+
+func (p *yamlParser) parseCollection(closing byte) (*yamlNode, error) {
+	p.position++ // Skip the opening bracket
+	node := &yamlNode{isMapping: closing == '}', isSequence: closing == ']'}
+
+	for {
+		p.skipSpaces()
+		if p.atEnd() {
+			return nil, fmt.Errorf("Expected '%c'", closing)
+		}
+		if p.peek() == closing {
+			p.position++
+			return node, nil
+		}
+
+		if node.isMapping {
+			key, err := p.parseScalar(":,}")
+			if err != nil {
+				return nil, err
+			}
+			if key == "" {
+				return nil, fmt.Errorf("Expected a key at position %d", p.position)
+			}
+			node.keys = append(node.keys, key)
+
+			p.skipSpaces()
+			if p.peek() != ':' {
+				// A key without a value
+				node.values = append(node.values, &yamlNode{})
+			} else {
+				p.position++
+				value, err := p.parseValue(",}")
+				if err != nil {
+					return nil, err
+				}
+				node.values = append(node.values, value)
+			}
+		} else {
+			value, err := p.parseValue(",]")
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		}
+
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.position++
+		case closing:
+			// Handled at the top of the loop
+		default:
+			return nil, fmt.Errorf("Expected ',' or '%c' at position %d", closing, p.position)
+		}
+	}
+}
+
+// Quoted scalars are returned with their quotes, so that they mean the same
+// thing in block style
+func (p *yamlParser) parseScalar(stop string) (string, error) {
+	p.skipSpaces()
+	start := p.position
+
+	if p.peek() == '"' || p.peek() == '\'' {
+		quote := p.peek()
+		p.position++
+		for !p.atEnd() {
+			char := p.peek()
+			p.position++
+			if char == '\\' && quote == '"' {
+				p.position++
+				continue
+			}
+			if char != quote {
+				continue
+			}
+			if quote == '\'' && p.peek() == '\'' {
+				// '' is an escaped single quote
+				p.position++
+				continue
+			}
+			return p.input[start:p.position], nil
+		}
+		return "", fmt.Errorf("Unterminated string at position %d", start)
+	}
+
+	for !p.atEnd() && !strings.ContainsRune(stop, rune(p.peek())) {
+		if p.peek() == '{' || p.peek() == '[' || p.peek() == ']' || p.peek() == '}' {
+			return "", fmt.Errorf("Unexpected '%c' at position %d", p.peek(), p.position)
+		}
+		p.position++
+	}
+
+	return strings.TrimSpace(p.input[start:p.position]), nil
+}
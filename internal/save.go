@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+)
+
+// How many lines to get from the reader at a time when writing lines out.
+// Getting all of a huge file at once would load all of it into memory.
+const writeBatchSize = 1000
+
+type saveOptions struct {
+	// Replace the file if it already exists
+	overwrite bool
+
+	// Write the lines with their ANSI escape codes. The default is plain
+	// text.
+	//
+	// Note that these are the lines as shown, so they include any syntax
+	// highlighting, and are reformatted if --reformat was used.
+	raw bool
+}
+
+// Write the lines of a reader to a file. Only the lines that have been read so
+// far will be written, see Pager.readAllThen().
+//
+// Pass a FilteringReader to write only the lines matching its filter.
+//
+// Returns the number of lines written.
+func saveToFile(r reader.Reader, fileName string, options saveOptions) (int, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !options.overwrite {
+		flags |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(fileName, flags, 0o666)
+	if err != nil {
+		return 0, err
+	}
+
+	writer := bufio.NewWriter(file)
+	written, err := writeLines(writer, r, linemetadata.Index{}, r.GetLineCount(), options.raw)
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Failed writing to %s: %w", fileName, err)
+	}
+
+	log.Debugf("Saved %d lines to %s", written, fileName)
+	return written, nil
+}
+
+// Write count lines starting at first, each followed by a newline. The "--"
+// dividers between groups of filtered lines are left out.
+//
+// Returns the number of lines written.
+func writeLines(writer *bufio.Writer, r reader.Reader, first linemetadata.Index, count int, raw bool) (int, error) {
+	written := 0
+	for done := 0; done < count; {
+		wanted := first.NonWrappingAdd(done)
+		lines := r.GetLines(wanted, min(writeBatchSize, count-done)).Lines
+		if len(lines) == 0 || lines[0].Index != wanted {
+			// Out of lines
+			break
+		}
+
+		for _, line := range lines {
+			if line.Line == filterDivider {
+				continue
+			}
+
+			var err error
+			if raw {
+				_, err = writer.Write(line.Line.Raw())
+			} else {
+				_, err = writer.WriteString(line.Plain())
+			}
+			if err == nil {
+				err = writer.WriteByte('\n')
+			}
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+
+		done += len(lines)
+	}
+
+	return written, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/rivo/uniseg"
 	log "github.com/sirupsen/logrus"
 	"github.com/walles/moor/v2/internal/linemetadata"
 	"github.com/walles/moor/v2/internal/reader"
@@ -43,6 +44,21 @@ type renderedScreen struct {
 // the bottom
 func (p *Pager) redraw(spinner string) {
 	log.Trace("redraw called")
+
+	if p.split != nil {
+		// The spinner is for the focused pane only
+		p.swapPanes()
+		p.drawPane("")
+		p.swapPanes()
+	}
+	p.drawPane(spinner)
+
+	p.screen.Show()
+}
+
+// Draw contents lines and the status line of the focused pane, without showing
+// them on screen
+func (p *Pager) drawPane(spinner string) {
 	p.screen.Clear()
 	p.longestLineLength = 0
 
@@ -69,9 +85,74 @@ func (p *Pager) redraw(spinner string) {
 		column += p.screen.SetCell(column, lastUpdatedScreenLineNumber+1, cell.ToStyledRune())
 	}
 
-	p.mode.drawFooter(renderedScreen.filenameText, renderedScreen.statusText, spinner)
+	statusText := renderedScreen.statusText
+	if hits := p.hitCountText(renderedScreen); hits != "" {
+		statusText += "  " + hits
+	}
+	if pending := p.pendingGotoText(); pending != "" {
+		statusText += "  " + pending
+	}
 
-	p.screen.Show()
+	if len(renderedScreen.lines) > 0 {
+		p.drawScrollbar(renderedScreen.lines[0].inputLineIndex, renderedScreen.lines[len(renderedScreen.lines)-1].inputLineIndex)
+	}
+
+	p.mode.drawFooter(renderedScreen.filenameText, statusText, spinner)
+
+	p.highlightVisibleLines(renderedScreen)
+}
+
+// Inputs too large for highlighting all at once get highlighted around what's
+// on screen
+func (p *Pager) highlightVisibleLines(rendered renderedScreen) {
+	if p.isShowingHelp || len(rendered.inputLines) == 0 {
+		return
+	}
+
+	p.readerLock.Lock()
+	r := p.readers[p.currentReader]
+	p.readerLock.Unlock()
+
+	// Filtered lines keep their original line numbers, and that's what the
+	// backing reader wants
+	visible := make([]linemetadata.Index, 0, len(rendered.inputLines))
+	for _, line := range rendered.inputLines {
+		visible = append(visible, linemetadata.IndexFromZeroBased(line.Number.AsZeroBased()))
+	}
+	r.HighlightVisible(visible)
+}
+
+// Like "hit 12/340", or empty if we aren't searching
+func (p *Pager) hitCountText(rendered renderedScreen) string {
+	if p.hitCounter == nil || p.search.Inactive() || len(rendered.lines) == 0 {
+		return ""
+	}
+
+	// Help is never filtered
+	key := hitCounterKey{reader: _HelpReader, search: p.search}
+	var lines reader.Reader = _HelpReader
+	allRead := true
+	if !p.isShowingHelp {
+		p.readerLock.Lock()
+		r := p.readers[p.currentReader]
+		p.readerLock.Unlock()
+
+		key.reader = r
+		key.filter = *p.filter
+		key.context = p.filterContext
+		key.keepNonJSON = p.keepNonJSON
+		allRead = r.ReadingDone.Load()
+
+		// Counting in what we have already filtered saves filtering again
+		lines = r
+		if filtered := p.filteringReader.snapshot(); filtered != nil {
+			lines = filtered
+		}
+	}
+
+	firstVisible := rendered.lines[0].inputLineIndex
+	lastVisible := rendered.lines[len(rendered.lines)-1].inputLineIndex
+	return p.hitCounter.status(p.screen, key, lines, allRead, firstVisible, lastVisible)
 }
 
 // Render all lines that should go on the screen.
@@ -182,7 +263,7 @@ func (p *Pager) internalRenderLines(highlightSearchHitLines bool) renderedScreen
 	}
 
 	// Fill in the line trailers
-	screenWidth, _ := p.screen.Size()
+	screenWidth := p.contentWidth()
 	for i := range allLines {
 		line := &allLines[i]
 		if line.trailer == twin.StyleDefault {
@@ -211,28 +292,66 @@ func (p *Pager) internalRenderLines(highlightSearchHitLines bool) renderedScreen
 // lineNumber and numberPrefixLength are required for knowing how much to
 // indent, and to (optionally) render the line number.
 func (p *Pager) renderLine(line reader.NumberedLine, numberPrefixLength int, highlightSearchHitLines bool) []renderedLine {
-	width, _ := p.screen.Size()
+	width := p.contentWidth()
+
+	kind := filteredLineMatch
+	if !p.isShowingHelp && p.filteringReader != nil {
+		kind = p.filteringReader.lineKind(line.Index)
+	}
+	if kind == filteredLineDivider {
+		return []renderedLine{p.renderFilterDivider(line.Index, numberPrefixLength)}
+	}
+	if kind == filteredLineContext {
+		// Context lines shouldn't stand out as search hits
+		highlightSearchHitLines = false
+	}
+
+	// Unfolded JSON records are shown on multiple rows, just like wrapped
+	// lines
+	rows := []reader.NumberedLine{line}
+	if columnized := p.columnizeLine(line); columnized != nil {
+		rows[0] = *columnized
+	} else if unfolded := p.unfoldJSONRecord(line); unfolded != nil {
+		rows = unfolded
+	}
+
+	// Merged views tell which file each line came from. This isn't part of
+	// the line contents, so it's never a search hit.
+	var prefix []textstyles.CellWithMetadata
+	if line.Line.Prefix() != "" {
+		prefix = textstyles.StyledRunesFromString(plainTextStyle, line.Line.Prefix(), nil, 0).StyledRunes
+	}
+
 	var wrapped []textstyles.StyledRunesWithTrailer
 	var highlighted textstyles.StyledRunesWithTrailer
-	if p.WrapLongLines {
-		highlighted = line.HighlightedTokens(plainTextStyle, searchHitStyle, p.search, 0)
+	for rowIndex, row := range rows {
+		if p.WrapLongLines {
+			highlighted = row.HighlightedTokens(plainTextStyle, searchHitStyle, p.search, p.highlightsForRendering(), 0)
+			if rowIndex == 0 {
+				highlighted.StyledRunes = append(prefix, highlighted.StyledRunes...)
+			}
+
+			wrapped = append(wrapped, wrapLine(width-numberPrefixLength, highlighted.StyledRunes)...)
+			continue
+		}
 
-		wrapped = wrapLine(width-numberPrefixLength, highlighted.StyledRunes)
-	} else {
 		// Request only screen width tokens plus whatever is needed on the left
 		// due to horizontal scrolling. Also, get one extra to the right so we
 		// can know whether to show overflow markers.
 		//
 		// This is a huge performance gain when dealing with files with
 		// extremeny long lines: https://github.com/walles/moor/issues/358
-		highlighted = line.HighlightedTokens(plainTextStyle, searchHitStyle, p.search, width+p.leftColumnZeroBased+1)
+		highlighted = row.HighlightedTokens(plainTextStyle, searchHitStyle, p.search, p.highlightsForRendering(), width+p.leftColumnZeroBased+1)
+		if rowIndex == 0 {
+			highlighted.StyledRunes = append(prefix, highlighted.StyledRunes...)
+		}
 
 		// All on one line
-		wrapped = []textstyles.StyledRunesWithTrailer{{
+		wrapped = append(wrapped, textstyles.StyledRunesWithTrailer{
 			StyledRunes:       highlighted.StyledRunes,
 			Trailer:           highlighted.Trailer,
 			ContainsSearchHit: highlighted.ContainsSearchHit,
-		}}
+		})
 	}
 
 	if highlightSearchHitLines && searchHitLineBackground != nil {
@@ -242,6 +361,10 @@ func (p *Pager) renderLine(line reader.NumberedLine, numberPrefixLength int, hig
 			if line.ContainsSearchHit {
 				// Highlight this line!
 				for i := range line.StyledRunes {
+					if line.StyledRunes[i].IsHighlighted {
+						// Keep the highlight color
+						continue
+					}
 					line.StyledRunes[i].Style = line.StyledRunes[i].Style.WithBackground(*searchHitLineBackground)
 				}
 				line.Trailer = line.Trailer.WithBackground(*searchHitLineBackground)
@@ -249,15 +372,31 @@ func (p *Pager) renderLine(line reader.NumberedLine, numberPrefixLength int, hig
 		}
 	}
 
+	if kind == filteredLineContext {
+		for i := range wrapped {
+			for j := range wrapped[i].StyledRunes {
+				wrapped[i].StyledRunes[j].Style = wrapped[i].StyledRunes[j].Style.WithAttr(twin.AttrDim)
+			}
+		}
+	}
+
+	mark := p.markOnLine(line.Index)
+
 	rendered := make([]renderedLine, 0)
 	for wrapIndex, subLine := range wrapped {
 		lineNumber := line.Number
 		visibleLineNumber := &lineNumber
+		visibleMark := mark
 		if wrapIndex > 0 {
 			visibleLineNumber = nil
+			visibleMark = 0
+		}
+		if !p.showLineNumbers {
+			// We may still have a mark gutter
+			visibleLineNumber = nil
 		}
 
-		decorated := p.decorateLine(visibleLineNumber, numberPrefixLength, subLine.StyledRunes)
+		decorated := p.decorateLine(visibleLineNumber, visibleMark, numberPrefixLength, subLine.StyledRunes)
 
 		rendered = append(rendered, renderedLine{
 			inputLineIndex:    line.Index,
@@ -276,17 +415,77 @@ func (p *Pager) renderLine(line reader.NumberedLine, numberPrefixLength int, hig
 		lastRenderedLine.trailer = highlighted.Trailer
 	}
 
+	if tailEvent := line.Line.TailEvent(); tailEvent != "" {
+		rendered = append(rendered, p.renderTailEventSeparator(line.Index, len(rendered), tailEvent, numberPrefixLength))
+	}
+
 	return rendered
 }
 
+// Like "──── app.log rotated 12:34:56 ────", below the last line before the
+// file we're following was rotated or truncated
+func (p *Pager) renderTailEventSeparator(index linemetadata.Index, wrapIndex int, tailEvent string, numberPrefixLength int) renderedLine {
+	style := twin.StyleDefault.WithAttr(twin.AttrReverse)
+
+	cells := createLinePrefix(nil, 0, numberPrefixLength)
+	for _, char := range "──── " + tailEvent + " ────" {
+		if len(cells) >= p.contentWidth() {
+			break
+		}
+		cells = append(cells, textstyles.CellWithMetadata{Rune: char, Style: style})
+	}
+
+	return renderedLine{
+		inputLineIndex: index,
+		wrapIndex:      wrapIndex,
+		cells:          cells,
+	}
+}
+
+// The highlight patterns, with a color for each
+func (p *Pager) highlightsForRendering() []reader.PatternHighlight {
+	if len(p.highlights) == 0 {
+		return nil
+	}
+
+	highlights := make([]reader.PatternHighlight, 0, len(p.highlights))
+	for i, highlight := range p.highlights {
+		highlights = append(highlights, reader.PatternHighlight{
+			Search: highlight,
+			Style:  highlightStyles[i%len(highlightStyles)],
+		})
+	}
+
+	return highlights
+}
+
+// A dim line between non-adjacent groups of filtered lines
+func (p *Pager) renderFilterDivider(index linemetadata.Index, numberPrefixLength int) renderedLine {
+	width := p.contentWidth()
+
+	cells := createLinePrefix(nil, 0, numberPrefixLength)
+	for len(cells) < width {
+		cells = append(cells, textstyles.CellWithMetadata{
+			Rune:  '╌',
+			Style: twin.StyleDefault.WithAttr(twin.AttrDim),
+		})
+	}
+
+	return renderedLine{
+		inputLineIndex: index,
+		cells:          cells,
+	}
+}
+
 // Take a rendered line and decorate as needed:
 //   - Line number, or leading whitespace for wrapped lines
+//   - Mark, if the line has one. Zero means no mark.
 //   - Scroll left indicator
 //   - Scroll right indicator
-func (p *Pager) decorateLine(lineNumberToShow *linemetadata.Number, numberPrefixLength int, contents []textstyles.CellWithMetadata) []textstyles.CellWithMetadata {
-	width, _ := p.screen.Size()
+func (p *Pager) decorateLine(lineNumberToShow *linemetadata.Number, mark rune, numberPrefixLength int, contents []textstyles.CellWithMetadata) []textstyles.CellWithMetadata {
+	width := p.contentWidth()
 	newLine := make([]textstyles.CellWithMetadata, 0, width)
-	newLine = append(newLine, createLinePrefix(lineNumberToShow, numberPrefixLength)...)
+	newLine = append(newLine, createLinePrefix(lineNumberToShow, mark, numberPrefixLength)...)
 
 	// Find the first and last fully visible runes.
 	var firstVisibleRuneIndex *int
@@ -392,34 +591,89 @@ func (p *Pager) decorateLine(lineNumberToShow *linemetadata.Number, numberPrefix
 
 // Generate a line number prefix of the given length.
 //
+// If mark is non-zero, it goes in the gutter between the line number and the
+// line contents.
+//
 // Can be empty or all-whitespace depending on parameters.
-func createLinePrefix(lineNumber *linemetadata.Number, numberPrefixLength int) []textstyles.CellWithMetadata {
+func createLinePrefix(lineNumber *linemetadata.Number, mark rune, numberPrefixLength int) []textstyles.CellWithMetadata {
 	if numberPrefixLength == 0 {
 		return []textstyles.CellWithMetadata{}
 	}
 
 	lineNumberPrefix := make([]textstyles.CellWithMetadata, 0, numberPrefixLength)
 	if lineNumber == nil {
+		// Wrapped line, divider or just the mark gutter
 		for len(lineNumberPrefix) < numberPrefixLength {
 			lineNumberPrefix = append(lineNumberPrefix, textstyles.CellWithMetadata{Rune: ' '})
 		}
-		return lineNumberPrefix
+	} else {
+		lineNumberString := fmt.Sprintf("%*s ", numberPrefixLength-1, lineNumber.Format())
+		if len(lineNumberString) > numberPrefixLength {
+			panic(fmt.Errorf(
+				"lineNumberString <%s> longer than numberPrefixLength %d",
+				lineNumberString, numberPrefixLength))
+		}
+
+		for column, digit := range lineNumberString {
+			if column >= numberPrefixLength {
+				break
+			}
+
+			lineNumberPrefix = append(lineNumberPrefix, textstyles.CellWithMetadata{Rune: digit, Style: lineNumbersStyle})
+		}
 	}
 
-	lineNumberString := fmt.Sprintf("%*s ", numberPrefixLength-1, lineNumber.Format())
-	if len(lineNumberString) > numberPrefixLength {
-		panic(fmt.Errorf(
-			"lineNumberString <%s> longer than numberPrefixLength %d",
-			lineNumberString, numberPrefixLength))
+	if mark != 0 && uniseg.StringWidth(string(mark)) == 1 {
+		// Replace the space after the line number
+		lineNumberPrefix[len(lineNumberPrefix)-1] = textstyles.CellWithMetadata{
+			Rune:  mark,
+			Style: lineNumbersStyle.WithAttr(twin.AttrReverse),
+		}
 	}
 
-	for column, digit := range lineNumberString {
-		if column >= numberPrefixLength {
-			break
+	return lineNumberPrefix
+}
+
+// With line numbers hidden, we need a column of our own for showing marks
+func (p *Pager) showMarkGutter() bool {
+	if p.showLineNumbers || p.isShowingHelp || len(p.bookmarks) == 0 {
+		return false
+	}
+
+	for mark := range p.currentBookmarks() {
+		if mark != '\'' {
+			// Not just where we were last time
+			return true
 		}
+	}
 
-		lineNumberPrefix = append(lineNumberPrefix, textstyles.CellWithMetadata{Rune: digit, Style: lineNumbersStyle})
+	return false
+}
+
+// Returns the first (alphabetically) mark on the given line, or zero if there
+// is none
+func (p *Pager) markOnLine(index linemetadata.Index) rune {
+	if p.isShowingHelp || len(p.bookmarks) == 0 {
+		return 0
 	}
 
-	return lineNumberPrefix
+	var found rune
+	for mark, position := range p.currentBookmarks() {
+		if mark == '\'' {
+			// Where we were last time, not a real mark
+			continue
+		}
+
+		// Not lineIndex(), canonicalizing would make us recurse back in here
+		markIndex := position.unclippedLineIndex()
+		if markIndex == nil || *markIndex != index {
+			continue
+		}
+
+		if found == 0 || mark < found {
+			found = mark
+		}
+	}
+
+	return found
 }
@@ -102,9 +102,10 @@ func TestEmpty(t *testing.T) {
 
 		scrollPosition: newScrollPosition("TestEmpty"),
 	}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 
 	rendered := pager.renderLines()
@@ -156,9 +157,10 @@ func TestOverflowDown(t *testing.T) {
 		// This value can be anything and should be clipped, that's what we're testing
 		scrollPosition: *scrollPositionFromIndex("TestOverflowDown", linemetadata.IndexFromOneBased(42)),
 	}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 
 	rendered := pager.renderLines()
@@ -182,9 +184,10 @@ func TestOverflowUp(t *testing.T) {
 
 		// NOTE: scrollPosition intentionally not initialized
 	}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 
 	rendered := pager.renderLines()
@@ -253,9 +256,10 @@ func TestOneLineTerminal(t *testing.T) {
 		readers:       []*reader.ReaderImpl{reader.NewFromTextForTesting("test", "hej")},
 		ShowStatusBar: true,
 	}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 
 	rendered := pager.renderLines()
@@ -280,16 +284,17 @@ func TestShortenedInput(t *testing.T) {
 	pager.mode = PagerModeViewing{&pager}
 	pager.ShowStatusBar = false
 
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 
 	pager.scrollToEnd()
 	assert.Equal(t, pager.lineIndex().Index(), 991, "This should have been the effect of calling scrollToEnd()")
 
 	pager.mode = NewPagerModeFilter(&pager)
-	pager.filter = search.For("first") // Match only the first line
+	*pager.filter = filterChainFor("first") // Match only the first line
 
 	rendered := pager.renderLines()
 	assert.Equal(t, len(rendered.lines), 1, "Should have rendered one line")
@@ -318,16 +323,17 @@ func TestShortenedInputManyLines(t *testing.T) {
 		scrollPosition: newScrollPosition("TestShortenedInputManyLines"),
 	}
 
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 
 	pager.scrollToEnd()
 	assert.Equal(t, pager.lineIndex().Index(), 991, "Should be at the last line before filtering")
 
 	pager.mode = NewPagerModeFilter(&pager)
-	pager.filter = search.For(`^match`)
+	*pager.filter = filterChainFor(`^match`)
 
 	rendered := pager.renderLines()
 	assert.Equal(t, len(rendered.lines), 9, "Should have rendered 9 lines (10 minus one status bar)")
@@ -395,9 +401,10 @@ func testRenderLinesWithSearchHits(t *testing.T, input string, expectedBackgroun
 		readers:        []*reader.ReaderImpl{r},
 		scrollPosition: newScrollPosition(scrollPositionName),
 	}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 	pager.search = search.For("xxx")
 	pager.ShowStatusBar = false
@@ -455,3 +462,35 @@ func BenchmarkRenderHugeLine(b *testing.B) {
 		pager.renderLines()
 	}
 }
+
+func TestMarkGutter(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("test", "first\nsecond\nthird"))
+	pager.screen = twin.NewFakeScreen(20, 5)
+	pager.showLineNumbers = true
+
+	pager.currentBookmarks()['b'] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(2), "b")
+	pager.currentBookmarks()['a'] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(2), "a")
+	pager.currentBookmarks()['\''] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(3), "last")
+
+	rendered := pager.renderLines()
+	assert.Equal(t, renderedToString(rendered.lines[0].cells), "  1 first")
+	assert.Equal(t, renderedToString(rendered.lines[1].cells), "  2asecond")
+	assert.Equal(t, renderedToString(rendered.lines[2].cells), "  3 third")
+}
+
+// Marks should be visible without line numbers too
+func TestMarkGutterWithoutLineNumbers(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("test", "first\nsecond"))
+	pager.screen = twin.NewFakeScreen(20, 5)
+	pager.showLineNumbers = false
+
+	// Where we were last time isn't a mark, so no gutter for that
+	pager.currentBookmarks()['\''] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(1), "last")
+	rendered := pager.renderLines()
+	assert.Equal(t, renderedToString(rendered.lines[0].cells), "first")
+
+	pager.currentBookmarks()['a'] = NewScrollPositionFromIndex(linemetadata.IndexFromOneBased(2), "a")
+	rendered = pager.renderLines()
+	assert.Equal(t, renderedToString(rendered.lines[0].cells), " first")
+	assert.Equal(t, renderedToString(rendered.lines[1].cells), "asecond")
+}
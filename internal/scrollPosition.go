@@ -40,8 +40,10 @@ type scrollPositionCanonical struct {
 	width           int  // From pager
 	height          int  // From pager
 	showLineNumbers bool // From pager
+	showMarkGutter  bool // From pager
 	showStatusBar   bool // From pager
 	wrapLongLines   bool // From pager
+	unfoldRecords   bool // From pager
 
 	pagerLineCount int // From pager.Reader().GetLineCount()
 
@@ -50,14 +52,16 @@ type scrollPositionCanonical struct {
 }
 
 func canonicalFromPager(pager *Pager) scrollPositionCanonical {
-	width, _ := pager.screen.Size()
+	width := pager.contentWidth()
 	height := pager.visibleHeight()
 	return scrollPositionCanonical{
 		width:           width,
 		height:          height,
 		showLineNumbers: pager.showLineNumbers,
+		showMarkGutter:  pager.showMarkGutter(),
 		showStatusBar:   pager.ShowStatusBar,
 		wrapLongLines:   pager.WrapLongLines,
+		unfoldRecords:   pager.UnfoldJSONRecords,
 
 		pagerLineCount: pager.Reader().GetLineCount(),
 
@@ -290,6 +294,13 @@ func scrollPositionFromIndex(name string, index linemetadata.Index) *scrollPosit
 	}
 }
 
+// The line index this position was created with, without clipping it to the
+// lines we have read so far. Use lineIndex() for anything but finding out
+// whether we need to read more lines.
+func (sp scrollPosition) unclippedLineIndex() *linemetadata.Index {
+	return sp.internalDontTouch.lineIndex
+}
+
 // Line index in the input stream, or nil if nothing has been read
 func (p *Pager) lineIndex() *linemetadata.Index {
 	p.scrollPosition.internalDontTouch.canonicalize(p)
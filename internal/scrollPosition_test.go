@@ -18,9 +18,10 @@ func testCanonicalize1000(t *testing.T, withStatusBar bool, currentStartLine lin
 	pager := Pager{}
 	pager.screen = twin.NewFakeScreen(100, screenHeight)
 	pager.readers = []*reader.ReaderImpl{reader.NewFromTextForTesting("test", strings.Repeat("a\n", 2000))}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 	pager.ShowLineNumbers = true
 	pager.showLineNumbers = true
@@ -74,9 +75,10 @@ func tryScrollAmount(t *testing.T, scrollFrom linemetadata.Index, scrollDistance
 	pager := Pager{}
 	pager.screen = twin.NewFakeScreen(80, screenHeight)
 	pager.readers = []*reader.ReaderImpl{reader.NewFromTextForTesting("test", strings.Repeat("x\n", 1492))}
-	pager.filteringReader = FilteringReader{
+	pager.filter = &filterChain{}
+	pager.filteringReader = &FilteringReader{
 		BackingReader: pager.readers[pager.currentReader],
-		Filter:        &pager.filter,
+		Filter:        pager.filter,
 	}
 	pager.ShowLineNumbers = true
 	pager.showLineNumbers = true
@@ -0,0 +1,138 @@
+// A scrollbar column on the right edge of the screen, showing where in the
+// input the viewport, the search hits, the filter matches and the bookmarks
+// are.
+
+package internal
+
+import (
+	"github.com/rivo/uniseg"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Screen width minus the scrollbar, if we have one
+func (p *Pager) contentWidth() int {
+	width, _ := p.screen.Size()
+	if p.ShowScrollbar && width > 1 {
+		return width - 1
+	}
+
+	return width
+}
+
+// Which input lines are shown by a scrollbar row. First is inclusive, last is
+// exclusive.
+func scrollbarRowLines(row int, height int, lineCount int) (int, int) {
+	return row * lineCount / height, (row + 1) * lineCount / height
+}
+
+// The scrollbar row for an input line
+func scrollbarRowForLine(index int, height int, lineCount int) int {
+	if lineCount <= 0 {
+		return 0
+	}
+
+	return min(index*height/lineCount, height-1)
+}
+
+// Draw the scrollbar. The first and last visible lines are inclusive.
+func (p *Pager) drawScrollbar(firstVisible linemetadata.Index, lastVisible linemetadata.Index) {
+	if !p.ShowScrollbar {
+		return
+	}
+
+	width, _ := p.screen.Size()
+	if width <= 1 {
+		return
+	}
+	column := width - 1
+	height := p.visibleHeight()
+	if height <= 0 {
+		return
+	}
+
+	lineCount := p.Reader().GetLineCount()
+	if lineCount == 0 {
+		return
+	}
+
+	// What to show on each row, in order of increasing priority
+	cells := make([]twin.StyledRune, height)
+	for row := range cells {
+		cells[row] = twin.NewStyledRune(' ', scrollbarStyle)
+	}
+
+	if !p.isShowingHelp && p.filteringReader != nil {
+		for _, index := range p.filteringReader.matchIndices() {
+			cells[scrollbarRowForLine(index, height, lineCount)] = twin.NewStyledRune('·', scrollbarStyle)
+		}
+	}
+
+	for _, index := range p.searchHitIndices() {
+		cells[scrollbarRowForLine(index.Index(), height, lineCount)] = twin.NewStyledRune('•', scrollbarStyle)
+	}
+
+	if !p.isShowingHelp && len(p.bookmarks) > 0 {
+		for mark, position := range p.currentBookmarks() {
+			if mark == '\'' || uniseg.StringWidth(string(mark)) != 1 {
+				continue
+			}
+
+			// Not lineIndex(), canonicalizing would make us re-render
+			markIndex := position.unclippedLineIndex()
+			if markIndex == nil || markIndex.Index() >= lineCount {
+				continue
+			}
+			cells[scrollbarRowForLine(markIndex.Index(), height, lineCount)] = twin.NewStyledRune(mark, scrollbarStyle)
+		}
+	}
+
+	// The viewport is shown by reversing the rows it covers
+	firstViewportRow := scrollbarRowForLine(firstVisible.Index(), height, lineCount)
+	lastViewportRow := scrollbarRowForLine(lastVisible.Index(), height, lineCount)
+	for row := firstViewportRow; row <= lastViewportRow; row++ {
+		cells[row].Style = cells[row].Style.WithAttr(twin.AttrReverse)
+	}
+
+	for row, cell := range cells {
+		p.screen.SetCell(column, row, cell)
+	}
+}
+
+// Handle a mouse click. Returns true if it hit the scrollbar.
+func (p *Pager) onScrollbarClick(column int, row int) bool {
+	if !p.ShowScrollbar {
+		return false
+	}
+
+	width, _ := p.screen.Size()
+	if column != width-1 || row < 0 || row >= p.visibleHeight() {
+		return false
+	}
+
+	lineCount := p.Reader().GetLineCount()
+	if lineCount == 0 {
+		return true
+	}
+
+	firstLine, _ := scrollbarRowLines(row, p.visibleHeight(), lineCount)
+	p.scrollPosition = NewScrollPositionFromIndex(linemetadata.IndexFromZeroBased(firstLine), "onScrollbarClick")
+	p.setTargetLine(nil)
+	return true
+}
+
+// The search hits counted so far
+func (p *Pager) searchHitIndices() []linemetadata.Index {
+	if p.hitCounter == nil || p.search.Inactive() {
+		return nil
+	}
+
+	p.hitCounter.lock.Lock()
+	defer p.hitCounter.lock.Unlock()
+	if !p.hitCounter.key.search.Equals(p.search) {
+		// Not counted yet
+		return nil
+	}
+
+	return p.hitCounter.hits
+}
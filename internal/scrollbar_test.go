@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func scrollbarColumn(screen twin.Screen, height int) string {
+	width, _ := screen.Size()
+	result := ""
+	for row := range height {
+		result += string(screen.GetCell(width-1, row).Rune)
+	}
+	return result
+}
+
+func TestScrollbar(t *testing.T) {
+	lines := []string{}
+	for i := range 90 {
+		if i == 60 {
+			lines = append(lines, "needle")
+		} else {
+			lines = append(lines, "hay")
+		}
+	}
+	pager := newCommandTestPager(t, strings.Join(lines, "\n"))
+	pager.ShowScrollbar = true
+	pager.currentBookmarks()['a'] = NewScrollPositionFromIndex(linemetadata.IndexFromZeroBased(30), "test")
+
+	pager.search = search.For("needle")
+	awaitHitCount(t, pager.hitCounter, pager.Reader(), hitCounterKey{reader: pager.filteringReader.BackingReader, search: pager.search}, 0, 8)
+
+	pager.redraw("")
+
+	// 9 content rows for 90 lines, 10 lines per row
+	assert.Equal(t, scrollbarColumn(pager.screen, 9), "   a  •  ")
+	assert.Assert(t, pager.screen.GetCell(19, 0).Style.HasAttr(twin.AttrReverse), "Viewport should be reversed")
+	assert.Assert(t, !pager.screen.GetCell(19, 1).Style.HasAttr(twin.AttrReverse))
+
+	// Content should stop before the scrollbar
+	assert.Equal(t, pager.contentWidth(), 19)
+
+	// Clicking the scrollbar scrolls there
+	assert.Assert(t, pager.onScrollbarClick(19, 6))
+	assert.Equal(t, pager.lineIndex().Index(), 60)
+
+	// Clicking elsewhere doesn't
+	assert.Assert(t, !pager.onScrollbarClick(18, 2))
+	assert.Equal(t, pager.lineIndex().Index(), 60)
+}
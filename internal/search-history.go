@@ -10,6 +10,7 @@ import (
 	"unicode"
 
 	"github.com/adrg/xdg"
+	"github.com/walles/moor/v2/internal/search"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -19,6 +20,11 @@ type SearchHistory struct {
 	absFileName string
 
 	entries []string
+
+	// Non-default search modifiers, by entry. These go in a file of their own,
+	// see modifiersFileName(), so that the history file stays readable for
+	// older moor versions.
+	modifiers map[string]search.Modifiers
 }
 
 /*
@@ -37,7 +43,7 @@ const maxSearchHistoryEntries = 640 // This should be enough for anyone
 // A relative path or just a file name means relative to the user's home
 // directory. Empty means follow the XDG spec for data files.
 func BootSearchHistory(fileName string) SearchHistory {
-	fileName = resolveHistoryFilePath(fileName)
+	fileName = resolveHistoryFilePath(fileName, "moor/search_history")
 
 	history, err := loadMoorSearchHistory(fileName)
 	if err != nil {
@@ -50,6 +56,7 @@ func BootSearchHistory(fileName string) SearchHistory {
 		return SearchHistory{
 			absFileName: fileName,
 			entries:     history,
+			modifiers:   loadSearchModifiers(modifiersFileName(fileName)),
 		}
 	}
 
@@ -77,6 +84,28 @@ func BootSearchHistory(fileName string) SearchHistory {
 	}
 }
 
+// Like BootSearchHistory(), but for the ':' command line. There's no less
+// history to import for this one.
+func BootCommandHistory(fileName string) SearchHistory {
+	fileName = resolveHistoryFilePath(fileName, "moor/command_history")
+
+	history, err := loadMoorSearchHistory(fileName)
+	if err != nil {
+		log.Infof("Could not load moor command history from %s: %v", fileName, err)
+		// IO Error, give up
+		return SearchHistory{}
+	}
+	if history == nil {
+		history = []string{}
+	}
+
+	log.Infof("Loaded %d command history entries from %s", len(history), fileName)
+	return SearchHistory{
+		absFileName: fileName,
+		entries:     history,
+	}
+}
+
 // Returns (nil, nil) if the file doesn't exist. Otherwise returns history slice
 // or error.
 func loadMoorSearchHistory(absHistoryFileName string) ([]string, error) {
@@ -111,19 +140,19 @@ func loadMoorSearchHistory(absHistoryFileName string) ([]string, error) {
 	return removeDupsKeepingLast(lines), nil
 }
 
-// Empty file name will resolve to a default XDG friendly path. Absolute will be
-// left untouched. Relative will be interpreted relative to the user's home
-// directory.
-func resolveHistoryFilePath(fileName string) string {
+// Empty file name will resolve to xdgName in the XDG data directory. Absolute
+// will be left untouched. Relative will be interpreted relative to the user's
+// home directory.
+func resolveHistoryFilePath(fileName string, xdgName string) string {
 	if fileName == "-" || fileName == "/dev/null" {
 		// No history file
 		return ""
 	}
 
 	if fileName == "" {
-		xdgPath, err := xdg.DataFile("moor/search_history")
+		xdgPath, err := xdg.DataFile(xdgName)
 		if err != nil {
-			log.Infof("Could not resolve XDG data file path for search history: %v", err)
+			log.Infof("Could not resolve XDG data file path for %s: %v", xdgName, err)
 			return ""
 		}
 		return xdgPath
@@ -278,12 +307,69 @@ func removeDupsKeepingLast(history []string) []string {
 	return cleaned
 }
 
+// Where the search modifiers for a history file go, or "" if there is no
+// history file
+func modifiersFileName(absHistoryFileName string) string {
+	if absHistoryFileName == "" {
+		return ""
+	}
+
+	return absHistoryFileName + ".modifiers"
+}
+
+// Each line is the modifier flags, a space and the entry, like "lw a.b". The
+// flags never contain spaces.
+func loadSearchModifiers(absFileName string) map[string]search.Modifiers {
+	if absFileName == "" {
+		return nil
+	}
+
+	modifiers := map[string]search.Modifiers{}
+	err := iterateFileByLines(absFileName, func(line string) {
+		flags, entry, found := strings.Cut(line, " ")
+		if !found {
+			return
+		}
+
+		modifiers[entry] = search.ModifiersFromFlags(flags)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Infof("Could not load search modifiers from %s: %v", absFileName, err)
+	}
+
+	return modifiers
+}
+
+// Which modifiers to use with a search history entry
+func (h *SearchHistory) modifiersFor(entry string) search.Modifiers {
+	return h.modifiers[entry]
+}
+
+// Like addEntry(), but remembers the modifiers too
+func (h *SearchHistory) addSearch(text string, modifiers search.Modifiers) {
+	if text == "" {
+		return
+	}
+
+	if modifiers == (search.Modifiers{}) {
+		delete(h.modifiers, text)
+	} else {
+		if h.modifiers == nil {
+			h.modifiers = map[string]search.Modifiers{}
+		}
+		h.modifiers[text] = modifiers
+	}
+
+	h.addEntry(text)
+}
+
 func (h *SearchHistory) addEntry(entry string) {
 	if entry == "" {
 		return
 	}
 	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry {
-		// Same as last entry, do nothing
+		// Same as last entry, but the modifiers may have changed
+		h.saveModifiers()
 		return
 	}
 
@@ -291,21 +377,56 @@ func (h *SearchHistory) addEntry(entry string) {
 	h.entries = removeDupsKeepingLast(append(h.entries, entry))
 	for len(h.entries) > maxSearchHistoryEntries {
 		// Remove oldest entry
+		delete(h.modifiers, h.entries[0])
 		h.entries = h.entries[1:]
 	}
 
+	writeHistoryFile(h.absFileName, h.entries)
+	h.saveModifiers()
+}
+
+// Write the modifiers of the entries that have any. With no such entries, the
+// modifiers file is removed.
+func (h *SearchHistory) saveModifiers() {
+	absFileName := modifiersFileName(h.absFileName)
+	if absFileName == "" || h.modifiers == nil || os.Getenv("LESSSECURE") == "1" {
+		// Nowhere to save, nothing to save or not allowed to save
+		return
+	}
+
+	lines := []string{}
+	for _, entry := range h.entries {
+		if modifiers, found := h.modifiers[entry]; found {
+			lines = append(lines, modifiers.Flags()+" "+entry)
+		}
+	}
+
+	if len(lines) == 0 {
+		err := os.Remove(absFileName)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Infof("Could not remove search modifiers file %s: %v", absFileName, err)
+		}
+		return
+	}
+
+	writeHistoryFile(absFileName, lines)
+}
+
+// Replace the contents of a history file with some lines. Does nothing if
+// absFileName is empty.
+func writeHistoryFile(absFileName string, lines []string) {
 	if os.Getenv("LESSSECURE") == "1" {
 		// LESSSECURE=1 means not writing anything to disk
 		return
 	}
 
-	if h.absFileName == "" {
+	if absFileName == "" {
 		// No history file configured
 		return
 	}
 
 	// Write new file to a temp file and rename it into place
-	tmpFilePath := h.absFileName + ".tmp"
+	tmpFilePath := absFileName + ".tmp"
 	f, err := os.Create(tmpFilePath)
 	if err != nil {
 		log.Infof("Could not create temp history file %s: %v", tmpFilePath, err)
@@ -327,9 +448,9 @@ func (h *SearchHistory) addEntry(entry string) {
 
 		if shouldRename {
 			// Rename temp file into place
-			err = os.Rename(tmpFilePath, h.absFileName)
+			err = os.Rename(tmpFilePath, absFileName)
 			if err != nil {
-				log.Infof("Could not rename temp history file %s to %s: %v", tmpFilePath, h.absFileName, err)
+				log.Infof("Could not rename temp history file %s to %s: %v", tmpFilePath, absFileName, err)
 				return
 			}
 		} else {
@@ -342,7 +463,7 @@ func (h *SearchHistory) addEntry(entry string) {
 	}()
 
 	writer := bufio.NewWriter(f)
-	for _, line := range h.entries {
+	for _, line := range lines {
 		_, err := writer.WriteString(line + "\n")
 		if err != nil {
 			log.Infof("Could not write to temp history file %s: %v", tmpFilePath, err)
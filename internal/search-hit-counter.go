@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Stop counting after this many hits. Remembering where all the hits are costs
+// memory, and nobody cares whether there are 100k or 200k hits.
+const maxCountedHits = 100_000
+
+// Stop counting after this many lines. Otherwise we would read all of huge
+// files, pushing the lines on screen out of the reader's caches.
+const maxCountedLines = 1_000_000
+
+// What the hits are counted in. If any of this changes, we start over.
+type hitCounterKey struct {
+	reader      reader.Reader // The unfiltered reader
+	search      search.Search
+	filter      filterChain
+	context     filterContext
+	keepNonJSON bool
+}
+
+func (k hitCounterKey) equals(other hitCounterKey) bool {
+	return k.reader == other.reader &&
+		k.search.Equals(other.search) &&
+		k.filter.Equals(other.filter) &&
+		k.context == other.context &&
+		k.keepNonJSON == other.keepNonJSON
+}
+
+// Counts search hits in the background, so that we can show "hit 12/340" in
+// the status bar. One per pane.
+type hitCounter struct {
+	lock sync.Mutex
+
+	key hitCounterKey
+
+	// Where we count. Either key.reader, or the lines the pager has filtered
+	// out of it.
+	reader reader.Reader
+
+	// Hits in the first countedLines lines, in order
+	hits         []linemetadata.Index
+	countedLines int
+
+	// True if we stopped at maxCountedHits or maxCountedLines
+	capped bool
+
+	counting bool
+
+	// Bumped on every restart, so that outdated counts know to give up
+	generation int
+
+	// Where to tell the main loop that there's a new count to show
+	events chan twin.Event
+}
+
+// Start counting in the background if needed, and return what we have so far.
+// Call this on every redraw, that's how we pick up more lines as they are
+// read.
+//
+// lines is what to count in. That's key.reader, or a filteredLines snapshot
+// when filtering, so that we don't have to filter again. If allRead is false,
+// more lines may come and the count is marked as partial.
+//
+// The visible range is used for figuring out which hit we're at.
+func (c *hitCounter) status(screen twin.Screen, key hitCounterKey, lines reader.Reader, allRead bool, firstVisible linemetadata.Index, lastVisible linemetadata.Index) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.updateUnlocked(screen, key, lines)
+	if key.search.Inactive() {
+		return ""
+	}
+
+	if len(c.hits) == 0 {
+		if c.counting {
+			return ""
+		}
+		if !allRead {
+			return "no hits yet"
+		}
+		return "no hits"
+	}
+
+	// "+" means there may be more hits than we have counted
+	total := fmt.Sprintf("%d", len(c.hits))
+	if c.capped || c.counting || !allRead {
+		total += "+"
+	}
+
+	// The first hit on screen is the one we're at
+	current := sort.Search(len(c.hits), func(i int) bool {
+		return !c.hits[i].IsBefore(firstVisible)
+	})
+	if current < len(c.hits) && !c.hits[current].IsAfter(lastVisible) {
+		return fmt.Sprintf("hit %d/%s", current+1, total)
+	}
+
+	return total + " hits"
+}
+
+// Like status(), but returns the numbers rather than a text. firstHit is nil if
+// no hits have been found yet, and partial is true if there may be more hits.
+func (c *hitCounter) count(screen twin.Screen, key hitCounterKey, lines reader.Reader, allRead bool) (count int, firstHit *linemetadata.Index, partial bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.updateUnlocked(screen, key, lines)
+	if len(c.hits) > 0 {
+		first := c.hits[0]
+		firstHit = &first
+	}
+
+	return len(c.hits), firstHit, c.capped || c.counting || !allRead
+}
+
+// Start over if needed, and start counting more lines if there are any. Call
+// with the lock held.
+func (c *hitCounter) updateUnlocked(screen twin.Screen, key hitCounterKey, lines reader.Reader) {
+	c.events = screen.Events()
+
+	// Snapshots of filtered lines are replaced as more lines are filtered
+	c.reader = lines
+	lineCount := lines.GetLineCount()
+
+	if !key.equals(c.key) || lineCount < c.countedLines {
+		// Start over
+		c.key = key
+		c.hits = nil
+		c.countedLines = 0
+		c.capped = false
+		c.counting = false
+		c.generation++
+	}
+
+	if key.search.Inactive() {
+		return
+	}
+
+	if !c.counting && !c.capped && c.countedLines < lineCount {
+		c.startCountingUnlocked(lineCount)
+	}
+}
+
+// Count hits in the lines we haven't counted yet. Call with the lock held.
+func (c *hitCounter) startCountingUnlocked(lineCount int) {
+	c.counting = true
+	r := c.reader
+	generation := c.generation
+	searchFor := c.key.search
+	first := linemetadata.IndexFromZeroBased(c.countedLines)
+	countTo := min(lineCount, maxCountedLines)
+	before := linemetadata.IndexFromZeroBased(countTo)
+	maxHits := maxCountedHits - len(c.hits)
+
+	stillWanted := func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return c.generation == generation
+	}
+
+	go func() {
+		defer func() {
+			PanicHandler("hitCounter/count", recover(), debug.Stack())
+		}()
+
+		hits, capped := FindAllHits(r, searchFor, first, before, maxHits, stillWanted)
+
+		c.lock.Lock()
+		if c.generation != generation {
+			// Somebody started over while we were counting
+			c.lock.Unlock()
+			return
+		}
+
+		c.hits = append(c.hits, hits...)
+		c.countedLines = countTo
+		c.capped = capped || countTo < lineCount
+		c.counting = false
+		if capped {
+			log.Infof("Stopped counting search hits at %d", len(c.hits))
+		} else if c.capped {
+			log.Infof("Stopped counting search hits after %d lines", countTo)
+		}
+		events := c.events
+		c.lock.Unlock()
+
+		select {
+		case events <- eventHitsCounted{}:
+		default:
+			// Other events are waiting, we'll get redrawn anyway
+		}
+	}()
+}
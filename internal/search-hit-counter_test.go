@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+// Call status() until the counting is done
+func awaitHitCount(t *testing.T, counter *hitCounter, r reader.Reader, key hitCounterKey, first int, last int) string {
+	t.Helper()
+
+	allRead := true
+	if impl, ok := key.reader.(*reader.ReaderImpl); ok {
+		allRead = impl.ReadingDone.Load()
+	}
+
+	screen := twin.NewFakeScreen(20, 10)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := counter.status(screen, key, r, allRead, linemetadata.IndexFromZeroBased(first), linemetadata.IndexFromZeroBased(last))
+
+		counter.lock.Lock()
+		done := !counter.counting
+		counter.lock.Unlock()
+		if done {
+			return status
+		}
+
+		assert.Assert(t, time.Now().Before(deadline), "Counting never finished")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHitCounter(t *testing.T) {
+	lines := []string{}
+	for i := range 1000 {
+		if i%10 == 0 {
+			lines = append(lines, "hit")
+		} else {
+			lines = append(lines, "miss")
+		}
+	}
+	r := reader.NewFromTextForTesting("TestHitCounter", strings.Join(lines, "\n"))
+	assert.NilError(t, r.Wait())
+
+	counter := &hitCounter{}
+	key := hitCounterKey{reader: r, search: search.For("hit")}
+
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 0, 9), "hit 1/100")
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 25, 34), "hit 4/100")
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 21, 29), "100 hits")
+
+	// A new search starts over
+	key.search = search.For("nothing")
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 0, 9), "no hits")
+
+	key.search = search.Search{}
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 0, 9), "")
+}
+
+// When filtering, hits are counted in the pager's already filtered lines
+func TestHitCounterFiltered(t *testing.T) {
+	r := reader.NewFromTextForTesting("TestHitCounterFiltered", "hit a\nhit b\nmiss a\nhit a")
+	assert.NilError(t, r.Wait())
+
+	filter := filterChainFor("a")
+	filtering := &FilteringReader{BackingReader: r, Filter: &filter}
+	filtered := filtering.snapshot()
+	assert.Equal(t, filtered.GetLineCount(), 3)
+
+	counter := &hitCounter{}
+	key := hitCounterKey{reader: r, search: search.For("hit"), filter: filter}
+	assert.Equal(t, awaitHitCount(t, counter, filtered, key, 1, 2), "hit 2/2")
+
+	// Not filtering, no snapshot
+	filter = filterChain{}
+	assert.Assert(t, filtering.snapshot() == nil)
+}
+
+// Until all lines have been read, the count should say it's partial
+func TestHitCounterPausedReader(t *testing.T) {
+	pauseAfterLines := 2
+	r, err := reader.NewFromStream("TestHitCounterPausedReader", strings.NewReader("hit\nmiss\nhit\nhit\n"), formatters.TTY,
+		reader.ReaderOptions{PauseAfterLines: &pauseAfterLines})
+	assert.NilError(t, err)
+	//revive:disable-next-line:empty-block
+	for !r.PauseStatus.Load() {
+	}
+
+	counter := &hitCounter{}
+	key := hitCounterKey{reader: r, search: search.For("hit")}
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 0, 1), "hit 1/1+")
+
+	key.search = search.For("nothing")
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 0, 1), "no hits yet")
+
+	r.SetPauseAfterLines(math.MaxInt)
+	//revive:disable-next-line:empty-block
+	for !r.ReadingDone.Load() {
+	}
+
+	key.search = search.For("hit")
+	assert.Equal(t, awaitHitCount(t, counter, r, key, 0, 3), "hit 1/3")
+}
+
+func TestFindAllHitsCapped(t *testing.T) {
+	r := reader.NewFromTextForTesting("TestFindAllHitsCapped", strings.Repeat("hit\n", 100))
+	assert.NilError(t, r.Wait())
+
+	stillWanted := func() bool { return true }
+	hits, capped := FindAllHits(r, search.For("hit"), linemetadata.Index{}, linemetadata.IndexFromZeroBased(100), 10, stillWanted)
+	assert.Equal(t, len(hits), 10)
+	assert.Assert(t, capped)
+
+	hits, capped = FindAllHits(r, search.For("hit"), linemetadata.Index{}, linemetadata.IndexFromZeroBased(100), 100, stillWanted)
+	assert.Equal(t, len(hits), 100)
+	assert.Assert(t, !capped)
+	assert.Equal(t, hits[99], linemetadata.IndexFromZeroBased(99))
+}
@@ -157,3 +157,84 @@ func _findFirstHit(reader reader.Reader, startPosition linemetadata.Index, searc
 		}
 	}
 }
+
+// Find all search hits in input lines from startPosition up to, but not
+// including, beforePosition. Just like FindFirstHit(), this searches multiple
+// chunks in parallel.
+//
+// At most maxHits hits are returned, the second return value tells whether
+// there were more.
+//
+// stillWanted is called every now and then. If it returns false we give up,
+// and the return values are undefined.
+func FindAllHits(reader reader.Reader, search search.Search, startPosition linemetadata.Index, beforePosition linemetadata.Index, maxHits int, stillWanted func() bool) ([]linemetadata.Index, bool) {
+	linesCount := beforePosition.Index() - startPosition.Index()
+	if linesCount <= 0 {
+		return nil, false
+	}
+
+	chunkCount := runtime.NumCPU()
+	if linesCount < chunkCount {
+		chunkCount = 1
+	}
+	chunkSize := linesCount / chunkCount
+
+	log.Debugf("Counting hits in %d lines across %d cores with %d lines per core...", linesCount, chunkCount, chunkSize)
+
+	findings := make([]chan []linemetadata.Index, chunkCount)
+	for i := 0; i < chunkCount; i++ {
+		findings[i] = make(chan []linemetadata.Index)
+
+		chunkStart := startPosition.NonWrappingAdd(i * chunkSize)
+		chunkBefore := beforePosition
+		if i+1 < chunkCount {
+			chunkBefore = startPosition.NonWrappingAdd((i + 1) * chunkSize)
+		}
+
+		go func(i int, chunkStart linemetadata.Index, chunkBefore linemetadata.Index) {
+			defer func() {
+				PanicHandler("FindAllHits()/chunkSearch", recover(), debug.Stack())
+			}()
+
+			findings[i] <- _findAllHits(reader, chunkStart, search, chunkBefore, maxHits+1, stillWanted)
+		}(i, chunkStart, chunkBefore)
+	}
+
+	// Collect the results in order
+	hits := []linemetadata.Index{}
+	for _, finding := range findings {
+		hits = append(hits, <-finding...)
+	}
+
+	if len(hits) > maxHits {
+		return hits[:maxHits], true
+	}
+
+	return hits, false
+}
+
+// Search one chunk for FindAllHits(), returning at most maxHits hits
+func _findAllHits(reader reader.Reader, startPosition linemetadata.Index, search search.Search, beforePosition linemetadata.Index, maxHits int, stillWanted func() bool) []linemetadata.Index {
+	hits := []linemetadata.Index{}
+	lineCache := searchLineCache{}
+	for searchPosition := startPosition; searchPosition.IsBefore(beforePosition); searchPosition = searchPosition.NonWrappingAdd(1) {
+		if searchPosition.Index()%1000 == 0 && !stillWanted() {
+			return nil
+		}
+
+		line := lineCache.GetLine(reader, searchPosition, SearchDirectionForward)
+		if line == nil {
+			// Out of lines
+			break
+		}
+
+		if search.Matches(line.Plain()) {
+			hits = append(hits, searchPosition)
+			if len(hits) >= maxHits {
+				break
+			}
+		}
+	}
+
+	return hits
+}
@@ -0,0 +1,98 @@
+package search
+
+import "unicode"
+
+// fzf style fuzzy matching. All runes of the search string must be in the
+// line, in order, but there can be other runes between them. So "cnrf" matches
+// "connection refused".
+
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusConsecutive = 8
+	fuzzyBonusWordStart   = 8
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+// Returns the rune positions of the match in line, or nil if there is no
+// match. The pattern must already be lowercase unless caseSensitive is true.
+//
+// Like fzf's v1 algorithm, we find the first place where the match ends, and
+// then go backwards from there to find the shortest match ending there. The
+// shortest match isn't always the best one though, "conref" in "connection
+// refused" should match the start of "connection". So if the match we found
+// going forwards scores better, we use that instead.
+func fuzzyMatch(line []rune, pattern []rune, caseSensitive bool) []int {
+	if len(pattern) == 0 {
+		return nil
+	}
+
+	runeAt := func(i int) rune {
+		if caseSensitive {
+			return line[i]
+		}
+		return unicode.ToLower(line[i])
+	}
+
+	// Forwards to find the end of the first match
+	forwards := make([]int, 0, len(pattern))
+	for i := range line {
+		if runeAt(i) == pattern[len(forwards)] {
+			forwards = append(forwards, i)
+			if len(forwards) == len(pattern) {
+				break
+			}
+		}
+	}
+	if len(forwards) < len(pattern) {
+		return nil
+	}
+
+	// Backwards to find the shortest match ending there
+	backwards := make([]int, len(pattern))
+	patternIndex := len(pattern) - 1
+	for i := forwards[len(forwards)-1]; i >= 0; i-- {
+		if runeAt(i) == pattern[patternIndex] {
+			backwards[patternIndex] = i
+			if patternIndex == 0 {
+				break
+			}
+			patternIndex--
+		}
+	}
+
+	if fuzzyScore(line, forwards) > fuzzyScore(line, backwards) {
+		return forwards
+	}
+	return backwards
+}
+
+// Higher is better. Consecutive matches and matches at the start of words are
+// good, gaps are bad.
+func fuzzyScore(line []rune, positions []int) int {
+	score := 0
+	for i, position := range positions {
+		score += fuzzyScoreMatch
+
+		if position == 0 || !isWordRune(line[position-1]) {
+			score += fuzzyBonusWordStart
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		gap := position - positions[i-1] - 1
+		if gap == 0 {
+			score += fuzzyBonusConsecutive
+		} else {
+			score -= fuzzyPenaltyGapStart + (gap-1)*fuzzyPenaltyGapExtend
+		}
+	}
+
+	return score
+}
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char)
+}
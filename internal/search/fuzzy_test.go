@@ -0,0 +1,62 @@
+package search
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	fuzzy := Modifiers{Pattern: PatternFuzzy}
+
+	assert.Assert(t, ForWithModifiers("cnrf", fuzzy).Matches("connection refused"))
+	assert.Assert(t, !ForWithModifiers("cnrf", fuzzy).Matches("refused connection"))
+
+	// Smart case
+	assert.Assert(t, ForWithModifiers("cnrf", fuzzy).Matches("CONNECTION REFUSED"))
+	assert.Assert(t, !ForWithModifiers("CNRF", fuzzy).Matches("connection refused"))
+
+	// The shortest match ending at the first possible place, adjacent runes
+	// merged into one range
+	assert.DeepEqual(t,
+		ForWithModifiers("abc", fuzzy).GetMatchRanges("a xabc").Matches,
+		[][2]int{{3, 6}})
+
+	// Unless the first match going forwards is better
+	assert.DeepEqual(t,
+		ForWithModifiers("cnrf", fuzzy).GetMatchRanges("connection refused").Matches,
+		[][2]int{{0, 1}, {2, 3}, {11, 12}, {13, 14}})
+	assert.DeepEqual(t,
+		ForWithModifiers("conref", fuzzy).GetMatchRanges("connection refused").Matches,
+		[][2]int{{0, 3}, {11, 14}})
+
+	// Rune positions, not byte positions
+	assert.DeepEqual(t,
+		ForWithModifiers("åö", fuzzy).GetMatchRanges("åäö").Matches,
+		[][2]int{{0, 1}, {2, 3}})
+
+	assert.Assert(t, !ForWithModifiers("x", fuzzy).Equals(For("x")))
+	assert.Equal(t, Modifiers{Pattern: PatternFuzzy}.Flags(), "f")
+	assert.Equal(t, ModifiersFromFlags("f"), fuzzy)
+}
+
+func TestFuzzyScore(t *testing.T) {
+	fuzzy := ForWithModifiers("conref", Modifiers{Pattern: PatternFuzzy})
+
+	consecutive, ok := fuzzy.Score("connection refused")
+	assert.Assert(t, ok)
+	scattered, ok := fuzzy.Score("cxoxnxrxexf")
+	assert.Assert(t, ok)
+	wordStarts, ok := fuzzy.Score("xcon xref")
+	assert.Assert(t, ok)
+	assert.Assert(t, consecutive > scattered)
+	assert.Assert(t, consecutive > wordStarts)
+
+	_, ok = fuzzy.Score("nothing here")
+	assert.Assert(t, !ok)
+
+	// Non-fuzzy searches match or don't
+	score, ok := For("con").Score("connection refused")
+	assert.Assert(t, ok)
+	assert.Equal(t, score, 0)
+}
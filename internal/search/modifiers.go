@@ -0,0 +1,117 @@
+package search
+
+import "strings"
+
+type PatternMode int
+
+const (
+	PatternAuto    PatternMode = iota // Regexp if it looks like one, otherwise substring
+	PatternLiteral                    // Always substring
+	PatternRegexp                     // Always regexp, unless it's invalid
+	PatternFuzzy                      // Like fzf, see fuzzy.go
+)
+
+type CaseMode int
+
+const (
+	CaseSmart       CaseMode = iota // Case sensitive if there are upper case characters
+	CaseSensitive                   // Always case sensitive
+	CaseInsensitive                 // Never case sensitive
+)
+
+// How to interpret a search string. The zero value is what For() does.
+type Modifiers struct {
+	Pattern PatternMode
+	Case    CaseMode
+
+	// Only match whole words, like "grep -w"
+	WholeWord bool
+}
+
+// Like "literal, case sensitive, word". Empty for the default modifiers.
+func (m Modifiers) String() string {
+	parts := []string{}
+	switch m.Pattern {
+	case PatternLiteral:
+		parts = append(parts, "literal")
+	case PatternRegexp:
+		parts = append(parts, "regexp")
+	case PatternFuzzy:
+		parts = append(parts, "fuzzy")
+	}
+
+	switch m.Case {
+	case CaseSensitive:
+		parts = append(parts, "case sensitive")
+	case CaseInsensitive:
+		parts = append(parts, "ignore case")
+	}
+
+	if m.WholeWord {
+		parts = append(parts, "word")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// One letter per non-default modifier, like "lsw". Parse with
+// ModifiersFromFlags().
+func (m Modifiers) Flags() string {
+	flags := ""
+	switch m.Pattern {
+	case PatternLiteral:
+		flags += "l"
+	case PatternRegexp:
+		flags += "r"
+	case PatternFuzzy:
+		flags += "f"
+	}
+
+	switch m.Case {
+	case CaseSensitive:
+		flags += "s"
+	case CaseInsensitive:
+		flags += "i"
+	}
+
+	if m.WholeWord {
+		flags += "w"
+	}
+
+	return flags
+}
+
+// Unknown flags are ignored
+func ModifiersFromFlags(flags string) Modifiers {
+	m := Modifiers{}
+	for _, flag := range flags {
+		switch flag {
+		case 'l':
+			m.Pattern = PatternLiteral
+		case 'r':
+			m.Pattern = PatternRegexp
+		case 'f':
+			m.Pattern = PatternFuzzy
+		case 's':
+			m.Case = CaseSensitive
+		case 'i':
+			m.Case = CaseInsensitive
+		case 'w':
+			m.WholeWord = true
+		}
+	}
+
+	return m
+}
+
+// Auto -> literal -> regexp -> fuzzy -> auto
+func (m Modifiers) WithNextPatternMode() Modifiers {
+	m.Pattern = (m.Pattern + 1) % 4
+	return m
+}
+
+// Smart -> sensitive -> insensitive -> smart
+func (m Modifiers) WithNextCaseMode() Modifiers {
+	m.Case = (m.Case + 1) % 3
+	return m
+}
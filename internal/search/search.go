@@ -11,30 +11,53 @@ import (
 type Search struct {
 	findMe string
 
+	modifiers Modifiers
+
 	// If this is false it means the input has to be interpreted as a regexp.
 	isSubstringSearch bool
 
+	// Named for what it means in smart case mode, where upper case characters
+	// in the search string make the search case sensitive.
 	hasUppercase bool
 
 	pattern *regexp.Regexp
+
+	// Set for fuzzy searches, lowercase unless hasUppercase is set
+	fuzzy []rune
 }
 
 func (search Search) Equals(other Search) bool {
-	return search.findMe == other.findMe
+	return search.findMe == other.findMe && search.modifiers == other.modifiers
 }
 
 func (search Search) String() string {
 	return search.findMe
 }
 
+func (search Search) Modifiers() Modifiers {
+	return search.modifiers
+}
+
 func For(s string) Search {
 	search := Search{}
 	search.For(s)
 	return search
 }
 
+func ForWithModifiers(s string, modifiers Modifiers) Search {
+	search := Search{}
+	search.ForWithModifiers(s, modifiers)
+	return search
+}
+
 func (search *Search) For(s string) *Search {
+	return search.ForWithModifiers(s, Modifiers{})
+}
+
+func (search *Search) ForWithModifiers(s string, modifiers Modifiers) *Search {
 	search.findMe = s
+	search.modifiers = modifiers
+	search.fuzzy = nil
 	if s == "" {
 		// No search
 		search.pattern = nil
@@ -46,6 +69,13 @@ func (search *Search) For(s string) *Search {
 	search.pattern, err = regexp.Compile(s)
 	isValidRegexp := err == nil
 	regexpMatchingRequired := hasSpecialChars && isValidRegexp
+	switch modifiers.Pattern {
+	case PatternLiteral:
+		regexpMatchingRequired = false
+	case PatternRegexp:
+		// Invalid regexps fall back to substring search
+		regexpMatchingRequired = isValidRegexp
+	}
 	search.isSubstringSearch = !regexpMatchingRequired
 
 	search.hasUppercase = false
@@ -56,26 +86,53 @@ func (search *Search) For(s string) *Search {
 		}
 	}
 
-	if search.isSubstringSearch {
-		// Pattern still needed for GetMatchRanges()
-		search.pattern, err = regexp.Compile(regexp.QuoteMeta(s))
-		if err != nil {
-			panic(err)
+	ignoreCaseFlag := ""
+	switch modifiers.Case {
+	case CaseSensitive:
+		search.hasUppercase = true
+	case CaseInsensitive:
+		if search.hasUppercase {
+			// We lowercase the lines, but we can't lowercase a regexp
+			ignoreCaseFlag = "(?i)"
 		}
+		search.hasUppercase = false
+	}
 
+	if modifiers.Pattern == PatternFuzzy {
+		search.isSubstringSearch = false
+		search.pattern = nil
+		search.fuzzy = []rune(s)
+		if !search.hasUppercase {
+			search.fuzzy = []rune(strings.ToLower(s))
+		}
 		return search
 	}
 
-	// At this point we know it's a valid regexp, and that it does include
-	// regexp specific characters. We also know the pattern has been
-	// successfully compiled.
+	expression := s
+	if search.isSubstringSearch {
+		expression = regexp.QuoteMeta(s)
+	}
+
+	if modifiers.WholeWord {
+		// Substring search can't do this, go for the regexp
+		search.isSubstringSearch = false
+		expression = `\b(?:` + expression + `)\b`
+	}
+
+	// For substring searches, the pattern is still needed for GetMatchRanges()
+	search.pattern, err = regexp.Compile(ignoreCaseFlag + expression)
+	if err != nil {
+		panic(err)
+	}
 
 	return search
 }
 
 func (search *Search) Clear() {
 	search.findMe = ""
+	search.modifiers = Modifiers{}
 	search.pattern = nil
+	search.fuzzy = nil
 }
 
 func (search Search) Active() bool {
@@ -91,6 +148,10 @@ func (search Search) Matches(line string) bool {
 		return false
 	}
 
+	if search.fuzzy != nil {
+		return fuzzyMatch([]rune(line), search.fuzzy, search.hasUppercase) != nil
+	}
+
 	if search.isSubstringSearch && search.hasUppercase {
 		// Case sensitive substring search
 		return strings.Contains(line, search.findMe)
@@ -118,6 +179,10 @@ func (search Search) GetMatchRanges(String string) *MatchRanges {
 		return nil
 	}
 
+	if search.fuzzy != nil {
+		return fuzzyMatchRanges(fuzzyMatch([]rune(String), search.fuzzy, search.hasUppercase))
+	}
+
 	if !search.hasUppercase {
 		// Case insensitive search, lowercase the string. The pattern is already
 		// lowercase whenever hasUppercase is false.
@@ -129,6 +194,37 @@ func (search Search) GetMatchRanges(String string) *MatchRanges {
 	}
 }
 
+// How well a line matches a fuzzy search, higher is better. False if the line
+// doesn't match. All matching lines get the same score for non-fuzzy searches.
+func (search Search) Score(line string) (int, bool) {
+	if search.fuzzy == nil {
+		return 0, search.Matches(line)
+	}
+
+	runes := []rune(line)
+	positions := fuzzyMatch(runes, search.fuzzy, search.hasUppercase)
+	if positions == nil {
+		return 0, false
+	}
+
+	return fuzzyScore(runes, positions), true
+}
+
+// Merge adjacent fuzzy match positions into ranges
+func fuzzyMatchRanges(positions []int) *MatchRanges {
+	ranges := &MatchRanges{}
+	for _, position := range positions {
+		last := len(ranges.Matches) - 1
+		if last >= 0 && ranges.Matches[last][1] == position {
+			ranges.Matches[last][1] = position + 1
+			continue
+		}
+		ranges.Matches = append(ranges.Matches, [2]int{position, position + 1})
+	}
+
+	return ranges
+}
+
 // Convert byte indices to rune indices
 func toRunePositions(byteIndices [][]int, matchedString string) [][2]int {
 	var returnMe [][2]int
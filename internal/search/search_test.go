@@ -63,3 +63,46 @@ func BenchmarkCaseSensitiveRegexMatch(b *testing.B) {
 func BenchmarkCaseInsensitiveRegexMatch(b *testing.B) {
 	benchmarkMatch(b, "this [w]on't match anything")
 }
+
+func TestSearchModifiers(t *testing.T) {
+	// Literal, even though this is a valid regexp
+	literal := Modifiers{Pattern: PatternLiteral}
+	assert.Assert(t, ForWithModifiers("a.b", literal).Matches("xa.by"))
+	assert.Assert(t, !ForWithModifiers("a.b", literal).Matches("axb"))
+	assert.Assert(t, For("a.b").Matches("axb"))
+
+	// Regexp, even without any special characters
+	assert.Assert(t, ForWithModifiers("ab", Modifiers{Pattern: PatternRegexp}).Matches("xaby"))
+
+	// Invalid regexps fall back to substring search
+	assert.Assert(t, ForWithModifiers("a(b", Modifiers{Pattern: PatternRegexp}).Matches("xa(by"))
+
+	// Case sensitive lower case search
+	sensitive := Modifiers{Case: CaseSensitive}
+	assert.Assert(t, !ForWithModifiers("error", sensitive).Matches("ERROR"))
+	assert.Assert(t, ForWithModifiers("error", sensitive).Matches("error"))
+
+	// Case insensitive upper case search, both substring and regexp
+	insensitive := Modifiers{Case: CaseInsensitive}
+	assert.Assert(t, ForWithModifiers("ERROR", insensitive).Matches("error"))
+	assert.Assert(t, ForWithModifiers("ERR.R", insensitive).Matches("error"))
+	assert.DeepEqual(t, ForWithModifiers("ERR.R", insensitive).GetMatchRanges("an error").Matches, [][2]int{{3, 8}})
+
+	// Whole words only
+	word := Modifiers{WholeWord: true}
+	assert.Assert(t, ForWithModifiers("id", word).Matches("user id=5"))
+	assert.Assert(t, !ForWithModifiers("id", word).Matches("userid=5"))
+	assert.Assert(t, ForWithModifiers("a.b", Modifiers{Pattern: PatternLiteral, WholeWord: true}).Matches("x a.b y"))
+
+	assert.Assert(t, !For("x").Equals(ForWithModifiers("x", word)))
+}
+
+func TestModifiersFlags(t *testing.T) {
+	m := Modifiers{Pattern: PatternLiteral, Case: CaseInsensitive, WholeWord: true}
+	assert.Equal(t, m.Flags(), "liw")
+	assert.Equal(t, ModifiersFromFlags(m.Flags()), m)
+	assert.Equal(t, m.String(), "literal, ignore case, word")
+
+	assert.Equal(t, Modifiers{}.Flags(), "")
+	assert.Equal(t, Modifiers{}.String(), "")
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/search"
+	"github.com/walles/moor/v2/twin"
+)
+
+// When the screen is split, the focused pane's state lives in the Pager
+// itself, and the other pane's state lives in here.
+//
+// To do something with the other pane, call swapPanes(), do your thing, then
+// call swapPanes() again.
+type splitScreen struct {
+	// The real screen, shared by both panes
+	root twin.Screen
+
+	other paneState
+}
+
+// Everything that is specific to one pane
+type paneState struct {
+	screen twin.Screen
+	mode   PagerMode
+
+	currentReader   int
+	filteringReader *FilteringReader
+
+	scrollPosition      scrollPosition
+	leftColumnZeroBased int
+	targetLine          *linemetadata.Index
+	pendingGoto         *pendingGoto
+	longestLineLength   int
+	showLineNumbers     bool
+
+	search     search.Search
+	hitCounter *hitCounter
+
+	// The filteringReader filters through this one, so it must stay the same
+	// object while the pane lives
+	filter *filterChain
+
+	highlights        []search.Search
+	columns           columnSettings
+	unfoldJSONRecords bool
+
+	isShowingHelp bool
+	preHelpState  *_PreHelpState
+}
+
+// One half of the real screen. The top pane gets the upper half, the bottom
+// pane gets the rest.
+type paneScreen struct {
+	twin.Screen // The real screen
+	isBottom    bool
+}
+
+func (s *paneScreen) firstRow() int {
+	if !s.isBottom {
+		return 0
+	}
+
+	_, height := s.Screen.Size()
+	return height / 2
+}
+
+func (s *paneScreen) Size() (width int, height int) {
+	width, height = s.Screen.Size()
+	if s.isBottom {
+		return width, height - height/2
+	}
+	return width, height / 2
+}
+
+func (s *paneScreen) Clear() {
+	width, height := s.Size()
+	firstRow := s.firstRow()
+	for row := firstRow; row < firstRow+height; row++ {
+		for column := 0; column < width; column++ {
+			s.Screen.SetCell(column, row, twin.NewStyledRune(' ', twin.StyleDefault))
+		}
+	}
+}
+
+func (s *paneScreen) SetCell(column int, row int, styledRune twin.StyledRune) int {
+	_, height := s.Size()
+	if row < 0 || row >= height {
+		// Don't draw into the other pane
+		return styledRune.Width()
+	}
+
+	return s.Screen.SetCell(column, s.firstRow()+row, styledRune)
+}
+
+func (s *paneScreen) GetCell(column int, row int) twin.StyledRune {
+	_, height := s.Size()
+	if row < 0 || row >= height {
+		return twin.NewStyledRune(' ', twin.StyleDefault)
+	}
+
+	return s.Screen.GetCell(column, s.firstRow()+row)
+}
+
+func (s *paneScreen) ShowCursorAt(column int, row int) {
+	s.Screen.ShowCursorAt(column, s.firstRow()+row)
+}
+
+// Split the screen in two. The new bottom pane will show the next file if there
+// is one, or the same file as the top pane otherwise.
+func (p *Pager) splitScreen() {
+	if p.split != nil {
+		return
+	}
+
+	filter := &filterChain{}
+	p.readerLock.Lock()
+	otherReader := (p.currentReader + 1) % len(p.readers)
+	filteringReader := &FilteringReader{
+		BackingReader: p.readers[otherReader],
+		Filter:        filter,
+		Context:       &p.filterContext,
+		KeepNonJSON:   &p.keepNonJSON,
+	}
+	p.readerLock.Unlock()
+
+	// The new pane starts out looking like this one
+	columns := p.columns
+	columns.names = slices.Clone(columns.names)
+	columns.widths = slices.Clone(columns.widths)
+
+	other := paneState{
+		screen:            &paneScreen{Screen: p.screen, isBottom: true},
+		mode:              PagerModeUnfocused{pager: p},
+		currentReader:     otherReader,
+		filteringReader:   filteringReader,
+		hitCounter:        &hitCounter{},
+		filter:            filter,
+		scrollPosition:    newScrollPosition("Other pane scroll position"),
+		showLineNumbers:   p.showLineNumbers,
+		highlights:        slices.Clone(p.highlights),
+		columns:           columns,
+		unfoldJSONRecords: p.UnfoldJSONRecords,
+	}
+	if otherReader == p.currentReader {
+		// Same file, start out at the same position
+		other.scrollPosition = p.scrollPosition
+		other.leftColumnZeroBased = p.leftColumnZeroBased
+		other.search = p.search
+		*other.filter = *p.filter
+	}
+
+	p.readerLock.Lock()
+	p.split = &splitScreen{
+		root:  p.screen,
+		other: other,
+	}
+	p.readerLock.Unlock()
+	p.screen = &paneScreen{Screen: p.screen}
+
+	p.notifyReaderSwitched()
+	log.Debug("Screen split")
+}
+
+// Close the pane that doesn't have focus
+func (p *Pager) unsplitScreen() {
+	if p.split == nil {
+		return
+	}
+
+	p.screen = p.split.root
+	p.readerLock.Lock()
+	p.split = nil
+	p.readerLock.Unlock()
+
+	p.notifyReaderSwitched()
+	log.Debug("Screen unsplit")
+}
+
+// Move focus to the other pane
+func (p *Pager) switchPane() {
+	if p.split == nil {
+		return
+	}
+
+	p.swapPanes()
+	p.mode = PagerModeViewing{pager: p}
+	p.split.other.mode = PagerModeUnfocused{pager: p}
+
+	p.notifyReaderSwitched()
+	log.Debug("Focus moved to the other pane")
+}
+
+// Exchange the state of the focused pane with the state of the other pane
+func (p *Pager) swapPanes() {
+	other := &p.split.other
+
+	p.readerLock.Lock()
+	p.currentReader, other.currentReader = other.currentReader, p.currentReader
+	p.readerLock.Unlock()
+
+	p.screen, other.screen = other.screen, p.screen
+	p.mode, other.mode = other.mode, p.mode
+	p.filteringReader, other.filteringReader = other.filteringReader, p.filteringReader
+	p.scrollPosition, other.scrollPosition = other.scrollPosition, p.scrollPosition
+	p.leftColumnZeroBased, other.leftColumnZeroBased = other.leftColumnZeroBased, p.leftColumnZeroBased
+	p.TargetLine, other.targetLine = other.targetLine, p.TargetLine
+	p.pendingGoto, other.pendingGoto = other.pendingGoto, p.pendingGoto
+	p.longestLineLength, other.longestLineLength = other.longestLineLength, p.longestLineLength
+	p.showLineNumbers, other.showLineNumbers = other.showLineNumbers, p.showLineNumbers
+	p.search, other.search = other.search, p.search
+	p.filter, other.filter = other.filter, p.filter
+	p.highlights, other.highlights = other.highlights, p.highlights
+	p.columns, other.columns = other.columns, p.columns
+	p.UnfoldJSONRecords, other.unfoldJSONRecords = other.unfoldJSONRecords, p.UnfoldJSONRecords
+	p.hitCounter, other.hitCounter = other.hitCounter, p.hitCounter
+	p.isShowingHelp, other.isShowingHelp = other.isShowingHelp, p.isShowingHelp
+	p.preHelpState, other.preHelpState = other.preHelpState, p.preHelpState
+}
+
+// Tell the background goroutine to start listening for more lines from the
+// readers we're now showing, and to redraw the screen
+func (p *Pager) notifyReaderSwitched() {
+	select {
+	case p.readerSwitched <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestSplitScreen(t *testing.T) {
+	first := reader.NewFromTextForTesting("first", "a1\na2\na3\na4\na5\na6\na7\na8\na9")
+	second := reader.NewFromTextForTesting("second", "b1\nb2\nb3\nb4\nb5\nb6\nb7\nb8\nb9")
+
+	screen := twin.NewFakeScreen(20, 10)
+	pager := NewPager(first, second)
+	pager.ShowLineNumbers = false
+	pager.Quit()
+	pager.StartPaging(screen, nil, nil)
+	pager.showLineNumbers = false
+
+	// Split, the new bottom pane should show the second file
+	pager.mode.onRune('S')
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(0)), "a1")
+	assert.Equal(t, rowToString(screen.GetRow(3)), "a4")
+	assert.Equal(t, rowToString(screen.GetRow(5)), "b1")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "b4")
+
+	// Scrolling the bottom pane should leave the top pane alone
+	pager.mode.onRune('\t')
+	pager.mode.onRune('j')
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(0)), "a1")
+	assert.Equal(t, rowToString(screen.GetRow(5)), "b2")
+
+	// Unsplitting keeps the focused pane. All of the second file fits on
+	// screen now, so we're back at the top of it.
+	pager.mode.onRune('S')
+	pager.redraw("")
+	assert.Equal(t, rowToString(screen.GetRow(0)), "b1")
+	assert.Equal(t, rowToString(screen.GetRow(8)), "b9")
+}
+
+// Filters, highlights and columns in one pane shouldn't affect the other one
+func TestSplitScreenPaneState(t *testing.T) {
+	pager := newCommandTestPager(t, "ERROR one\nINFO two\nERROR three")
+	pager.splitScreen()
+
+	assert.NilError(t, pager.executeCommand("filter ERROR"))
+	assert.NilError(t, pager.executeCommand("highlight one"))
+	assert.NilError(t, pager.executeCommand("set columns"))
+	assert.Equal(t, pager.Reader().GetLineCount(), 2)
+
+	pager.switchPane()
+	assert.Assert(t, pager.filter.Inactive())
+	assert.Equal(t, len(pager.highlights), 0)
+	assert.Assert(t, !pager.columns.enabled)
+	assert.Equal(t, pager.Reader().GetLineCount(), 3)
+
+	// The unfocused pane's reader should still use its own filter
+	assert.Equal(t, pager.split.other.filteringReader.GetLineCount(), 2)
+
+	pager.switchPane()
+	assert.Equal(t, pager.filter.String(), "ERROR")
+	assert.Equal(t, len(pager.highlights), 1)
+	assert.Assert(t, pager.columns.enabled)
+}
@@ -28,6 +28,18 @@ var searchHitStyle = twin.StyleDefault.WithAttr(twin.AttrReverse)
 // This can be nil
 var searchHitLineBackground *twin.Color
 
+var scrollbarStyle = twin.StyleDefault.WithAttr(twin.AttrDim)
+
+// Highlight patterns get these styles in order, starting over when we run out
+var highlightStyles = []twin.Style{
+	twin.StyleDefault.WithForeground(twin.NewColor16(0)).WithBackground(twin.NewColor16(3)),  // Yellow
+	twin.StyleDefault.WithForeground(twin.NewColor16(0)).WithBackground(twin.NewColor16(2)),  // Green
+	twin.StyleDefault.WithForeground(twin.NewColor16(0)).WithBackground(twin.NewColor16(6)),  // Cyan
+	twin.StyleDefault.WithForeground(twin.NewColor16(0)).WithBackground(twin.NewColor16(5)),  // Magenta
+	twin.StyleDefault.WithForeground(twin.NewColor16(0)).WithBackground(twin.NewColor16(9)),  // Bright red
+	twin.StyleDefault.WithForeground(twin.NewColor16(0)).WithBackground(twin.NewColor16(12)), // Bright blue
+}
+
 func setStyle(updateMe *twin.Style, envVarName string, fallback *twin.Style) {
 	envValue := os.Getenv(envVarName)
 	if envValue == "" {
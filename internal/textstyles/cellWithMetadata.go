@@ -15,6 +15,7 @@ type CellWithMetadata struct {
 
 	StartsSearchHit bool // True if this cell is the start of a search hit
 	IsSearchHit     bool // True if this cell is part of a search hit
+	IsHighlighted   bool // True if this cell matches a highlight pattern
 }
 
 // Required for some tests to pass
@@ -35,6 +36,10 @@ func (r CellWithMetadata) Equal(b CellWithMetadata) bool {
 		return false
 	}
 
+	if r.IsHighlighted != b.IsHighlighted {
+		return false
+	}
+
 	return true
 }
 
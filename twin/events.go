@@ -19,10 +19,15 @@ const (
 	MouseWheelDown
 	MouseWheelLeft
 	MouseWheelRight
+	MouseButtonLeft // Pressed, we don't report releases
 )
 
 type EventMouse struct {
 	buttons MouseButtonMask
+
+	// Zero based screen position
+	column int
+	row    int
 }
 
 // After you get this, query Screen.Size() to get the new size
@@ -49,3 +54,8 @@ func (eventKeyCode *EventKeyCode) KeyCode() KeyCode {
 func (eventMouse *EventMouse) Buttons() MouseButtonMask {
 	return eventMouse.buttons
 }
+
+// Zero based screen column and row of the mouse pointer
+func (eventMouse *EventMouse) Position() (column int, row int) {
+	return eventMouse.column, eventMouse.row
+}
@@ -129,8 +129,9 @@ type UnixScreen struct {
 //   - "65" says this is Wheel Up. "64" would be Wheel Down.
 //   - "127" is the column number on screen, "1" is the first column.
 //   - "41" is the row number on screen, "1" is the first row.
-//   - "M" marks the end of the mouse event.
-var mouseEventRegex = regexp.MustCompile("^\x1b\\[<([0-9]+);([0-9]+);([0-9]+)M")
+//   - "M" marks the end of the mouse event. "m" would mean a button was
+//     released.
+var mouseEventRegex = regexp.MustCompile("^\x1b\\[<([0-9]+);([0-9]+);([0-9]+)([Mm])")
 
 // NewScreen() requires Close() to be called after you are done with your new
 // screen, most likely somewhere in your shutdown code.
@@ -579,13 +580,28 @@ func consumeEncodedEvent(encodedEventSequences string) (*Event, string) {
 
 	mouseMatch := mouseEventRegex.FindStringSubmatch(encodedEventSequences)
 	if mouseMatch != nil {
-		if mouseMatch[1] == "64" {
-			var event Event = EventMouse{buttons: MouseWheelUp}
-			return &event, strings.TrimPrefix(encodedEventSequences, mouseMatch[0])
+		remainder := strings.TrimPrefix(encodedEventSequences, mouseMatch[0])
+		if mouseMatch[4] == "m" {
+			// Button released, nobody cares
+			return consumeEncodedEvent(remainder)
 		}
-		if mouseMatch[1] == "65" {
-			var event Event = EventMouse{buttons: MouseWheelDown}
-			return &event, strings.TrimPrefix(encodedEventSequences, mouseMatch[0])
+
+		// The regexp guarantees these are numbers
+		column, _ := strconv.Atoi(mouseMatch[2])
+		row, _ := strconv.Atoi(mouseMatch[3])
+		mouseEvent := EventMouse{column: column - 1, row: row - 1}
+
+		switch mouseMatch[1] {
+		case "0":
+			mouseEvent.buttons = MouseButtonLeft
+		case "64":
+			mouseEvent.buttons = MouseWheelUp
+		case "65":
+			mouseEvent.buttons = MouseWheelDown
+		}
+		if mouseEvent.buttons != 0 {
+			var event Event = mouseEvent
+			return &event, remainder
 		}
 
 		log.Debug(fmt.Sprint(
@@ -32,8 +32,12 @@ func TestConsumeEncodedEvent(t *testing.T) {
 	// Implicitly test having a remaining rune at the end
 	assertEncode(t, "\x1b[Ax", EventKeyCode{keyCode: KeyUp}, "x")
 
-	assertEncode(t, "\x1b[<64;127;41M", EventMouse{buttons: MouseWheelUp}, "")
-	assertEncode(t, "\x1b[<65;127;41M", EventMouse{buttons: MouseWheelDown}, "")
+	assertEncode(t, "\x1b[<64;127;41M", EventMouse{buttons: MouseWheelUp, column: 126, row: 40}, "")
+	assertEncode(t, "\x1b[<65;127;41M", EventMouse{buttons: MouseWheelDown, column: 126, row: 40}, "")
+
+	// Clicks get reported, releases don't
+	assertEncode(t, "\x1b[<0;5;1M\x1b[<0;5;1mx", EventMouse{buttons: MouseButtonLeft, column: 4, row: 0}, "\x1b[<0;5;1mx")
+	assertEncode(t, "\x1b[<0;5;1mx", EventRune{rune: 'x'}, "")
 
 	// This happens when users paste.
 	//